@@ -1,13 +1,73 @@
 package config
 
 type CommenceConfig struct {
-	// 追踪服务配置
+	// 追踪服务配置，17track的API密钥同时用于校验webhook签名
 	The17TrackSecretKey string `cfg:"17TRACK_SECRET_KEY"`
 
+	// 主动查询追踪信息的缓存时间（秒），0表示不缓存
+	TrackingCacheSeconds int `cfg:"TRACKING_CACHE_SECONDS" default:"300"`
+
+	// UPS追踪，启用后1Z单号直接走UPS Track API和Track Alert推送
+	UPS struct {
+		Enabled           bool   `cfg:"ENABLED" default:"false"`
+		ClientID          string `cfg:"CLIENT_ID"`
+		ClientSecret      string `cfg:"CLIENT_SECRET"`
+		Sandbox           bool   `cfg:"SANDBOX" default:"false"`
+		Locale            string `cfg:"LOCALE" default:"en_US"`
+		WebhookCredential string `cfg:"WEBHOOK_CREDENTIAL"` // 订阅时交给UPS，推送时用于校验请求
+	} `cfg:"UPS"`
+
+	// USPS追踪，启用后USPS单号直接查询USPS v3接口，需要在TrackingPoll中轮询
+	USPS struct {
+		Enabled      bool   `cfg:"ENABLED" default:"false"`
+		ClientID     string `cfg:"CLIENT_ID"`
+		ClientSecret string `cfg:"CLIENT_SECRET"`
+		ApiBaseUrl   string `cfg:"API_BASE_URL" default:"https://apis.usps.com"` // 测试环境为 https://apis-tem.usps.com
+	} `cfg:"USPS"`
+
+	// 主动轮询未完成的包裹，间隔按包裹状态自动调整
+	TrackingPoll struct {
+		Minutes     int    `cfg:"MINUTES" default:"5"`          // 调度检查间隔，0表示不启用
+		Providers   string `cfg:"PROVIDERS" default:"usps,ups"` // 需要轮询的服务商，逗号分隔，未启用的跳过
+		Concurrency int    `cfg:"CONCURRENCY" default:"2"`      // 每个服务商的并发请求数
+		BatchSize   int    `cfg:"BATCH_SIZE" default:"200"`     // 每轮每个服务商最多查询的包裹数
+
+		UnknownGiveUpDays int `cfg:"UNKNOWN_GIVE_UP_DAYS" default:"30"` // 单号持续未知且没有事件超过该天数后停止轮询，0表示不停止
+	} `cfg:"TRACKING_POLL"`
+
+	// 追踪服务商的单号配额检查，剩余低于WarnPercent时告警
+	TrackingQuota struct {
+		CheckMinutes int `cfg:"CHECK_MINUTES" default:"60"` // 检查间隔，0表示不检查
+		WarnPercent  int `cfg:"WARN_PERCENT" default:"10"`
+	} `cfg:"TRACKING_QUOTA"`
+
+	// 包裹异常预警规则
+	TrackingAlert struct {
+		Minutes        int `cfg:"MINUTES" default:"60"`         // 检查间隔，0表示不检查
+		NoEventDays    int `cfg:"NO_EVENT_DAYS" default:"7"`    // 超过天数没有新事件
+		EtaGraceHours  int `cfg:"ETA_GRACE_HOURS" default:"24"` // 超过预计送达时间仍未送达
+		FailedAttempts int `cfg:"FAILED_ATTEMPTS" default:"2"`  // 派送失败次数
+		MaxAgeDays     int `cfg:"MAX_AGE_DAYS" default:"60"`    // 超过天数的包裹不再检查
+	} `cfg:"TRACKING_ALERT"`
+
+	// 店铺健康检查间隔（分钟），0表示不启用
+	ShopHealthCheckMinutes int `cfg:"SHOP_HEALTH_CHECK_MINUTES" default:"360"`
+
+	// 定时上架检查间隔（分钟），0表示不启用
+	ScheduledPublishMinutes int `cfg:"SCHEDULED_PUBLISH_MINUTES" default:"1"`
+
+	// 发布前的产品图片处理
+	ProductImages struct {
+		Attach         bool  `cfg:"ATTACH" default:"false"` // 下载后以base64附件上传，适用于私有或会过期的图片URL
+		MaxBytes       int64 `cfg:"MAX_BYTES" default:"20971520"`
+		TimeoutSeconds int   `cfg:"TIMEOUT_SECONDS" default:"30"`
+	} `cfg:"PRODUCT_IMAGES"`
+
 	Shopify struct {
 		Enabled        bool   `cfg:"ENABLED" default:"false"`
 		ApiKey         string `cfg:"API_KEY"`
 		ApiSecret      string `cfg:"API_SECRET"`
+		Scopes         string `cfg:"SCOPES" default:"read_products,write_products,read_orders,write_orders"` // 销售渠道和报关信息需要额外的read_publications,write_publications,write_inventory
 		EventBridgeARN string `cfg:"EVENT_BRIDGE_ARN"`
 		AWSRegion      string `cfg:"AWS_REGION"`
 		AWSAccessKey   string `cfg:"AWS_ACCESS_KEY"`
@@ -15,8 +75,51 @@ type CommenceConfig struct {
 		SQSQueueURL    string `cfg:"SQS_QUEUE_URL"`
 	} `cfg:"SHOPIFY"`
 
+	TikTokShop struct {
+		Enabled          bool   `cfg:"ENABLED" default:"false"`
+		AppKey           string `cfg:"APP_KEY"`
+		AppSecret        string `cfg:"APP_SECRET"`
+		ServiceID        string `cfg:"SERVICE_ID"`
+		AuthorizeUrl     string `cfg:"AUTHORIZE_URL" default:"https://services.tiktokshop.com/open/authorize"`
+		AuthBaseUrl      string `cfg:"AUTH_BASE_URL" default:"https://auth.tiktok-shops.com"`
+		ApiBaseUrl       string `cfg:"API_BASE_URL" default:"https://open-api.tiktokglobalshop.com"`
+		DefaultInventory int    `cfg:"DEFAULT_INVENTORY" default:"100"`
+	} `cfg:"TIKTOKSHOP"`
+
+	Etsy struct {
+		Enabled          bool   `cfg:"ENABLED" default:"false"`
+		ApiKey           string `cfg:"API_KEY"`
+		SharedSecret     string `cfg:"SHARED_SECRET"`
+		Scopes           string `cfg:"SCOPES" default:"listings_r listings_w shops_r transactions_r transactions_w"`
+		AuthorizeUrl     string `cfg:"AUTHORIZE_URL" default:"https://www.etsy.com/oauth/connect"`
+		ApiBaseUrl       string `cfg:"API_BASE_URL" default:"https://api.etsy.com"`
+		TaxonomyID       int64  `cfg:"TAXONOMY_ID"` // 产品未指定类目时使用
+		WhoMade          string `cfg:"WHO_MADE" default:"i_did"`
+		WhenMade         string `cfg:"WHEN_MADE" default:"made_to_order"`
+		DefaultInventory int    `cfg:"DEFAULT_INVENTORY" default:"100"`
+		PollMinutes      int    `cfg:"POLL_MINUTES" default:"10"` // 订单轮询间隔，0表示不轮询
+	} `cfg:"ETSY"`
+
+	Amazon struct {
+		Enabled          bool   `cfg:"ENABLED" default:"false"`
+		ApplicationID    string `cfg:"APPLICATION_ID"`
+		ClientID         string `cfg:"CLIENT_ID"` // LWA凭证
+		ClientSecret     string `cfg:"CLIENT_SECRET"`
+		Beta             bool   `cfg:"BETA" default:"false"` // 应用未发布时授权需要version=beta
+		AuthorizeUrl     string `cfg:"AUTHORIZE_URL" default:"https://sellercentral.amazon.com/apps/authorize/consent"`
+		TokenUrl         string `cfg:"TOKEN_URL" default:"https://api.amazon.com/auth/o2/token"`
+		Endpoint         string `cfg:"ENDPOINT" default:"https://sellingpartnerapi-na.amazon.com"`
+		MarketplaceID    string `cfg:"MARKETPLACE_ID" default:"ATVPDKIKX0DER"`
+		LanguageTag      string `cfg:"LANGUAGE_TAG" default:"en_US"`
+		ProductType      string `cfg:"PRODUCT_TYPE" default:"PRODUCT"` // 产品未指定类目时使用
+		DefaultBrand     string `cfg:"DEFAULT_BRAND" default:"Generic"`
+		DefaultInventory int    `cfg:"DEFAULT_INVENTORY" default:"100"`
+		PollMinutes      int    `cfg:"POLL_MINUTES" default:"10"` // 订单和listing状态的轮询间隔，0表示不轮询
+	} `cfg:"AMAZON"`
+
 	// 支付服务配置
 	PayPal struct {
+		Enabled      bool   `cfg:"ENABLED" default:"true"`
 		ClientID     string `cfg:"CLIENT_ID"`
 		ClientSecret string `cfg:"CLIENT_SECRET"`
 		Sandbox      bool   `cfg:"SANDBOX" default:"false"`
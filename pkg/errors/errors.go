@@ -4,16 +4,33 @@ import "github.com/flaboy/pin/usererrors"
 
 // Shop相关错误
 var (
-	ErrShopNameEmpty            = usererrors.New("shop.name_empty", "Shop name is empty")
-	ErrNonceGeneration          = usererrors.New("shop.nonce_generation_failed", "Failed to generate nonce")
-	ErrAuthURLGeneration        = usererrors.New("shop.auth_url_generation_failed", "Failed to generate authorization URL")
-	ErrInvalidCallbackSignature = usererrors.New("shop.invalid_callback_signature", "Invalid callback signature")
-	ErrAccessTokenFailed        = usererrors.New("shop.access_token_failed", "Failed to get access token")
-	ErrShopifyClientCreation    = usererrors.New("shop.shopify_client_creation_failed", "Failed to create Shopify client")
-	ErrShopInfoFailed           = usererrors.New("shop.shop_info_failed", "Failed to get shop info")
-	ErrWebhookSubscription      = usererrors.New("shop.webhook_subscription_failed", "Failed to subscribe webhooks")
-	ErrCredentialsMarshal       = usererrors.New("shop.credentials_marshal_failed", "Failed to marshal credentials")
-	ErrShopCreation             = usererrors.New("shop.creation_failed", "Failed to create shop")
-	ErrPlatformNotSupported     = usererrors.New("shop.platform_not_supported", "Unsupported platform")
-	ErrPlatformNotFound         = usererrors.New("shop.platform_not_found", "Platform not found")
+	ErrShopNameEmpty             = usererrors.New("shop.name_empty", "Shop name is empty")
+	ErrNonceGeneration           = usererrors.New("shop.nonce_generation_failed", "Failed to generate nonce")
+	ErrAuthURLGeneration         = usererrors.New("shop.auth_url_generation_failed", "Failed to generate authorization URL")
+	ErrInvalidCallbackSignature  = usererrors.New("shop.invalid_callback_signature", "Invalid callback signature")
+	ErrInvalidWebhookSignature   = usererrors.New("shop.invalid_webhook_signature", "Invalid webhook signature")
+	ErrInvalidWebhookPayload     = usererrors.New("shop.invalid_webhook_payload", "Invalid webhook payload")
+	ErrAccessTokenFailed         = usererrors.New("shop.access_token_failed", "Failed to get access token")
+	ErrShopifyClientCreation     = usererrors.New("shop.shopify_client_creation_failed", "Failed to create Shopify client")
+	ErrShopInfoFailed            = usererrors.New("shop.shop_info_failed", "Failed to get shop info")
+	ErrWebhookSubscription       = usererrors.New("shop.webhook_subscription_failed", "Failed to subscribe webhooks")
+	ErrCredentialsMarshal        = usererrors.New("shop.credentials_marshal_failed", "Failed to marshal credentials")
+	ErrShopCreation              = usererrors.New("shop.creation_failed", "Failed to create shop")
+	ErrPlatformNotSupported      = usererrors.New("shop.platform_not_supported", "Unsupported platform")
+	ErrPlatformNotFound          = usererrors.New("shop.platform_not_found", "Platform not found")
+	ErrShopNotFound              = usererrors.New("shop.not_found", "Shop not found")
+	ErrShopOwnedByOther          = usererrors.New("shop.owned_by_other", "Shop is already connected by another owner")
+	ErrOwnerResolverNotSet       = usererrors.New("shop.owner_resolver_not_set", "Request owner resolver is not configured")
+	ErrPublishNoShops            = usererrors.New("shop.publish_no_shops", "No shops selected for publishing")
+	ErrPublishJobNotFound        = usererrors.New("shop.publish_job_not_found", "Publish job not found")
+	ErrTrackingNotSupported      = usererrors.New("shop.tracking_not_supported", "Platform does not support tracking submission")
+	ErrCollectionsNotSupported   = usererrors.New("shop.collections_not_supported", "Platform does not support collections")
+	ErrChannelsNotSupported      = usererrors.New("shop.channels_not_supported", "Platform does not support sales channels")
+	ErrInvalidPublishMode        = usererrors.New("shop.invalid_publish_mode", "Invalid publish mode")
+	ErrInvalidPublishAt          = usererrors.New("shop.invalid_publish_at", "Scheduled publish time must be in the future")
+	ErrPublishModeNotSupported   = usererrors.New("shop.publish_mode_not_supported", "Platform does not support draft or scheduled publishing")
+	ErrProductStatusNotSupported = usererrors.New("shop.product_status_not_supported", "Platform does not support changing product status")
+	ErrShopProductNotFound       = usererrors.New("shop.product_not_found", "Shop product not found")
+	ErrDriftNotSupported         = usererrors.New("shop.drift_not_supported", "Platform does not support drift detection")
+	ErrInvalidDriftPolicy        = usererrors.New("shop.invalid_drift_policy", "Invalid drift policy")
 )
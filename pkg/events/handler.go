@@ -9,6 +9,16 @@ type EventHandler interface {
 	OnPaymentCompleted(event *types.PaymentCompletedEvent) error
 }
 
+// ShopUnhealthyHandler EventHandler可选实现，接收店铺授权失效事件
+type ShopUnhealthyHandler interface {
+	OnShopUnhealthy(event *types.ShopUnhealthyEvent) error
+}
+
+// ProductPublishFailedHandler EventHandler可选实现，接收商品发布失败事件
+type ProductPublishFailedHandler interface {
+	OnProductPublishFailed(event *types.ProductPublishFailedEvent) error
+}
+
 var handler EventHandler
 
 func SetEventHandler(h EventHandler) {
@@ -22,6 +32,13 @@ func EmitShopConnected(event *types.ShopConnectedEvent) error {
 	return nil
 }
 
+func EmitShopUnhealthy(event *types.ShopUnhealthyEvent) error {
+	if h, ok := handler.(ShopUnhealthyHandler); ok {
+		return h.OnShopUnhealthy(event)
+	}
+	return nil
+}
+
 func EmitProductPublished(event *types.ProductPublishedEvent) error {
 	if handler != nil {
 		return handler.OnProductPublished(event)
@@ -29,6 +46,13 @@ func EmitProductPublished(event *types.ProductPublishedEvent) error {
 	return nil
 }
 
+func EmitProductPublishFailed(event *types.ProductPublishFailedEvent) error {
+	if h, ok := handler.(ProductPublishFailedHandler); ok {
+		return h.OnProductPublishFailed(event)
+	}
+	return nil
+}
+
 func EmitOrderReceived(event *types.OrderReceivedEvent) error {
 	if handler != nil {
 		return handler.OnOrderReceived(event)
@@ -1,6 +1,7 @@
 package payment
 
 import (
+	"github.com/flaboy/aira-shop/pkg/config"
 	"github.com/flaboy/aira-shop/pkg/extensions/payment/paypal"
 	"github.com/flaboy/aira-shop/pkg/extensions/payment/types"
 	"github.com/flaboy/pin"
@@ -21,28 +22,8 @@ type PaymentChannel interface {
 	GetChannelName() string
 }
 
-func Get(channel string) PaymentChannel {
-	return paymentChannels[channel]
-}
-
-var paymentChannels map[string]PaymentChannel
-
+// Init 注册内置渠道并初始化所有已注册渠道，初始化失败的渠道被禁用而不影响启动
 func Init() {
-	paymentChannels = make(map[string]PaymentChannel)
-	paymentChannels["paypal"] = &paypal.PayPal{}
-
-	for _, channel := range paymentChannels {
-		if err := channel.Init(); err != nil {
-			panic(err)
-		}
-	}
-}
-
-// GetAvailableChannels 获取所有可用的支付渠道
-func GetAvailableChannels() []string {
-	channels := make([]string, 0, len(paymentChannels))
-	for name := range paymentChannels {
-		channels = append(channels, name)
-	}
-	return channels
+	registerBuiltin(&paypal.PayPal{}, config.Config.PayPal.Enabled)
+	initChannels()
 }
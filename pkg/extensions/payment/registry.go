@@ -0,0 +1,37 @@
+package payment
+
+import "github.com/flaboy/aira-shop/pkg/extensions/registry"
+
+// ChannelStatus 支付渠道的启用和初始化状态
+type ChannelStatus = registry.Status
+
+var channels = registry.New("payment channel", PaymentChannel.GetChannelName)
+
+// RegisterChannel 注册支付渠道实现，同名渠道会替换已注册的实现（包括内置渠道）
+// 在Init之后注册的渠道会立即初始化
+func RegisterChannel(channel PaymentChannel, enabled bool) {
+	channels.Register(channel, enabled)
+}
+
+func registerBuiltin(channel PaymentChannel, enabled bool) {
+	channels.RegisterBuiltin(channel, enabled)
+}
+
+func initChannels() {
+	channels.Init()
+}
+
+// Get 获取已启用的支付渠道，未启用或初始化失败时返回nil
+func Get(channel string) PaymentChannel {
+	return channels.Get(channel)
+}
+
+// GetAvailableChannels 获取所有可用的支付渠道
+func GetAvailableChannels() []string {
+	return channels.Names()
+}
+
+// GetChannelStatuses 获取所有已注册渠道的状态，包括未启用和初始化失败的渠道
+func GetChannelStatuses() []ChannelStatus {
+	return channels.Statuses()
+}
@@ -0,0 +1,137 @@
+package registry
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+)
+
+// Extension 可注册的扩展实现，如店铺平台和支付渠道
+type Extension interface {
+	Init() error
+}
+
+// Status 扩展的启用和初始化状态
+type Status struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Error   string `json:"error,omitempty"` // 初始化失败原因，失败的扩展视为未启用
+}
+
+type registration[T Extension] struct {
+	ext     T
+	enabled bool
+	status  Status
+}
+
+// Registry 按名称注册扩展，同名注册替换已有实现
+// Init时初始化已启用的扩展，单个扩展失败只禁用该扩展，Init之后注册的扩展立即初始化
+type Registry[T Extension] struct {
+	kind   string
+	nameOf func(T) string
+
+	mu            sync.RWMutex
+	registrations map[string]*registration[T]
+	enabled       map[string]T
+	initialized   bool
+}
+
+// New 创建注册表，kind用于日志，nameOf返回扩展的名称
+func New[T Extension](kind string, nameOf func(T) string) *Registry[T] {
+	return &Registry[T]{
+		kind:          kind,
+		nameOf:        nameOf,
+		registrations: map[string]*registration[T]{},
+		enabled:       map[string]T{},
+	}
+}
+
+// Register 注册扩展，同名扩展会替换已注册的实现（包括内置扩展）
+func (r *Registry[T]) Register(ext T, enabled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := r.nameOf(ext)
+	reg := &registration[T]{ext: ext, enabled: enabled}
+	r.registrations[name] = reg
+	delete(r.enabled, name)
+
+	if r.initialized {
+		r.initOne(name, reg)
+	}
+}
+
+// RegisterBuiltin 注册内置扩展，业务系统已注册同名扩展时保留业务系统的实现
+func (r *Registry[T]) RegisterBuiltin(ext T, enabled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := r.nameOf(ext)
+	if _, ok := r.registrations[name]; ok {
+		return
+	}
+	r.registrations[name] = &registration[T]{ext: ext, enabled: enabled}
+}
+
+// Init 初始化所有已注册的扩展
+func (r *Registry[T]) Init() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for name, reg := range r.registrations {
+		r.initOne(name, reg)
+	}
+	r.initialized = true
+}
+
+func (r *Registry[T]) initOne(name string, reg *registration[T]) {
+	reg.status = Status{Name: name}
+	if !reg.enabled {
+		return
+	}
+
+	if err := reg.ext.Init(); err != nil {
+		slog.Error("Failed to initialize "+r.kind+", disabled", "name", name, "error", err)
+		reg.status.Error = err.Error()
+		return
+	}
+	reg.status.Enabled = true
+	r.enabled[name] = reg.ext
+}
+
+// Get 获取已启用的扩展，未启用或初始化失败时返回零值
+func (r *Registry[T]) Get(name string) T {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.enabled[name]
+}
+
+// Names 获取所有已启用扩展的名称
+func (r *Registry[T]) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.enabled))
+	for name := range r.enabled {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Statuses 获取所有已注册扩展的状态，包括未启用和初始化失败的扩展
+func (r *Registry[T]) Statuses() []Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	statuses := make([]Status, 0, len(r.registrations))
+	for name, reg := range r.registrations {
+		status := reg.status
+		status.Name = name
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
@@ -0,0 +1,311 @@
+package amazon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/flaboy/aira-shop/pkg/errors"
+	"github.com/flaboy/aira-shop/pkg/events"
+	"github.com/flaboy/aira-shop/pkg/extensions/shoplink/utils"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
+	"github.com/flaboy/aira-web/pkg/helper"
+	"github.com/flaboy/pin"
+	"github.com/flaboy/pin/usererrors"
+)
+
+const platformName = "amazon"
+
+// 访问令牌剩余有效期低于该值时刷新，LWA访问令牌有效期为1小时
+const tokenRefreshBefore = 5 * time.Minute
+
+type Amazon struct {
+	httpClient *http.Client
+}
+
+type AmazonCredential struct {
+	SellingPartnerID string
+	MarketplaceID    string
+	Currency         string
+	StoreName        string
+	AccessToken      string
+	RefreshToken     string
+	ExpiresAt        int64
+}
+
+func (p *Amazon) Init() error {
+	if !config.Config.Amazon.Enabled {
+		return nil
+	}
+
+	p.httpClient = &http.Client{
+		Timeout: 120 * time.Second,
+	}
+
+	// 大部分卖家无法使用订单推送，通过轮询获取订单
+	if config.Config.Amazon.PollMinutes > 0 {
+		go p.StartOrderPoller(time.Duration(config.Config.Amazon.PollMinutes) * time.Minute)
+	}
+	return nil
+}
+
+func (p *Amazon) GetPlatformName() string {
+	return platformName
+}
+
+func redirectUrl() string {
+	return helper.BuildUrl("stores/callback/amazon")
+}
+
+// HandleRequest 生成Seller Central授权URL
+func (p *Amazon) HandleRequest(c *pin.Context, path string) (*types.HandleRequestResult, error) {
+	state, err := utils.GenerateNonce()
+	if err != nil {
+		return nil, errors.ErrNonceGeneration
+	}
+
+	query := url.Values{}
+	query.Set("application_id", config.Config.Amazon.ApplicationID)
+	query.Set("state", state)
+	query.Set("redirect_uri", redirectUrl())
+	if config.Config.Amazon.Beta {
+		query.Set("version", "beta")
+	}
+
+	return &types.HandleRequestResult{
+		AuthURL: config.Config.Amazon.AuthorizeUrl + "?" + query.Encode(),
+	}, nil
+}
+
+func (p *Amazon) HandleCallback(c *pin.Context, businessContext json.RawMessage, callbackUrl *url.URL) (*types.CallbackResponse, error) {
+	ownerID, err := utils.ResolveOwner(businessContext)
+	if err != nil {
+		return nil, errors.ErrShopCreation
+	}
+
+	query := callbackUrl.Query()
+	code := query.Get("spapi_oauth_code")
+	sellerID := query.Get("selling_partner_id")
+	if code == "" || sellerID == "" {
+		return nil, errors.ErrInvalidCallbackSignature
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	token, err := p.requestToken(ctx, url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {redirectUrl()},
+	})
+	if err != nil {
+		fmt.Printf("Amazon LWA token exchange failed: %v\n", err)
+		return nil, errors.ErrAccessTokenFailed
+	}
+
+	cred := &AmazonCredential{SellingPartnerID: sellerID}
+	applyToken(cred, token)
+
+	marketplace, err := p.resolveMarketplace(ctx, cred)
+	if err != nil {
+		fmt.Printf("Amazon marketplace participation query failed: %v\n", err)
+		return nil, errors.ErrShopInfoFailed
+	}
+	cred.MarketplaceID = marketplace.Marketplace.ID
+	cred.Currency = marketplace.Marketplace.DefaultCurrencyCode
+	cred.StoreName = marketplace.StoreName
+
+	name := marketplace.StoreName
+	if name == "" {
+		name = "Amazon " + sellerID
+	}
+
+	credentialsJson, err := json.Marshal(cred)
+	if err != nil {
+		return nil, errors.ErrCredentialsMarshal
+	}
+
+	shopLink, err := utils.SaveShopLink(&models.ShopLink{
+		OwnerID:     ownerID,
+		Platform:    platformName,
+		Domain:      sellerID,
+		Name:        name,
+		Url:         "https://" + marketplace.Marketplace.DomainName,
+		Credentials: credentialsJson,
+	})
+	if err == errors.ErrShopOwnedByOther {
+		return nil, err
+	} else if err != nil {
+		return nil, errors.ErrShopCreation
+	}
+
+	events.EmitShopConnected(&types.ShopConnectedEvent{
+		ShopID:   shopLink.ID,
+		Platform: platformName,
+		ShopData: map[string]interface{}{
+			"name":           name,
+			"seller_id":      sellerID,
+			"marketplace_id": cred.MarketplaceID,
+		},
+		BusinessContext: businessContext,
+		CreatedAt:       time.Now(),
+	})
+
+	return &types.CallbackResponse{
+		Type: types.CallbackResponseTypeShopLinked,
+		ShopLinkedData: &types.ShopLinkedData{
+			ShopLink: shopLink,
+		},
+	}, nil
+}
+
+// resolveMarketplace 优先使用配置的站点，卖家未开通时使用其第一个站点
+func (p *Amazon) resolveMarketplace(ctx context.Context, cred *AmazonCredential) (*marketplaceParticipation, error) {
+	var data participationsResponse
+	if err := p.call(ctx, http.MethodGet, "/sellers/v1/marketplaceParticipations", nil, nil, cred, &data); err != nil {
+		return nil, err
+	}
+
+	var fallback *marketplaceParticipation
+	for i := range data.Payload {
+		m := &data.Payload[i]
+		if !m.Participation.IsParticipating {
+			continue
+		}
+		if m.Marketplace.ID == config.Config.Amazon.MarketplaceID {
+			return m, nil
+		}
+		if fallback == nil {
+			fallback = m
+		}
+	}
+	if fallback == nil {
+		return nil, fmt.Errorf("seller does not participate in any marketplace")
+	}
+	return fallback, nil
+}
+
+// CheckHealth 验证刷新令牌可用且卖家仍在原站点销售
+func (p *Amazon) CheckHealth(credential *types.ShopCredential) (*types.HealthCheckResult, error) {
+	cred, err := decodeCredential(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	result := &types.HealthCheckResult{}
+	if err := p.ensureToken(ctx, cred); err != nil {
+		result.Message = fmt.Sprintf("failed to refresh access token: %v", err)
+		return result, nil
+	}
+
+	var data participationsResponse
+	if err := p.call(ctx, http.MethodGet, "/sellers/v1/marketplaceParticipations", nil, nil, cred, &data); err != nil {
+		if isAuthError(err) {
+			result.Message = err.Error()
+			return result, nil
+		}
+		return nil, err
+	}
+
+	result.CredentialsValid = true
+	for _, m := range data.Payload {
+		if m.Marketplace.ID != cred.MarketplaceID {
+			continue
+		}
+		if !m.Participation.IsParticipating {
+			result.Message = "seller no longer participates in marketplace " + cred.MarketplaceID
+			return result, nil
+		}
+		result.Healthy = true
+		return result, nil
+	}
+	result.Message = "marketplace " + cred.MarketplaceID + " not found"
+	return result, nil
+}
+
+func decodeCredential(credential *types.ShopCredential) (*AmazonCredential, error) {
+	var cred AmazonCredential
+	credData, err := json.Marshal(credential.Data)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to marshal credentials: %s", err.Error()))
+	}
+	if err := json.Unmarshal(credData, &cred); err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to unmarshal credentials: %s", err.Error()))
+	}
+	return &cred, nil
+}
+
+// requestToken 调用LWA获取或刷新令牌
+func (p *Amazon) requestToken(ctx context.Context, form url.Values) (*tokenResponse, error) {
+	form.Set("client_id", config.Config.Amazon.ClientID)
+	form.Set("client_secret", config.Config.Amazon.ClientSecret)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.Config.Amazon.TokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("failed to decode LWA response (status %d): %w", resp.StatusCode, err)
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("lwa error %s: %s", token.Error, token.ErrorDescription)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("lwa returned no access token (status %d)", resp.StatusCode)
+	}
+	return &token, nil
+}
+
+func applyToken(cred *AmazonCredential, token *tokenResponse) {
+	cred.AccessToken = token.AccessToken
+	// 刷新令牌时LWA不一定返回新的refresh_token
+	if token.RefreshToken != "" {
+		cred.RefreshToken = token.RefreshToken
+	}
+	cred.ExpiresAt = time.Now().Unix() + token.ExpiresIn
+}
+
+// ensureToken 访问令牌即将过期时刷新并保存到ShopLink
+func (p *Amazon) ensureToken(ctx context.Context, cred *AmazonCredential) error {
+	if time.Until(time.Unix(cred.ExpiresAt, 0)) > tokenRefreshBefore {
+		return nil
+	}
+
+	token, err := p.requestToken(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {cred.RefreshToken},
+	})
+	if err != nil {
+		return err
+	}
+	applyToken(cred, token)
+
+	shop, err := utils.FindShopByDomain(platformName, cred.SellingPartnerID)
+	if err != nil {
+		return err
+	}
+	credentialsJson, err := json.Marshal(cred)
+	if err != nil {
+		return err
+	}
+	return database.Database().Model(shop).Update("credentials", credentialsJson).Error
+}
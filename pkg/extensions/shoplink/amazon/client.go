@@ -0,0 +1,93 @@
+package amazon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/flaboy/aira-shop/pkg/config"
+)
+
+// apiError SP-API返回的错误
+type apiError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("amazon sp-api error %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// 访问令牌无效或授权已撤销
+func isAuthError(err error) bool {
+	if e, ok := err.(*apiError); ok {
+		return e.Status == http.StatusUnauthorized || e.Status == http.StatusForbidden
+	}
+	return false
+}
+
+// 超出接口速率限制
+func isThrottled(err error) bool {
+	if e, ok := err.(*apiError); ok {
+		return e.Status == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// call 发送SP-API请求，body为nil时不发送请求体
+func (p *Amazon) call(ctx context.Context, method, path string, query url.Values, body interface{}, cred *AmazonCredential, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	target := strings.TrimSuffix(config.Config.Amazon.Endpoint, "/") + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("x-amz-access-token", cred.AccessToken)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		e := &apiError{Status: resp.StatusCode, Message: string(respBody)}
+		var errs errorsResponse
+		if json.Unmarshal(respBody, &errs) == nil && len(errs.Errors) > 0 {
+			e.Code = errs.Errors[0].Code
+			e.Message = errs.Errors[0].Message
+		}
+		return e
+	}
+
+	if out != nil && len(respBody) > 0 {
+		return json.Unmarshal(respBody, out)
+	}
+	return nil
+}
@@ -0,0 +1,492 @@
+package amazon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/flaboy/aira-shop/pkg/events"
+	"github.com/flaboy/aira-shop/pkg/extensions/shoplink/utils"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
+	"github.com/flaboy/pin/usererrors"
+)
+
+// 主图之外最多8张附图
+const maxOtherImages = 8
+
+// 提交后超过该时间仍查不到listing时视为处理失败
+const pendingListingTimeout = 48 * time.Hour
+
+type AmazonRemoteData struct {
+	ProductType string
+	ParentSku   string
+	// 卖家SKU -> 内部变体ID
+	VariantMapper map[string]uint
+	// 卖家SKU -> 提交ID，Amazon异步处理listing
+	Submissions map[string]string
+	Images      []types.ImageResult
+	// Amazon处理listing时报告的错误
+	Error string `json:",omitempty"`
+}
+
+func (p *Amazon) PutProduct(credential *types.ShopCredential, product *types.ProductData, businessContext json.RawMessage) (*types.PutProductResult, error) {
+	cred, err := decodeCredential(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	// 商品创建后即上架，不支持草稿和定时发布
+	if err := utils.RequireImmediatePublish(product); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	defer cancel()
+
+	if err := p.ensureToken(ctx, cred); err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to refresh access token: %s", err.Error()))
+	}
+
+	shop, err := utils.FindShopByDomain(platformName, cred.SellingPartnerID)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to find shop: %s", err.Error()))
+	}
+
+	// 应用店铺覆盖规则，后续保存的是实际发布的数据
+	product, err = utils.ApplyShopOverrides(shop, product)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to apply shop overrides: %s", err.Error()))
+	}
+	if len(product.Variants) == 0 {
+		return nil, usererrors.New("Product has no variants")
+	}
+
+	productType := product.CategoryID
+	if productType == "" {
+		productType = config.Config.Amazon.ProductType
+	}
+
+	remote := &AmazonRemoteData{
+		ProductType:   productType,
+		VariantMapper: make(map[string]uint),
+		Submissions:   make(map[string]string),
+	}
+
+	// Amazon按URL抓取图片，只提交校验通过并去重后的图片
+	prepared, imageResults := utils.PrepareImages(ctx, product)
+	remote.Images = imageResults
+
+	// 多变体时先创建父商品，子商品通过variation_theme关联
+	hasVariations := len(product.Variants) > 1 && len(product.Options) > 0
+	theme := variationTheme(product.Options)
+	if hasVariations {
+		remote.ParentSku = fmt.Sprintf("aira-%d-parent", product.Variants[0].ID)
+		attributes := baseAttributes(cred, product, prepared)
+		attributes["parentage_level"] = []attributeValue{{"marketplace_id": cred.MarketplaceID, "value": "parent"}}
+		attributes["variation_theme"] = []attributeValue{{"name": theme}}
+
+		submissionID, err := p.putListingItem(ctx, cred, remote.ParentSku, productType, attributes)
+		if err != nil {
+			return nil, usererrors.New(fmt.Sprintf("Failed to create parent listing: %s", err.Error()))
+		}
+		remote.Submissions[remote.ParentSku] = submissionID
+	}
+
+	var firstSku string
+	for _, v := range product.Variants {
+		sku := v.Sku
+		if sku == "" {
+			sku = fmt.Sprintf("aira-%d", v.ID)
+		}
+		if firstSku == "" {
+			firstSku = sku
+		}
+
+		attributes := baseAttributes(cred, product, prepared)
+		addOfferAttributes(attributes, cred, v)
+		if hasVariations {
+			attributes["parentage_level"] = []attributeValue{{"marketplace_id": cred.MarketplaceID, "value": "child"}}
+			attributes["child_parent_sku_relationship"] = []attributeValue{{
+				"marketplace_id":          cred.MarketplaceID,
+				"child_relationship_type": "variation",
+				"parent_sku":              remote.ParentSku,
+			}}
+			attributes["variation_theme"] = []attributeValue{{"name": theme}}
+			for i, value := range []string{v.Option1, v.Option2, v.Option3} {
+				if value == "" || i >= len(product.Options) {
+					continue
+				}
+				attributes[attributeName(product.Options[i].Name)] = localized(cred, value)
+			}
+		}
+
+		submissionID, err := p.putListingItem(ctx, cred, sku, productType, attributes)
+		if err != nil {
+			return nil, usererrors.New(fmt.Sprintf("Failed to create listing %s: %s", sku, err.Error()))
+		}
+		remote.VariantMapper[sku] = v.ID
+		remote.Submissions[sku] = submissionID
+	}
+
+	// 单变体商品直接以其SKU作为外部ID
+	outerID := remote.ParentSku
+	if outerID == "" {
+		outerID = firstSku
+	}
+	listingUrl := "https://sellercentral.amazon.com/skucentral?mSku=" + url.QueryEscape(outerID)
+
+	// 提交已被接受，Amazon处理完成前listing不可售，由订单轮询时的checkPendingListings更新状态
+	shopProduct := models.ShopProduct{
+		ShopID:   shop.ID,
+		OuterID:  outerID,
+		Status:   models.ShopProductStatusPending,
+		Url:      listingUrl,
+		Name:     product.ProductName,
+		Platform: platformName,
+	}
+
+	productData, err := json.Marshal(product)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to marshal product data: %s", err.Error()))
+	}
+	shopProduct.Data = productData
+
+	remoteData, err := json.Marshal(remote)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to marshal remote data: %s", err.Error()))
+	}
+	shopProduct.RemoteData = remoteData
+
+	if err := database.Database().Create(&shopProduct).Error; err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to create shop product: %s", err.Error()))
+	}
+
+	events.EmitProductPublished(&types.ProductPublishedEvent{
+		ShopProductID: shopProduct.ID,
+		ShopID:        shop.ID,
+		Platform:      platformName,
+		OuterID:       outerID,
+		ProductData: map[string]interface{}{
+			"product_name": product.ProductName,
+			"body_html":    product.BodyHTML,
+			"tags":         product.Tags,
+		},
+		BusinessContext: businessContext,
+		CreatedAt:       time.Now(),
+	})
+
+	return &types.PutProductResult{
+		CommandResult: types.CommandResult{
+			Success: true,
+			Message: "Listing submitted successfully",
+		},
+		OuterID:    outerID,
+		Url:        listingUrl,
+		RemoteData: remote,
+	}, nil
+}
+
+// putListingItem 创建或替换卖家SKU对应的listing，返回提交ID
+func (p *Amazon) putListingItem(ctx context.Context, cred *AmazonCredential, sku, productType string, attributes map[string]interface{}) (string, error) {
+	query := url.Values{}
+	query.Set("marketplaceIds", cred.MarketplaceID)
+	query.Set("issueLocale", config.Config.Amazon.LanguageTag)
+
+	path := fmt.Sprintf("/listings/2021-08-01/items/%s/%s", url.PathEscape(cred.SellingPartnerID), url.PathEscape(sku))
+	var resp listingResponse
+	err := p.call(ctx, http.MethodPut, path, query, &listingRequest{
+		ProductType:  productType,
+		Requirements: "LISTING",
+		Attributes:   attributes,
+	}, cred, &resp)
+	if err != nil {
+		return "", err
+	}
+
+	var problems []string
+	for _, issue := range resp.Issues {
+		if issue.Severity == "ERROR" {
+			problems = append(problems, fmt.Sprintf("%s: %s", issue.Code, issue.Message))
+		}
+	}
+	if resp.Status != "ACCEPTED" || len(problems) > 0 {
+		return "", fmt.Errorf("listing %s rejected (%s): %s", sku, resp.Status, strings.Join(problems, "; "))
+	}
+	return resp.SubmissionID, nil
+}
+
+// checkPendingListings 查询已提交、等待Amazon处理的商品，所有SKU可售后改为上架
+// 处理时报告错误或超时仍未创建的标记为失败，并通知业务系统
+func (p *Amazon) checkPendingListings(shop *models.ShopLink) error {
+	var products []models.ShopProduct
+	err := database.Database().
+		Where("shop_id = ? AND platform = ? AND status = ?", shop.ID, platformName, models.ShopProductStatusPending).
+		Find(&products).Error
+	if err != nil || len(products) == 0 {
+		return err
+	}
+
+	var cred AmazonCredential
+	if err := json.Unmarshal(shop.Credentials, &cred); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if err := p.ensureToken(ctx, &cred); err != nil {
+		return fmt.Errorf("failed to refresh access token: %w", err)
+	}
+
+	for i := range products {
+		if err := p.checkPendingListing(ctx, &cred, &products[i]); err != nil {
+			if isThrottled(err) {
+				slog.Warn("Amazon getListingsItem throttled, resuming next round", "shop_id", shop.ID)
+				return nil
+			}
+			slog.Error("Failed to check Amazon listing", "shop_product_id", products[i].ID, "error", err)
+		}
+	}
+	return nil
+}
+
+func (p *Amazon) checkPendingListing(ctx context.Context, cred *AmazonCredential, product *models.ShopProduct) error {
+	var remote AmazonRemoteData
+	if err := json.Unmarshal(product.RemoteData, &remote); err != nil {
+		return err
+	}
+
+	skus := make([]string, 0, len(remote.Submissions))
+	for sku := range remote.Submissions {
+		skus = append(skus, sku)
+	}
+	sort.Strings(skus)
+
+	buyable := true
+	var problems []string
+	for _, sku := range skus {
+		item, err := p.getListingItem(ctx, cred, sku)
+		if err != nil {
+			return err
+		}
+		if item == nil {
+			buyable = false
+			continue
+		}
+		for _, issue := range item.Issues {
+			if issue.Severity == "ERROR" {
+				problems = append(problems, fmt.Sprintf("%s %s: %s", sku, issue.Code, issue.Message))
+			}
+		}
+		// 父商品本身不可售，只看子商品和单变体商品
+		if _, ok := remote.VariantMapper[sku]; ok && !item.buyable(cred.MarketplaceID) {
+			buyable = false
+		}
+	}
+
+	switch {
+	case len(problems) > 0:
+		return failPendingListing(product, &remote, strings.Join(problems, "; "))
+	case buyable:
+		return database.Database().Model(&models.ShopProduct{}).
+			Where("id = ? AND status = ?", product.ID, models.ShopProductStatusPending).
+			Update("status", models.ShopProductStatusActive).Error
+	case time.Since(product.CreatedAt) > pendingListingTimeout:
+		return failPendingListing(product, &remote, "listing was not created by Amazon within 48 hours")
+	}
+	return nil
+}
+
+// getListingItem 查询卖家SKU的listing摘要和问题，Amazon尚未创建listing时返回nil
+func (p *Amazon) getListingItem(ctx context.Context, cred *AmazonCredential, sku string) (*listingItem, error) {
+	query := url.Values{}
+	query.Set("marketplaceIds", cred.MarketplaceID)
+	query.Set("includedData", "summaries,issues")
+	query.Set("issueLocale", config.Config.Amazon.LanguageTag)
+
+	path := fmt.Sprintf("/listings/2021-08-01/items/%s/%s", url.PathEscape(cred.SellingPartnerID), url.PathEscape(sku))
+	var item listingItem
+	if err := p.call(ctx, http.MethodGet, path, query, nil, cred, &item); err != nil {
+		if e, ok := err.(*apiError); ok && e.Status == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (item *listingItem) buyable(marketplaceID string) bool {
+	for _, summary := range item.Summaries {
+		if summary.MarketplaceID == marketplaceID && slices.Contains(summary.Status, "BUYABLE") {
+			return true
+		}
+	}
+	return false
+}
+
+// failPendingListing 以status条件更新，同一商品只通知一次
+func failPendingListing(product *models.ShopProduct, remote *AmazonRemoteData, reason string) error {
+	remote.Error = reason
+	remoteData, err := json.Marshal(remote)
+	if err != nil {
+		return err
+	}
+	result := database.Database().Model(&models.ShopProduct{}).
+		Where("id = ? AND status = ?", product.ID, models.ShopProductStatusPending).
+		Updates(map[string]interface{}{
+			"status":      models.ShopProductStatusFailed,
+			"remote_data": remoteData,
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+
+	slog.Warn("Amazon listing failed", "shop_product_id", product.ID, "sku", product.OuterID, "reason", reason)
+	var data types.ProductData
+	json.Unmarshal(product.Data, &data)
+	return events.EmitProductPublishFailed(&types.ProductPublishFailedEvent{
+		ShopProductID: product.ID,
+		ShopID:        product.ShopID,
+		Platform:      platformName,
+		Error:         reason,
+		ProductData: map[string]interface{}{
+			"product_name": data.ProductName,
+			"body_html":    data.BodyHTML,
+			"tags":         data.Tags,
+		},
+		CreatedAt: time.Now(),
+	})
+}
+
+// baseAttributes 父子商品共用的属性，第一张图片为主图
+func baseAttributes(cred *AmazonCredential, product *types.ProductData, images []*utils.PreparedImage) map[string]interface{} {
+	attributes := map[string]interface{}{
+		"item_name":           localized(cred, product.ProductName),
+		"product_description": localized(cred, product.BodyHTML),
+		"condition_type":      []attributeValue{{"marketplace_id": cred.MarketplaceID, "value": "new_new"}},
+		"brand":               localized(cred, config.Config.Amazon.DefaultBrand),
+		"supplier_declared_has_product_identifier_exemption": []attributeValue{{"marketplace_id": cred.MarketplaceID, "value": true}},
+	}
+
+	// 产品属性按名称转为Amazon属性，可覆盖上面的默认值（如brand）
+	for _, attr := range product.Attributes {
+		var values []attributeValue
+		for _, value := range attr.Values {
+			values = append(values, attributeValue{
+				"marketplace_id": cred.MarketplaceID,
+				"language_tag":   config.Config.Amazon.LanguageTag,
+				"value":          value,
+			})
+		}
+		if len(values) > 0 {
+			attributes[attributeName(attr.Name)] = values
+		}
+	}
+
+	if len(product.Tags) > 0 {
+		var keywords []string
+		for _, tag := range strings.Split(product.Tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				keywords = append(keywords, tag)
+			}
+		}
+		if len(keywords) > 0 {
+			attributes["generic_keyword"] = localized(cred, strings.Join(keywords, " "))
+		}
+	}
+
+	for i, img := range images {
+		key := "main_product_image_locator"
+		if i > 0 {
+			if i > maxOtherImages {
+				break
+			}
+			key = fmt.Sprintf("other_product_image_locator_%d", i)
+		}
+		attributes[key] = []attributeValue{{"marketplace_id": cred.MarketplaceID, "media_location": img.Image.Src}}
+	}
+
+	return attributes
+}
+
+// addOfferAttributes 价格、库存和重量按变体设置
+func addOfferAttributes(attributes map[string]interface{}, cred *AmazonCredential, v types.ProductVariant) {
+	if v.Price != nil {
+		attributes["purchasable_offer"] = []attributeValue{{
+			"marketplace_id": cred.MarketplaceID,
+			"currency":       cred.Currency,
+			"our_price": []attributeValue{{
+				"schedule": []attributeValue{{"value_with_tax": v.Price.StringFixed(2)}},
+			}},
+		}}
+	}
+
+	quantity := v.InventoryQuantity
+	if quantity <= 0 {
+		quantity = config.Config.Amazon.DefaultInventory
+	}
+	attributes["fulfillment_availability"] = []attributeValue{{
+		"fulfillment_channel_code": "DEFAULT",
+		"quantity":                 quantity,
+	}}
+
+	if v.Weight != nil {
+		if unit := weightUnit(v.WeightUnit); unit != "" {
+			attributes["item_package_weight"] = []attributeValue{{
+				"marketplace_id": cred.MarketplaceID,
+				"value":          v.Weight.String(),
+				"unit":           unit,
+			}}
+		}
+	}
+}
+
+func localized(cred *AmazonCredential, value string) []attributeValue {
+	return []attributeValue{{
+		"marketplace_id": cred.MarketplaceID,
+		"language_tag":   config.Config.Amazon.LanguageTag,
+		"value":          value,
+	}}
+}
+
+// attributeName 选项/属性名转为Amazon属性名，如 "Color" -> "color"
+func attributeName(name string) string {
+	name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+	if name == "colour" {
+		return "color"
+	}
+	return name
+}
+
+// variationTheme 由选项名组合变体主题，如 SIZE/COLOR
+func variationTheme(options []types.ProductOption) string {
+	var names []string
+	for _, option := range options {
+		names = append(names, strings.ToUpper(attributeName(option.Name)))
+	}
+	return strings.Join(names, "/")
+}
+
+func weightUnit(unit string) string {
+	switch strings.ToLower(unit) {
+	case "g":
+		return "grams"
+	case "kg":
+		return "kilograms"
+	case "lb":
+		return "pounds"
+	case "oz":
+		return "ounces"
+	}
+	return ""
+}
@@ -0,0 +1,341 @@
+package amazon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/events"
+	"github.com/flaboy/aira-shop/pkg/extensions/shoplink/utils"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ordersCheckpoint = "orders"
+	// 首次轮询回溯的时间
+	ordersInitialLookback = 24 * time.Hour
+	// 每次轮询与上次进度重叠的时间，避免平台更新时间延迟导致漏单
+	ordersOverlap = 5 * time.Minute
+	// getOrders恢复速率约每分钟1次，翻页之间稍作等待
+	ordersPageInterval = 2 * time.Second
+)
+
+// 已付款的订单状态，Pending订单尚未完成支付
+var paidOrderStatuses = []string{"Unshipped", "PartiallyShipped", "Shipped"}
+
+// StartOrderPoller 定期拉取所有Amazon店铺的订单，并检查等待处理的listing
+func (p *Amazon) StartOrderPoller(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.pollAllShops()
+		<-ticker.C
+	}
+}
+
+func (p *Amazon) pollAllShops() {
+	var shops []models.ShopLink
+	if err := database.Database().Where("platform = ?", platformName).Find(&shops).Error; err != nil {
+		slog.Error("Failed to load Amazon shops", "error", err)
+		return
+	}
+
+	for i := range shops {
+		if err := p.pollShop(&shops[i]); err != nil {
+			slog.Error("Failed to poll Amazon orders", "shop_id", shops[i].ID, "error", err)
+		}
+		if err := p.checkPendingListings(&shops[i]); err != nil {
+			slog.Error("Failed to check pending Amazon listings", "shop_id", shops[i].ID, "error", err)
+		}
+	}
+}
+
+// pollShop 拉取上次进度之后更新的已付款订单，每个订单只通知一次
+// 所有分页处理完成后才保存进度，中途限流或出错时保留原进度，下一轮重新拉取
+func (p *Amazon) pollShop(shop *models.ShopLink) error {
+	var cred AmazonCredential
+	if err := json.Unmarshal(shop.Credentials, &cred); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	if err := p.ensureToken(ctx, &cred); err != nil {
+		return fmt.Errorf("failed to refresh access token: %w", err)
+	}
+
+	checkpoint, err := utils.GetSyncCheckpoint(shop.ID, ordersCheckpoint)
+	if err != nil {
+		return err
+	}
+	// 进度只向前推进，重叠时间只用于查询条件
+	latest := time.Now().Add(-ordersInitialLookback)
+	since := latest
+	if checkpoint != nil {
+		latest = checkpoint.SyncedAt
+		since = latest.Add(-ordersOverlap)
+	}
+
+	var skus map[string]skuMapping
+	nextToken := ""
+	for {
+		query := url.Values{}
+		query.Set("MarketplaceIds", cred.MarketplaceID)
+		if nextToken != "" {
+			query.Set("NextToken", nextToken)
+		} else {
+			query.Set("LastUpdatedAfter", since.UTC().Format(time.RFC3339))
+			query.Set("OrderStatuses", strings.Join(paidOrderStatuses, ","))
+		}
+
+		var data ordersResponse
+		if err := p.call(ctx, http.MethodGet, "/orders/v0/orders", query, nil, &cred, &data); err != nil {
+			if isThrottled(err) {
+				slog.Warn("Amazon getOrders throttled, resuming next round", "shop_id", shop.ID)
+				return nil
+			}
+			return err
+		}
+
+		// 已通知的订单由MarkOrderSynced去重，重新拉取不会重复通知
+		for i := range data.Payload.Orders {
+			o := &data.Payload.Orders[i]
+			if skus == nil {
+				if skus, err = loadSkuMappings(shop.ID); err != nil {
+					return err
+				}
+			}
+			if err := p.emitOrder(ctx, shop, &cred, o, skus); err != nil {
+				if isThrottled(err) {
+					slog.Warn("Amazon getOrderItems throttled, resuming next round", "shop_id", shop.ID)
+					return nil
+				}
+				return err
+			}
+			if updated, err := time.Parse(time.RFC3339, o.LastUpdateDate); err == nil && updated.After(latest) {
+				latest = updated
+			}
+		}
+
+		nextToken = data.Payload.NextToken
+		if nextToken == "" {
+			break
+		}
+		time.Sleep(ordersPageInterval)
+	}
+
+	return utils.SaveSyncCheckpoint(shop.ID, ordersCheckpoint, latest, "")
+}
+
+func (p *Amazon) emitOrder(ctx context.Context, shop *models.ShopLink, cred *AmazonCredential, o *order, skus map[string]skuMapping) error {
+	isNew, err := utils.MarkOrderSynced(shop.ID, o.AmazonOrderID)
+	if err != nil || !isNew {
+		return err
+	}
+
+	orderData, err := p.convertOrder(ctx, cred, o, skus)
+	if err == nil {
+		err = events.EmitOrderReceived(&types.OrderReceivedEvent{
+			Platform:  platformName,
+			OrderData: *orderData,
+			ShopID:    shop.ID,
+			CreatedAt: time.Now(),
+		})
+	}
+	if err != nil {
+		if unmarkErr := utils.UnmarkOrderSynced(shop.ID, o.AmazonOrderID); unmarkErr != nil {
+			slog.Error("Failed to unmark Amazon order", "order_id", o.AmazonOrderID, "error", unmarkErr)
+		}
+		return err
+	}
+
+	fmt.Printf("Amazon order %s received for shop %d\n", o.AmazonOrderID, shop.ID)
+	return nil
+}
+
+// skuMapping 卖家SKU对应的已发布商品和内部变体
+type skuMapping struct {
+	ProductOuterID string
+	VariantID      uint
+}
+
+// loadSkuMappings 订单只返回卖家SKU，从店铺已发布商品中建立SKU索引
+func loadSkuMappings(shopID uint) (map[string]skuMapping, error) {
+	var products []models.ShopProduct
+	if err := database.Database().Where("shop_id = ? AND platform = ?", shopID, platformName).Find(&products).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]skuMapping)
+	for _, product := range products {
+		rm := AmazonRemoteData{}
+		if err := json.Unmarshal(product.RemoteData, &rm); err != nil {
+			slog.Warn("Invalid Amazon remote data", "shop_product_id", product.ID, "error", err)
+			continue
+		}
+		for sku, variantID := range rm.VariantMapper {
+			result[sku] = skuMapping{ProductOuterID: product.OuterID, VariantID: variantID}
+		}
+	}
+	return result, nil
+}
+
+// convertOrder 将Amazon订单转换为统一订单结构，只保留本系统发布的商品
+func (p *Amazon) convertOrder(ctx context.Context, cred *AmazonCredential, o *order, skus map[string]skuMapping) (*types.OrderData, error) {
+	items, err := p.orderItems(ctx, cred, o.AmazonOrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	orderData := &types.OrderData{
+		ID:                o.AmazonOrderID,
+		Name:              o.AmazonOrderID,
+		FinancialStatus:   types.OrderFinancialStatusPaid,
+		FulfillmentStatus: convertFulfillmentStatus(o.OrderStatus),
+		TotalPrice:        o.OrderTotal.Decimal(),
+		RawData: map[string]interface{}{
+			"source_name": platformName,
+			"order":       o,
+		},
+	}
+	if o.OrderTotal != nil {
+		orderData.Currency = o.OrderTotal.CurrencyCode
+	}
+	if t, err := time.Parse(time.RFC3339, o.PurchaseDate); err == nil {
+		orderData.CreatedAt = &t
+	}
+	if t, err := time.Parse(time.RFC3339, o.LastUpdateDate); err == nil {
+		orderData.UpdatedAt = &t
+	}
+
+	// 买家信息和收货地址需要应用具有对应的PII角色才会返回
+	customer := &types.OrderCustomer{}
+	if o.BuyerInfo != nil {
+		orderData.Email = o.BuyerInfo.BuyerEmail
+		customer.Email = o.BuyerInfo.BuyerEmail
+		customer.FirstName, customer.LastName = splitName(o.BuyerInfo.BuyerName)
+	}
+	orderData.Customer = customer
+	if a := o.ShippingAddress; a != nil {
+		firstName, lastName := splitName(a.Name)
+		orderData.Phone = a.Phone
+		orderData.ShippingAddress = &types.OrderAddress{
+			FirstName:   firstName,
+			LastName:    lastName,
+			Address1:    a.AddressLine1,
+			Address2:    a.AddressLine2,
+			City:        a.City,
+			Province:    a.StateOrRegion,
+			CountryCode: a.CountryCode,
+			Zip:         a.PostalCode,
+			Phone:       a.Phone,
+		}
+	}
+
+	subtotal := decimal.Zero
+	shipping := decimal.Zero
+	tax := decimal.Zero
+	for _, item := range items {
+		if d := item.ItemPrice.Decimal(); d != nil {
+			subtotal = subtotal.Add(*d)
+		}
+		if d := item.ShippingPrice.Decimal(); d != nil {
+			shipping = shipping.Add(*d)
+		}
+		if d := item.ItemTax.Decimal(); d != nil {
+			tax = tax.Add(*d)
+		}
+		if d := item.ShippingTax.Decimal(); d != nil {
+			tax = tax.Add(*d)
+		}
+
+		mapping, ok := skus[item.SellerSKU]
+		if !ok {
+			fmt.Printf("Amazon SKU %s not published by this system, skipping order item %s\n", item.SellerSKU, item.OrderItemID)
+			continue
+		}
+
+		// ItemPrice为该行总价，换算为单价
+		price := item.ItemPrice.Decimal()
+		if price != nil && item.QuantityOrdered > 1 {
+			unit := price.Div(decimal.NewFromInt(int64(item.QuantityOrdered)))
+			price = &unit
+		}
+
+		orderData.LineItems = append(orderData.LineItems, types.OrderLineItem{
+			ID:        item.OrderItemID,
+			ProductID: mapping.ProductOuterID,
+			VariantID: mapping.VariantID,
+			Title:     item.Title,
+			SKU:       item.SellerSKU,
+			Quantity:  item.QuantityOrdered,
+			Price:     price,
+			RawData:   map[string]interface{}{"order_item": item},
+		})
+	}
+	orderData.SubtotalPrice = &subtotal
+	orderData.TotalShipping = &shipping
+	orderData.TotalTax = &tax
+
+	if o.ShipServiceLevel != "" {
+		orderData.ShippingLines = append(orderData.ShippingLines, types.OrderShippingLine{
+			Code:   o.ShipServiceLevel,
+			Title:  o.ShipServiceLevel,
+			Price:  &shipping,
+			Source: platformName,
+		})
+	}
+
+	return orderData, nil
+}
+
+func (p *Amazon) orderItems(ctx context.Context, cred *AmazonCredential, orderID string) ([]orderItem, error) {
+	var items []orderItem
+	nextToken := ""
+	for {
+		var query url.Values
+		if nextToken != "" {
+			query = url.Values{"NextToken": {nextToken}}
+		}
+
+		var data orderItemsResponse
+		if err := p.call(ctx, http.MethodGet, "/orders/v0/orders/"+url.PathEscape(orderID)+"/orderItems", query, nil, cred, &data); err != nil {
+			return nil, err
+		}
+		items = append(items, data.Payload.OrderItems...)
+
+		nextToken = data.Payload.NextToken
+		if nextToken == "" {
+			return items, nil
+		}
+	}
+}
+
+func convertFulfillmentStatus(status string) types.OrderFulfillmentStatus {
+	switch status {
+	case "Shipped":
+		return types.OrderFulfillmentStatusFulfilled
+	case "PartiallyShipped":
+		return types.OrderFulfillmentStatusPartial
+	default:
+		return types.OrderFulfillmentStatusUnfulfilled
+	}
+}
+
+func splitName(name string) (string, string) {
+	name = strings.TrimSpace(name)
+	if i := strings.LastIndex(name, " "); i > 0 {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
@@ -0,0 +1,153 @@
+package amazon
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// https://developer-docs.amazon.com/sp-api/docs
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type errorsResponse struct {
+	Errors []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Details string `json:"details"`
+	} `json:"errors"`
+}
+
+type marketplaceParticipation struct {
+	Marketplace struct {
+		ID                  string `json:"id"`
+		Name                string `json:"name"`
+		CountryCode         string `json:"countryCode"`
+		DefaultCurrencyCode string `json:"defaultCurrencyCode"`
+		DomainName          string `json:"domainName"`
+	} `json:"marketplace"`
+	Participation struct {
+		IsParticipating      bool `json:"isParticipating"`
+		HasSuspendedListings bool `json:"hasSuspendedListings"`
+	} `json:"participation"`
+	StoreName string `json:"storeName"`
+}
+
+type participationsResponse struct {
+	Payload []marketplaceParticipation `json:"payload"`
+}
+
+type money struct {
+	CurrencyCode string `json:"CurrencyCode"`
+	Amount       string `json:"Amount"`
+}
+
+// Decimal 金额为字符串形式
+func (m *money) Decimal() *decimal.Decimal {
+	if m == nil || m.Amount == "" {
+		return nil
+	}
+	d, err := decimal.NewFromString(m.Amount)
+	if err != nil {
+		return nil
+	}
+	return &d
+}
+
+type ordersResponse struct {
+	Payload struct {
+		Orders            []order `json:"Orders"`
+		NextToken         string  `json:"NextToken"`
+		LastUpdatedBefore string  `json:"LastUpdatedBefore"`
+	} `json:"payload"`
+}
+
+type order struct {
+	AmazonOrderID      string   `json:"AmazonOrderId"`
+	PurchaseDate       string   `json:"PurchaseDate"`
+	LastUpdateDate     string   `json:"LastUpdateDate"`
+	OrderStatus        string   `json:"OrderStatus"`
+	FulfillmentChannel string   `json:"FulfillmentChannel"`
+	SalesChannel       string   `json:"SalesChannel"`
+	ShipServiceLevel   string   `json:"ShipServiceLevel"`
+	OrderTotal         *money   `json:"OrderTotal"`
+	MarketplaceID      string   `json:"MarketplaceId"`
+	BuyerInfo          *buyer   `json:"BuyerInfo"`
+	ShippingAddress    *address `json:"ShippingAddress"`
+}
+
+type buyer struct {
+	BuyerEmail string `json:"BuyerEmail"`
+	BuyerName  string `json:"BuyerName"`
+}
+
+type address struct {
+	Name          string `json:"Name"`
+	AddressLine1  string `json:"AddressLine1"`
+	AddressLine2  string `json:"AddressLine2"`
+	City          string `json:"City"`
+	StateOrRegion string `json:"StateOrRegion"`
+	PostalCode    string `json:"PostalCode"`
+	CountryCode   string `json:"CountryCode"`
+	Phone         string `json:"Phone"`
+}
+
+type orderItemsResponse struct {
+	Payload struct {
+		OrderItems []orderItem `json:"OrderItems"`
+		NextToken  string      `json:"NextToken"`
+	} `json:"payload"`
+}
+
+type orderItem struct {
+	ASIN            string `json:"ASIN"`
+	SellerSKU       string `json:"SellerSKU"`
+	OrderItemID     string `json:"OrderItemId"`
+	Title           string `json:"Title"`
+	QuantityOrdered int    `json:"QuantityOrdered"`
+	ItemPrice       *money `json:"ItemPrice"`
+	ShippingPrice   *money `json:"ShippingPrice"`
+	ItemTax         *money `json:"ItemTax"`
+	ShippingTax     *money `json:"ShippingTax"`
+}
+
+// listingItem Listings Items API的getListingsItem响应
+type listingItem struct {
+	Sku       string           `json:"sku"`
+	Summaries []listingSummary `json:"summaries"`
+	Issues    []listingIssue   `json:"issues"`
+}
+
+type listingSummary struct {
+	MarketplaceID string   `json:"marketplaceId"`
+	Status        []string `json:"status"` // BUYABLE, DISCOVERABLE
+}
+
+// listingRequest Listings Items API的putListingsItem请求
+type listingRequest struct {
+	ProductType  string                 `json:"productType"`
+	Requirements string                 `json:"requirements,omitempty"`
+	Attributes   map[string]interface{} `json:"attributes"`
+}
+
+type listingResponse struct {
+	Sku          string         `json:"sku"`
+	Status       string         `json:"status"` // ACCEPTED, INVALID
+	SubmissionID string         `json:"submissionId"`
+	Issues       []listingIssue `json:"issues"`
+}
+
+type listingIssue struct {
+	Code           string   `json:"code"`
+	Message        string   `json:"message"`
+	Severity       string   `json:"severity"` // ERROR, WARNING, INFO
+	AttributeNames []string `json:"attributeNames"`
+}
+
+// attributeValue 属性值通用结构，具体字段随属性不同
+type attributeValue map[string]interface{}
@@ -0,0 +1,390 @@
+package shoplink
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/errors"
+	"github.com/flaboy/aira-shop/pkg/events"
+	"github.com/flaboy/aira-shop/pkg/extensions/shoplink/utils"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPublishConcurrency = 5
+	// 同一店铺同时进行的发布数，跨任务生效，避免打满平台API限额
+	perShopPublishConcurrency = 1
+)
+
+// BulkPublishOptions 批量发布选项
+type BulkPublishOptions struct {
+	// 同时发布的店铺数，默认5
+	Concurrency int
+}
+
+var (
+	runningJobs sync.Map // jobID -> struct{}
+	shopSlots   sync.Map // shopID -> chan struct{}
+)
+
+// BulkPublish 为商户创建批量发布任务并在后台执行，返回已持久化的任务
+// 任一店铺不属于该商户时不创建任务
+func BulkPublish(ownerID string, product *types.ProductData, shopIDs []uint, businessContext json.RawMessage, opts *BulkPublishOptions) (*models.PublishJob, error) {
+	if len(shopIDs) == 0 {
+		return nil, errors.ErrPublishNoShops
+	}
+
+	productJson, err := json.Marshal(product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal product: %w", err)
+	}
+
+	concurrency := defaultPublishConcurrency
+	if opts != nil && opts.Concurrency > 0 {
+		concurrency = opts.Concurrency
+	}
+
+	// 去重，同一店铺只发布一次
+	seen := make(map[uint]bool)
+	var uniqueShopIDs []uint
+	for _, id := range shopIDs {
+		if !seen[id] {
+			seen[id] = true
+			uniqueShopIDs = append(uniqueShopIDs, id)
+		}
+	}
+
+	var owned int64
+	if err := database.Database().Model(&models.ShopLink{}).
+		Where("id IN ? AND owner_id = ?", uniqueShopIDs, ownerID).
+		Count(&owned).Error; err != nil {
+		return nil, err
+	}
+	if int(owned) != len(uniqueShopIDs) {
+		return nil, errors.ErrShopNotFound
+	}
+
+	job := &models.PublishJob{
+		OwnerID:         ownerID,
+		Status:          models.PublishJobStatusPending,
+		Product:         productJson,
+		BusinessContext: businessContext,
+		Concurrency:     concurrency,
+		Total:           len(uniqueShopIDs),
+	}
+
+	err = database.Database().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		items := make([]models.PublishJobItem, 0, len(uniqueShopIDs))
+		for _, shopID := range uniqueShopIDs {
+			items = append(items, models.PublishJobItem{
+				JobID:  job.ID,
+				ShopID: shopID,
+				Status: models.PublishItemStatusPending,
+			})
+		}
+		return tx.Create(&items).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create publish job: %w", err)
+	}
+
+	go runPublishJob(job.ID)
+
+	return job, nil
+}
+
+// GetPublishJob 获取商户的批量发布任务及各店铺的发布状态
+func GetPublishJob(ownerID string, jobID uint) (*models.PublishJob, []models.PublishJobItem, error) {
+	db := database.Database()
+
+	var job models.PublishJob
+	if err := db.Where("id = ? AND owner_id = ?", jobID, ownerID).First(&job).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, errors.ErrPublishJobNotFound
+		}
+		return nil, nil, err
+	}
+
+	var items []models.PublishJobItem
+	if err := db.Where("job_id = ?", jobID).Order("id").Find(&items).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return &job, items, nil
+}
+
+// ResumePublishJobs 恢复进程退出时尚未完成的批量发布任务
+func ResumePublishJobs() error {
+	db := database.Database()
+
+	if err := recoverInterruptedItems(); err != nil {
+		return err
+	}
+
+	var jobs []models.PublishJob
+	if err := db.Where("status IN ?", []string{models.PublishJobStatusPending, models.PublishJobStatusRunning}).Find(&jobs).Error; err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		slog.Info("Resuming publish job", "jobID", job.ID)
+		go runPublishJob(job.ID)
+	}
+	return nil
+}
+
+// recoverInterruptedItems 处理进程退出时正在执行的店铺
+// PutProduct可能已在平台创建商品，重新发布会产生重复商品，因此不重新排队：
+// 平台保存的商品名是应用店铺覆盖规则后的标题，找到该店铺在执行后创建的同名商品时视为成功，
+// 否则标记失败，由人工确认后重试
+func recoverInterruptedItems() error {
+	db := database.Database()
+
+	var items []models.PublishJobItem
+	if err := db.Where("status = ?", models.PublishItemStatusRunning).Find(&items).Error; err != nil {
+		return err
+	}
+
+	for i := range items {
+		item := &items[i]
+		var job models.PublishJob
+		if err := db.First(&job, item.JobID).Error; err != nil {
+			return err
+		}
+		var product types.ProductData
+		if err := json.Unmarshal(job.Product, &product); err != nil {
+			return err
+		}
+		name, err := publishedName(item.ShopID, &product)
+		if err != nil {
+			return err
+		}
+
+		var shopProduct models.ShopProduct
+		err = db.Where("shop_id = ? AND name = ? AND created_at >= ?", item.ShopID, name, item.UpdatedAt).
+			Order("id DESC").
+			First(&shopProduct).Error
+		updates := map[string]interface{}{
+			"status": models.PublishItemStatusFailed,
+			"error":  "publish interrupted by restart, verify the product on the platform before retrying",
+		}
+		if err == nil {
+			updates = map[string]interface{}{
+				"status":   models.PublishItemStatusSucceeded,
+				"outer_id": shopProduct.OuterID,
+				"url":      shopProduct.Url,
+				"error":    "",
+			}
+		} else if err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		slog.Warn("Recovering interrupted publish item", "jobID", item.JobID, "shopID", item.ShopID, "status", updates["status"])
+		if err := db.Model(item).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishedName 按店铺当前的覆盖规则计算平台保存的商品名，店铺已删除时使用原标题
+func publishedName(shopID uint, product *types.ProductData) (string, error) {
+	var shop models.ShopLink
+	if err := database.Database().First(&shop, shopID).Error; err == gorm.ErrRecordNotFound {
+		return product.ProductName, nil
+	} else if err != nil {
+		return "", err
+	}
+	overridden, err := utils.ApplyShopOverrides(&shop, product)
+	if err != nil {
+		return "", err
+	}
+	return overridden.ProductName, nil
+}
+
+func runPublishJob(jobID uint) {
+	if _, loaded := runningJobs.LoadOrStore(jobID, struct{}{}); loaded {
+		return
+	}
+	defer runningJobs.Delete(jobID)
+
+	db := database.Database()
+
+	var job models.PublishJob
+	if err := db.First(&job, jobID).Error; err != nil {
+		slog.Error("Failed to load publish job", "jobID", jobID, "error", err)
+		return
+	}
+
+	var product types.ProductData
+	if err := json.Unmarshal(job.Product, &product); err != nil {
+		slog.Error("Failed to unmarshal publish job product", "jobID", jobID, "error", err)
+		return
+	}
+
+	if err := db.Model(&job).Update("status", models.PublishJobStatusRunning).Error; err != nil {
+		slog.Error("Failed to update publish job status", "jobID", jobID, "error", err)
+		return
+	}
+
+	var items []models.PublishJobItem
+	if err := db.Where("job_id = ? AND status = ?", jobID, models.PublishItemStatusPending).Find(&items).Error; err != nil {
+		slog.Error("Failed to load publish job items", "jobID", jobID, "error", err)
+		return
+	}
+
+	concurrency := job.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultPublishConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(item *models.PublishJobItem) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			runPublishItem(&job, item, &product)
+		}(&items[i])
+	}
+	wg.Wait()
+
+	finishPublishJob(&job)
+}
+
+func runPublishItem(job *models.PublishJob, item *models.PublishJobItem, product *types.ProductData) {
+	release := acquireShopSlot(item.ShopID)
+	defer release()
+
+	db := database.Database()
+	if err := db.Model(item).Updates(map[string]interface{}{
+		"status":   models.PublishItemStatusRunning,
+		"attempts": gorm.Expr("attempts + 1"),
+	}).Error; err != nil {
+		slog.Error("Failed to update publish job item", "jobID", job.ID, "shopID", item.ShopID, "error", err)
+		return
+	}
+
+	platformName, result, err := publishToShop(job.OwnerID, item.ShopID, product, job.BusinessContext)
+	if err != nil {
+		slog.Error("Failed to publish product to shop", "jobID", job.ID, "shopID", item.ShopID, "error", err)
+		if saveErr := db.Model(item).Updates(map[string]interface{}{
+			"status": models.PublishItemStatusFailed,
+			"error":  err.Error(),
+		}).Error; saveErr != nil {
+			slog.Error("Failed to update publish job item", "jobID", job.ID, "shopID", item.ShopID, "error", saveErr)
+		}
+
+		events.EmitProductPublishFailed(&types.ProductPublishFailedEvent{
+			JobID:    job.ID,
+			ShopID:   item.ShopID,
+			Platform: platformName,
+			Error:    err.Error(),
+			ProductData: map[string]interface{}{
+				"product_name": product.ProductName,
+				"body_html":    product.BodyHTML,
+				"tags":         product.Tags,
+			},
+			BusinessContext: job.BusinessContext,
+			CreatedAt:       time.Now(),
+		})
+		return
+	}
+
+	// 发布成功事件由平台的PutProduct触发
+	if err := db.Model(item).Updates(map[string]interface{}{
+		"status":   models.PublishItemStatusSucceeded,
+		"outer_id": result.OuterID,
+		"url":      result.Url,
+		"error":    "",
+	}).Error; err != nil {
+		slog.Error("Failed to update publish job item", "jobID", job.ID, "shopID", item.ShopID, "error", err)
+	}
+}
+
+// publishToShop 执行时再次检查店铺归属，任务创建后店铺可能已被其他商户重新连接
+func publishToShop(ownerID string, shopID uint, product *types.ProductData, businessContext json.RawMessage) (string, *types.PutProductResult, error) {
+	shop, err := GetShop(ownerID, shopID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	platform := Get(shop.Platform)
+	if platform == nil {
+		return shop.Platform, nil, errors.ErrPlatformNotFound
+	}
+
+	credential, err := GetShopCredential(shop)
+	if err != nil {
+		return shop.Platform, nil, err
+	}
+
+	result, err := platform.PutProduct(credential, product, businessContext)
+	return shop.Platform, result, err
+}
+
+func finishPublishJob(job *models.PublishJob) {
+	db := database.Database()
+
+	var counts []struct {
+		Status string
+		Count  int
+	}
+	if err := db.Model(&models.PublishJobItem{}).
+		Select("status, count(*) as count").
+		Where("job_id = ?", job.ID).
+		Group("status").
+		Scan(&counts).Error; err != nil {
+		slog.Error("Failed to count publish job items", "jobID", job.ID, "error", err)
+		return
+	}
+
+	succeeded, failed, unfinished := 0, 0, 0
+	for _, c := range counts {
+		switch c.Status {
+		case models.PublishItemStatusSucceeded:
+			succeeded = c.Count
+		case models.PublishItemStatusFailed:
+			failed = c.Count
+		default:
+			unfinished += c.Count
+		}
+	}
+
+	updates := map[string]interface{}{
+		"succeeded": succeeded,
+		"failed":    failed,
+	}
+	if unfinished == 0 {
+		status := models.PublishJobStatusCompleted
+		if failed > 0 && succeeded > 0 {
+			status = models.PublishJobStatusPartial
+		} else if failed > 0 {
+			status = models.PublishJobStatusFailed
+		}
+		updates["status"] = status
+		updates["finished_at"] = time.Now()
+	}
+
+	if err := db.Model(job).Updates(updates).Error; err != nil {
+		slog.Error("Failed to update publish job", "jobID", job.ID, "error", err)
+	}
+}
+
+// acquireShopSlot 占用店铺的发布名额，返回释放函数
+func acquireShopSlot(shopID uint) func() {
+	v, _ := shopSlots.LoadOrStore(shopID, make(chan struct{}, perShopPublishConcurrency))
+	slot := v.(chan struct{})
+	slot <- struct{}{}
+	return func() { <-slot }
+}
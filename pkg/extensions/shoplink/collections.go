@@ -0,0 +1,75 @@
+package shoplink
+
+import (
+	"github.com/flaboy/aira-shop/pkg/errors"
+	"github.com/flaboy/aira-shop/pkg/types"
+)
+
+func loadCollectionManager(shopID uint) (CollectionManager, *types.ShopCredential, error) {
+	platform, credential, err := loadShopPlatform(shopID)
+	if err != nil {
+		return nil, nil, err
+	}
+	manager, ok := platform.(CollectionManager)
+	if !ok {
+		return nil, nil, errors.ErrCollectionsNotSupported
+	}
+	return manager, credential, nil
+}
+
+func loadSalesChannelManager(shopID uint) (SalesChannelManager, *types.ShopCredential, error) {
+	platform, credential, err := loadShopPlatform(shopID)
+	if err != nil {
+		return nil, nil, err
+	}
+	manager, ok := platform.(SalesChannelManager)
+	if !ok {
+		return nil, nil, errors.ErrChannelsNotSupported
+	}
+	return manager, credential, nil
+}
+
+// ListCollections 获取店铺的商品集合
+func ListCollections(shopID uint) ([]types.Collection, error) {
+	manager, credential, err := loadCollectionManager(shopID)
+	if err != nil {
+		return nil, err
+	}
+	return manager.ListCollections(credential)
+}
+
+// CreateCollection 在店铺中创建手动集合
+func CreateCollection(shopID uint, title string) (*types.Collection, error) {
+	manager, credential, err := loadCollectionManager(shopID)
+	if err != nil {
+		return nil, err
+	}
+	return manager.CreateCollection(credential, title)
+}
+
+// AddProductToCollections 将已发布的商品加入集合，productID为平台商品ID
+func AddProductToCollections(shopID uint, productID string, collectionIDs []string) error {
+	manager, credential, err := loadCollectionManager(shopID)
+	if err != nil {
+		return err
+	}
+	return manager.AddProductToCollections(credential, productID, collectionIDs)
+}
+
+// ListSalesChannels 获取店铺可发布的销售渠道
+func ListSalesChannels(shopID uint) ([]types.SalesChannel, error) {
+	manager, credential, err := loadSalesChannelManager(shopID)
+	if err != nil {
+		return nil, err
+	}
+	return manager.ListSalesChannels(credential)
+}
+
+// PublishToSalesChannels 将已发布的商品发布到指定销售渠道
+func PublishToSalesChannels(shopID uint, productID string, channelIDs []string) error {
+	manager, credential, err := loadSalesChannelManager(shopID)
+	if err != nil {
+		return err
+	}
+	return manager.PublishToSalesChannels(credential, productID, channelIDs)
+}
@@ -0,0 +1,59 @@
+package shoplink
+
+import (
+	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/errors"
+	"github.com/flaboy/aira-shop/pkg/extensions/shoplink/utils"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
+)
+
+// CheckProductDrift 立即检查商品是否被直接修改，按店铺策略记录或重新应用
+// 返回差异报告，存在差异时同时返回保存的记录
+func CheckProductDrift(shopProductID uint) (*types.DriftReport, *models.ShopProductDrift, error) {
+	product, err := loadShopProduct(shopProductID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	platform, credential, err := loadShopPlatform(product.ShopID)
+	if err != nil {
+		return nil, nil, err
+	}
+	detector, ok := platform.(DriftDetector)
+	if !ok {
+		return nil, nil, errors.ErrDriftNotSupported
+	}
+
+	report, err := detector.DetectDrift(credential, product)
+	if err != nil {
+		return nil, nil, err
+	}
+	record, err := utils.ProcessDrift(product, report, func() error {
+		return detector.ReapplyProduct(credential, product)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return report, record, nil
+}
+
+// ListProductDrifts 获取商品的漂移记录，最新的在前
+func ListProductDrifts(shopProductID uint) ([]models.ShopProductDrift, error) {
+	var records []models.ShopProductDrift
+	err := database.Database().
+		Where("shop_product_id = ?", shopProductID).
+		Order("id DESC").
+		Find(&records).Error
+	return records, err
+}
+
+// SetShopDriftPolicy 设置店铺商品被直接修改后的处理策略
+func SetShopDriftPolicy(shopID uint, policy types.DriftPolicy) error {
+	if policy != types.DriftPolicyReport && policy != types.DriftPolicyReapply {
+		return errors.ErrInvalidDriftPolicy
+	}
+	return database.Database().Model(&models.ShopLink{}).
+		Where("id = ?", shopID).
+		Update("drift_policy", string(policy)).Error
+}
@@ -0,0 +1,133 @@
+package etsy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/flaboy/aira-shop/pkg/config"
+)
+
+// apiError Etsy接口返回的错误
+type apiError struct {
+	Status  int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("etsy api error %d: %s", e.Status, e.Message)
+}
+
+// 访问令牌无效或授权已撤销
+func isAuthError(err error) bool {
+	if e, ok := err.(*apiError); ok {
+		return e.Status == http.StatusUnauthorized
+	}
+	return false
+}
+
+func apiUrl(path string) string {
+	return strings.TrimSuffix(config.Config.Etsy.ApiBaseUrl, "/") + path
+}
+
+// apiKeyHeader Etsy要求x-api-key为 keystring:shared_secret
+func apiKeyHeader() string {
+	if config.Config.Etsy.SharedSecret == "" {
+		return config.Config.Etsy.ApiKey
+	}
+	return config.Config.Etsy.ApiKey + ":" + config.Config.Etsy.SharedSecret
+}
+
+// call 发送JSON格式的API请求，body为nil时不发送请求体
+func (p *Etsy) call(ctx context.Context, method, path string, query url.Values, body interface{}, cred *EtsyCredential, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	target := apiUrl(path)
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return p.do(req, cred, out)
+}
+
+// upload 以multipart方式上传文件
+func (p *Etsy) upload(ctx context.Context, path string, fields map[string]string, fileField, filename string, data []byte, cred *EtsyCredential, out interface{}) error {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		if err := writer.WriteField(k, v); err != nil {
+			return err
+		}
+	}
+	part, err := writer.CreateFormFile(fileField, filename)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(data); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiUrl(path), &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return p.do(req, cred, out)
+}
+
+func (p *Etsy) do(req *http.Request, cred *EtsyCredential, out interface{}) error {
+	req.Header.Set("x-api-key", apiKeyHeader())
+	if cred != nil {
+		req.Header.Set("Authorization", "Bearer "+cred.AccessToken)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var e errorResponse
+		message := string(respBody)
+		if json.Unmarshal(respBody, &e) == nil && e.Error != "" {
+			message = e.Error
+			if e.ErrorDescription != "" {
+				message += ": " + e.ErrorDescription
+			}
+		}
+		return &apiError{Status: resp.StatusCode, Message: message}
+	}
+
+	if out != nil && len(respBody) > 0 {
+		return json.Unmarshal(respBody, out)
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package etsy
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/flaboy/aira-shop/pkg/errors"
+	"github.com/flaboy/aira-shop/pkg/events"
+	"github.com/flaboy/aira-shop/pkg/extensions/shoplink/utils"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
+	"github.com/flaboy/aira-web/pkg/helper"
+	"github.com/flaboy/pin"
+	"github.com/flaboy/pin/usererrors"
+)
+
+const platformName = "etsy"
+
+// 访问令牌剩余有效期低于该值时刷新，Etsy访问令牌有效期为1小时
+const tokenRefreshBefore = 5 * time.Minute
+
+type Etsy struct {
+	httpClient *http.Client
+}
+
+type EtsyCredential struct {
+	UserID       int64
+	ShopID       int64
+	ShopName     string
+	Currency     string
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    int64
+}
+
+func (p *Etsy) Init() error {
+	if !config.Config.Etsy.Enabled {
+		return nil
+	}
+
+	p.httpClient = &http.Client{
+		Timeout: 120 * time.Second,
+	}
+
+	if config.Config.Etsy.PollMinutes > 0 {
+		go p.StartReceiptPoller(time.Duration(config.Config.Etsy.PollMinutes) * time.Minute)
+	}
+	return nil
+}
+
+func (p *Etsy) GetPlatformName() string {
+	return platformName
+}
+
+func redirectUrl() string {
+	return helper.BuildUrl("stores/callback/etsy")
+}
+
+// codeVerifier 由state派生PKCE code_verifier，回调时无需额外存储
+func codeVerifier(state string) string {
+	mac := hmac.New(sha256.New, []byte(config.Config.Etsy.SharedSecret+config.Config.Etsy.ApiKey))
+	mac.Write([]byte(state))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// HandleRequest 生成带PKCE参数的授权URL
+func (p *Etsy) HandleRequest(c *pin.Context, path string) (*types.HandleRequestResult, error) {
+	state, err := utils.GenerateNonce()
+	if err != nil {
+		return nil, errors.ErrNonceGeneration
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", config.Config.Etsy.ApiKey)
+	query.Set("redirect_uri", redirectUrl())
+	query.Set("scope", config.Config.Etsy.Scopes)
+	query.Set("state", state)
+	query.Set("code_challenge", codeChallenge(codeVerifier(state)))
+	query.Set("code_challenge_method", "S256")
+
+	return &types.HandleRequestResult{
+		AuthURL: config.Config.Etsy.AuthorizeUrl + "?" + query.Encode(),
+	}, nil
+}
+
+func (p *Etsy) HandleCallback(c *pin.Context, businessContext json.RawMessage, callbackUrl *url.URL) (*types.CallbackResponse, error) {
+	ownerID, err := utils.ResolveOwner(businessContext)
+	if err != nil {
+		return nil, errors.ErrShopCreation
+	}
+
+	query := callbackUrl.Query()
+	code := query.Get("code")
+	state := query.Get("state")
+	if code == "" || state == "" {
+		return nil, errors.ErrInvalidCallbackSignature
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	token, err := p.requestToken(ctx, url.Values{
+		"grant_type":    {"authorization_code"},
+		"redirect_uri":  {redirectUrl()},
+		"code":          {code},
+		"code_verifier": {codeVerifier(state)},
+	})
+	if err != nil {
+		fmt.Printf("Etsy token exchange failed: %v\n", err)
+		return nil, errors.ErrAccessTokenFailed
+	}
+
+	cred := &EtsyCredential{}
+	applyToken(cred, token)
+
+	var me meResponse
+	if err := p.call(ctx, http.MethodGet, "/v3/application/users/me", nil, nil, cred, &me); err != nil {
+		fmt.Printf("Etsy user query failed: %v\n", err)
+		return nil, errors.ErrShopInfoFailed
+	}
+	if me.ShopID == 0 {
+		return nil, errors.ErrShopInfoFailed
+	}
+
+	var shopInfo shop
+	if err := p.call(ctx, http.MethodGet, fmt.Sprintf("/v3/application/shops/%d", me.ShopID), nil, nil, cred, &shopInfo); err != nil {
+		fmt.Printf("Etsy shop query failed: %v\n", err)
+		return nil, errors.ErrShopInfoFailed
+	}
+
+	cred.UserID = me.UserID
+	cred.ShopID = shopInfo.ShopID
+	cred.ShopName = shopInfo.ShopName
+	cred.Currency = shopInfo.CurrencyCode
+
+	credentialsJson, err := json.Marshal(cred)
+	if err != nil {
+		return nil, errors.ErrCredentialsMarshal
+	}
+
+	shopLink, err := utils.SaveShopLink(&models.ShopLink{
+		OwnerID:     ownerID,
+		Platform:    platformName,
+		Domain:      strconv.FormatInt(shopInfo.ShopID, 10),
+		Name:        shopInfo.ShopName,
+		Url:         shopInfo.Url,
+		Credentials: credentialsJson,
+		Scopes:      config.Config.Etsy.Scopes,
+	})
+	if err == errors.ErrShopOwnedByOther {
+		return nil, err
+	} else if err != nil {
+		return nil, errors.ErrShopCreation
+	}
+
+	events.EmitShopConnected(&types.ShopConnectedEvent{
+		ShopID:   shopLink.ID,
+		Platform: platformName,
+		ShopData: map[string]interface{}{
+			"name":     shopInfo.ShopName,
+			"url":      shopInfo.Url,
+			"currency": shopInfo.CurrencyCode,
+		},
+		BusinessContext: businessContext,
+		CreatedAt:       time.Now(),
+	})
+
+	return &types.CallbackResponse{
+		Type: types.CallbackResponseTypeShopLinked,
+		ShopLinkedData: &types.ShopLinkedData{
+			ShopLink: shopLink,
+		},
+	}, nil
+}
+
+// CheckHealth 验证访问令牌可用且仍关联原店铺，Etsy没有webhook需要检查
+func (p *Etsy) CheckHealth(credential *types.ShopCredential) (*types.HealthCheckResult, error) {
+	cred, err := decodeCredential(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	result := &types.HealthCheckResult{}
+	if err := p.ensureToken(ctx, cred); err != nil {
+		result.Message = fmt.Sprintf("failed to refresh access token: %v", err)
+		return result, nil
+	}
+
+	var me meResponse
+	if err := p.call(ctx, http.MethodGet, "/v3/application/users/me", nil, nil, cred, &me); err != nil {
+		if isAuthError(err) {
+			result.Message = err.Error()
+			return result, nil
+		}
+		return nil, err
+	}
+
+	result.CredentialsValid = true
+	if me.ShopID != cred.ShopID {
+		result.Message = "shop is no longer linked to the authorized user"
+		return result, nil
+	}
+	result.GrantedScopes = strings.Fields(config.Config.Etsy.Scopes)
+	result.Healthy = true
+	return result, nil
+}
+
+func decodeCredential(credential *types.ShopCredential) (*EtsyCredential, error) {
+	var cred EtsyCredential
+	credData, err := json.Marshal(credential.Data)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to marshal credentials: %s", err.Error()))
+	}
+	if err := json.Unmarshal(credData, &cred); err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to unmarshal credentials: %s", err.Error()))
+	}
+	return &cred, nil
+}
+
+// requestToken 调用令牌接口获取或刷新令牌
+func (p *Etsy) requestToken(ctx context.Context, form url.Values) (*tokenResponse, error) {
+	form.Set("client_id", config.Config.Etsy.ApiKey)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiUrl("/v3/public/oauth/token"), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token tokenResponse
+	if err := p.do(req, nil, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func applyToken(cred *EtsyCredential, token *tokenResponse) {
+	cred.AccessToken = token.AccessToken
+	cred.RefreshToken = token.RefreshToken
+	cred.ExpiresAt = time.Now().Unix() + token.ExpiresIn
+}
+
+// ensureToken 访问令牌即将过期时刷新并保存到ShopLink
+func (p *Etsy) ensureToken(ctx context.Context, cred *EtsyCredential) error {
+	if time.Until(time.Unix(cred.ExpiresAt, 0)) > tokenRefreshBefore {
+		return nil
+	}
+
+	token, err := p.requestToken(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {cred.RefreshToken},
+	})
+	if err != nil {
+		return err
+	}
+	applyToken(cred, token)
+
+	shop, err := utils.FindShopByDomain(platformName, strconv.FormatInt(cred.ShopID, 10))
+	if err != nil {
+		return err
+	}
+	credentialsJson, err := json.Marshal(cred)
+	if err != nil {
+		return err
+	}
+	return database.Database().Model(shop).Update("credentials", credentialsJson).Error
+}
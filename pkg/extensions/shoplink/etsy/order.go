@@ -0,0 +1,286 @@
+package etsy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/events"
+	"github.com/flaboy/aira-shop/pkg/extensions/shoplink/utils"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
+	"github.com/flaboy/pin/usererrors"
+)
+
+const (
+	receiptsCheckpoint = "orders"
+	receiptsPageSize   = 100
+	// 首次轮询回溯的时间
+	receiptsInitialLookback = 24 * time.Hour
+	// 每次轮询与上次进度重叠的时间，避免平台更新时间延迟导致漏单
+	receiptsOverlap = 5 * time.Minute
+)
+
+// StartReceiptPoller 定期拉取所有Etsy店铺的已支付订单
+func (p *Etsy) StartReceiptPoller(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.pollAllShops()
+		<-ticker.C
+	}
+}
+
+func (p *Etsy) pollAllShops() {
+	var shops []models.ShopLink
+	if err := database.Database().Where("platform = ?", platformName).Find(&shops).Error; err != nil {
+		slog.Error("Failed to load Etsy shops", "error", err)
+		return
+	}
+
+	for i := range shops {
+		if err := p.pollShop(&shops[i]); err != nil {
+			slog.Error("Failed to poll Etsy receipts", "shop_id", shops[i].ID, "error", err)
+		}
+	}
+}
+
+// pollShop 拉取上次进度之后更新的已支付订单，每个订单只通知一次
+func (p *Etsy) pollShop(shop *models.ShopLink) error {
+	var cred EtsyCredential
+	if err := json.Unmarshal(shop.Credentials, &cred); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if err := p.ensureToken(ctx, &cred); err != nil {
+		return fmt.Errorf("failed to refresh access token: %w", err)
+	}
+
+	checkpoint, err := utils.GetSyncCheckpoint(shop.ID, receiptsCheckpoint)
+	if err != nil {
+		return err
+	}
+	// 进度只向前推进，重叠时间只用于查询条件
+	latest := time.Now().Add(-receiptsInitialLookback)
+	since := latest
+	if checkpoint != nil {
+		latest = checkpoint.SyncedAt
+		since = latest.Add(-receiptsOverlap)
+	}
+
+	for offset := 0; ; offset += receiptsPageSize {
+		query := url.Values{}
+		query.Set("was_paid", "true")
+		query.Set("min_last_modified", strconv.FormatInt(since.Unix(), 10))
+		query.Set("sort_on", "updated")
+		query.Set("sort_order", "asc")
+		query.Set("limit", strconv.Itoa(receiptsPageSize))
+		query.Set("offset", strconv.Itoa(offset))
+
+		var data receiptsResponse
+		if err := p.call(ctx, http.MethodGet, fmt.Sprintf("/v3/application/shops/%d/receipts", cred.ShopID), query, nil, &cred, &data); err != nil {
+			return err
+		}
+
+		for i := range data.Results {
+			r := &data.Results[i]
+			if err := p.emitReceipt(shop, r); err != nil {
+				// 保存已处理的进度，失败的订单下次重试
+				if saveErr := utils.SaveSyncCheckpoint(shop.ID, receiptsCheckpoint, latest, ""); saveErr != nil {
+					slog.Error("Failed to save Etsy receipt checkpoint", "shop_id", shop.ID, "error", saveErr)
+				}
+				return err
+			}
+			if updated := time.Unix(r.UpdateTimestamp, 0); updated.After(latest) {
+				latest = updated
+			}
+		}
+
+		if len(data.Results) < receiptsPageSize {
+			break
+		}
+	}
+
+	return utils.SaveSyncCheckpoint(shop.ID, receiptsCheckpoint, latest, "")
+}
+
+func (p *Etsy) emitReceipt(shop *models.ShopLink, r *receipt) error {
+	receiptID := strconv.FormatInt(r.ReceiptID, 10)
+	isNew, err := utils.MarkOrderSynced(shop.ID, receiptID)
+	if err != nil || !isNew {
+		return err
+	}
+
+	orderData, err := convertReceipt(r)
+	if err == nil {
+		err = events.EmitOrderReceived(&types.OrderReceivedEvent{
+			Platform:  platformName,
+			OrderData: *orderData,
+			ShopID:    shop.ID,
+			CreatedAt: time.Now(),
+		})
+	}
+	if err != nil {
+		if unmarkErr := utils.UnmarkOrderSynced(shop.ID, receiptID); unmarkErr != nil {
+			slog.Error("Failed to unmark Etsy receipt", "receipt_id", receiptID, "error", unmarkErr)
+		}
+		return fmt.Errorf("failed to emit receipt %s: %w", receiptID, err)
+	}
+
+	fmt.Printf("Etsy receipt %s received for shop %d\n", receiptID, shop.ID)
+	return nil
+}
+
+// convertReceipt 将Etsy收据转换为统一订单结构，只保留本系统发布的商品
+func convertReceipt(r *receipt) (*types.OrderData, error) {
+	receiptID := strconv.FormatInt(r.ReceiptID, 10)
+	createdAt := time.Unix(r.CreateTimestamp, 0)
+	updatedAt := time.Unix(r.UpdateTimestamp, 0)
+
+	firstName, lastName := splitName(r.Name)
+	orderData := &types.OrderData{
+		ID:                receiptID,
+		Name:              receiptID,
+		Email:             r.BuyerEmail,
+		FinancialStatus:   convertFinancialStatus(r),
+		FulfillmentStatus: types.OrderFulfillmentStatusUnfulfilled,
+		CreatedAt:         &createdAt,
+		UpdatedAt:         &updatedAt,
+		TotalPrice:        r.Grandtotal.Decimal(),
+		SubtotalPrice:     r.Subtotal.Decimal(),
+		TotalShipping:     r.TotalShippingCost.Decimal(),
+		TotalTax:          r.TotalTaxCost.Decimal(),
+		Customer: &types.OrderCustomer{
+			ID:        strconv.FormatInt(r.BuyerUserID, 10),
+			Email:     r.BuyerEmail,
+			FirstName: firstName,
+			LastName:  lastName,
+		},
+		ShippingAddress: &types.OrderAddress{
+			FirstName:   firstName,
+			LastName:    lastName,
+			Address1:    r.FirstLine,
+			Address2:    r.SecondLine,
+			City:        r.City,
+			Province:    r.State,
+			CountryCode: r.CountryIso,
+			Zip:         r.Zip,
+		},
+		RawData: map[string]interface{}{
+			"source_name": platformName,
+			"receipt":     r,
+		},
+	}
+	if r.Grandtotal != nil {
+		orderData.Currency = r.Grandtotal.CurrencyCode
+	}
+	if r.IsShipped {
+		orderData.FulfillmentStatus = types.OrderFulfillmentStatusFulfilled
+	}
+
+	for _, t := range r.Transactions {
+		listingID := strconv.FormatInt(t.ListingID, 10)
+		product, ok, err := utils.GetShopProduct(platformName, listingID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			fmt.Printf("Shop product not found for Etsy listing %s, skipping transaction %d\n", listingID, t.TransactionID)
+			continue
+		}
+
+		rm := EtsyRemoteData{}
+		if err := json.Unmarshal(product.RemoteData, &rm); err != nil {
+			return nil, err
+		}
+		variantID, ok := rm.VariantMapper[strconv.FormatInt(t.ProductID, 10)]
+		if !ok {
+			fmt.Printf("Etsy product %d not found in variant mapper, skipping transaction\n", t.ProductID)
+			continue
+		}
+
+		var variantTitle []string
+		properties := make(map[string]string)
+		for _, v := range t.Variations {
+			variantTitle = append(variantTitle, v.FormattedValue)
+			properties[v.FormattedName] = v.FormattedValue
+		}
+
+		orderData.LineItems = append(orderData.LineItems, types.OrderLineItem{
+			ID:           strconv.FormatInt(t.TransactionID, 10),
+			ProductID:    listingID,
+			VariantID:    variantID,
+			Title:        t.Title,
+			SKU:          t.Sku,
+			Quantity:     t.Quantity,
+			Price:        t.Price.Decimal(),
+			Properties:   properties,
+			VariantTitle: strings.Join(variantTitle, " / "),
+			RawData:      map[string]interface{}{"transaction": t},
+		})
+	}
+
+	return orderData, nil
+}
+
+func convertFinancialStatus(r *receipt) types.OrderFinancialStatus {
+	switch strings.ToLower(r.Status) {
+	case "canceled":
+		return types.OrderFinancialStatusVoided
+	case "fully refunded":
+		return types.OrderFinancialStatusRefunded
+	case "partially refunded":
+		return types.OrderFinancialStatusPartiallyRefunded
+	case "payment processing":
+		return types.OrderFinancialStatusPending
+	}
+	if r.IsPaid {
+		return types.OrderFinancialStatusPaid
+	}
+	return types.OrderFinancialStatusPending
+}
+
+// splitName Etsy收货人只有全名
+func splitName(name string) (string, string) {
+	name = strings.TrimSpace(name)
+	if i := strings.LastIndex(name, " "); i > 0 {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
+
+// SubmitTracking 为Etsy订单提交物流单号，Etsy会将订单标记为已发货
+func (p *Etsy) SubmitTracking(credential *types.ShopCredential, tracking *types.ShipmentTracking) error {
+	cred, err := decodeCredential(credential)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	if err := p.ensureToken(ctx, cred); err != nil {
+		return usererrors.New(fmt.Sprintf("Failed to refresh access token: %s", err.Error()))
+	}
+
+	err = p.call(ctx, http.MethodPost, fmt.Sprintf("/v3/application/shops/%d/receipts/%s/tracking", cred.ShopID, tracking.OrderID), nil, &trackingRequest{
+		TrackingCode: tracking.TrackingNumber,
+		CarrierName:  tracking.Carrier,
+		SendBcc:      tracking.NotifyBuyer,
+	}, cred, nil)
+	if err != nil {
+		return usererrors.New(fmt.Sprintf("Failed to submit tracking: %s", err.Error()))
+	}
+	return nil
+}
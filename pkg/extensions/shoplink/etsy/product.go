@@ -0,0 +1,469 @@
+package etsy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/flaboy/aira-shop/pkg/events"
+	"github.com/flaboy/aira-shop/pkg/extensions/shoplink/utils"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
+	"github.com/flaboy/pin/usererrors"
+)
+
+const (
+	maxListingImages = 10
+	maxTitleLength   = 140
+	maxTags          = 13
+	maxTagLength     = 20
+)
+
+// Etsy自定义变体属性，类目中没有匹配属性时使用
+var customPropertyIDs = []int64{513, 514}
+
+type EtsyRemoteData struct {
+	ListingID  int64
+	TaxonomyID int64
+	ImageIDs   []int64
+	// Etsy库存product_id -> 内部变体ID
+	VariantMapper map[string]uint
+	Images        []types.ImageResult
+}
+
+// variationProperty 产品选项对应的Etsy变体属性
+type variationProperty struct {
+	PropertyID int64
+	Name       string
+	// 选项值 -> Etsy属性值ID，自定义属性没有值ID
+	ValueIDs map[string]int64
+}
+
+func (p *Etsy) PutProduct(credential *types.ShopCredential, product *types.ProductData, businessContext json.RawMessage) (*types.PutProductResult, error) {
+	cred, err := decodeCredential(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	defer cancel()
+
+	if err := p.ensureToken(ctx, cred); err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to refresh access token: %s", err.Error()))
+	}
+
+	shop, err := utils.FindShopByDomain(platformName, strconv.FormatInt(cred.ShopID, 10))
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to find shop: %s", err.Error()))
+	}
+
+	// 应用店铺覆盖规则，后续保存的是实际发布的数据
+	product, err = utils.ApplyShopOverrides(shop, product)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to apply shop overrides: %s", err.Error()))
+	}
+	if len(product.Variants) == 0 {
+		return nil, usererrors.New("Product has no variants")
+	}
+
+	status, publishAt, err := utils.PublishStatus(product)
+	if err != nil {
+		return nil, err
+	}
+
+	taxonomyID, err := taxonomyFor(product)
+	if err != nil {
+		return nil, usererrors.New(err.Error())
+	}
+
+	properties, err := p.variationProperties(ctx, cred, taxonomyID, product.Options)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to resolve variation properties: %s", err.Error()))
+	}
+
+	request, err := p.toEtsyListing(ctx, cred, product, taxonomyID)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to convert product: %s", err.Error()))
+	}
+
+	var created listing
+	if err := p.call(ctx, http.MethodPost, fmt.Sprintf("/v3/application/shops/%d/listings", cred.ShopID), nil, request, cred, &created); err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to create listing: %s", err.Error()))
+	}
+
+	remote := &EtsyRemoteData{
+		ListingID:     created.ListingID,
+		TaxonomyID:    taxonomyID,
+		VariantMapper: make(map[string]uint),
+	}
+
+	// 图片需要在创建listing后单独上传
+	prepared, imageResults := utils.PrepareImages(ctx, product)
+	remote.Images = imageResults
+	remote.ImageIDs, err = p.uploadListingImages(ctx, cred, created.ListingID, prepared, imageResults)
+	if err != nil {
+		p.deleteListing(cred, created.ListingID)
+		return nil, usererrors.New(fmt.Sprintf("Failed to upload images: %s", err.Error()))
+	}
+
+	inventory := buildInventory(product, properties)
+	var updated inventoryResponse
+	if err := p.call(ctx, http.MethodPut, fmt.Sprintf("/v3/application/listings/%d/inventory", created.ListingID), nil, inventory, cred, &updated); err != nil {
+		p.deleteListing(cred, created.ListingID)
+		return nil, usererrors.New(fmt.Sprintf("Failed to update inventory: %s", err.Error()))
+	}
+
+	// 通过sku将Etsy库存product映射回内部变体
+	skuToVariant := make(map[string]uint)
+	for i, item := range inventory.Products {
+		skuToVariant[item.Sku] = product.Variants[i].ID
+	}
+	for _, item := range updated.Products {
+		if variantID, ok := skuToVariant[item.Sku]; ok {
+			remote.VariantMapper[strconv.FormatInt(item.ProductID, 10)] = variantID
+		}
+	}
+
+	// 草稿listing需要有图片和库存后才能激活，草稿和定时发布的保持草稿
+	if status == models.ShopProductStatusActive && len(remote.ImageIDs) > 0 {
+		err := p.call(ctx, http.MethodPatch, fmt.Sprintf("/v3/application/shops/%d/listings/%d", cred.ShopID, created.ListingID), nil, map[string]string{
+			"state": "active",
+		}, cred, &created)
+		if err != nil {
+			p.deleteListing(cred, created.ListingID)
+			return nil, usererrors.New(fmt.Sprintf("Failed to activate listing: %s", err.Error()))
+		}
+	}
+
+	outerID := strconv.FormatInt(created.ListingID, 10)
+	listingUrl := created.Url
+	if listingUrl == "" {
+		listingUrl = "https://www.etsy.com/listing/" + outerID
+	}
+
+	// 没有图片的listing无法激活，按实际状态记录
+	if status == models.ShopProductStatusActive && created.State != "active" {
+		status = models.ShopProductStatusDraft
+	}
+
+	shopProduct := models.ShopProduct{
+		ShopID:    shop.ID,
+		OuterID:   outerID,
+		Status:    status,
+		Url:       listingUrl,
+		Name:      product.ProductName,
+		Platform:  platformName,
+		PublishAt: publishAt,
+	}
+
+	productData, err := json.Marshal(product)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to marshal product data: %s", err.Error()))
+	}
+	shopProduct.Data = productData
+
+	remoteData, err := json.Marshal(remote)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to marshal remote data: %s", err.Error()))
+	}
+	shopProduct.RemoteData = remoteData
+
+	if err := database.Database().Create(&shopProduct).Error; err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to create shop product: %s", err.Error()))
+	}
+
+	events.EmitProductPublished(&types.ProductPublishedEvent{
+		ShopProductID: shopProduct.ID,
+		ShopID:        shop.ID,
+		Platform:      platformName,
+		OuterID:       outerID,
+		ProductData: map[string]interface{}{
+			"product_name": product.ProductName,
+			"body_html":    product.BodyHTML,
+			"tags":         product.Tags,
+		},
+		BusinessContext: businessContext,
+		CreatedAt:       time.Now(),
+	})
+
+	return &types.PutProductResult{
+		CommandResult: types.CommandResult{
+			Success: true,
+			Message: "Product created successfully",
+		},
+		OuterID:    outerID,
+		Url:        listingUrl,
+		RemoteData: remote,
+	}, nil
+}
+
+// deleteListing 发布中途失败时删除已创建的listing，避免重试时留下重复的草稿
+// 原请求的context可能已超时，使用新的context
+func (p *Etsy) deleteListing(cred *EtsyCredential, listingID int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := p.call(ctx, http.MethodDelete, fmt.Sprintf("/v3/application/listings/%d", listingID), nil, nil, cred, nil); err != nil {
+		slog.Error("Failed to delete unfinished Etsy listing", "listing_id", listingID, "error", err)
+	}
+}
+
+// taxonomyFor 产品类目为Etsy taxonomy_id，未指定时使用配置的默认类目
+func taxonomyFor(product *types.ProductData) (int64, error) {
+	if product.CategoryID != "" {
+		id, err := strconv.ParseInt(product.CategoryID, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid Etsy taxonomy id %q", product.CategoryID)
+		}
+		return id, nil
+	}
+	if config.Config.Etsy.TaxonomyID == 0 {
+		return 0, fmt.Errorf("product has no category and no default Etsy taxonomy is configured")
+	}
+	return config.Config.Etsy.TaxonomyID, nil
+}
+
+func (p *Etsy) toEtsyListing(ctx context.Context, cred *EtsyCredential, product *types.ProductData, taxonomyID int64) (*createListingRequest, error) {
+	profileID, err := p.defaultShippingProfile(ctx, cred)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &createListingRequest{
+		Quantity:          inventoryQuantity(product.Variants[0].InventoryQuantity),
+		Title:             truncate(product.ProductName, maxTitleLength),
+		Description:       htmlToText(product.BodyHTML),
+		WhoMade:           config.Config.Etsy.WhoMade,
+		WhenMade:          config.Config.Etsy.WhenMade,
+		TaxonomyID:        taxonomyID,
+		ShippingProfileID: profileID,
+		Tags:              listingTags(product.Tags),
+		Type:              "physical",
+	}
+
+	// listing价格取最低变体价格，各变体价格由库存接口设置
+	for _, v := range product.Variants {
+		if v.Price == nil {
+			continue
+		}
+		price, _ := v.Price.Float64()
+		if request.Price == 0 || price < request.Price {
+			request.Price = price
+		}
+		if request.ItemWeight == 0 && v.Weight != nil && isEtsyWeightUnit(v.WeightUnit) {
+			request.ItemWeight, _ = v.Weight.Float64()
+			request.ItemWeightUnit = strings.ToLower(v.WeightUnit)
+		}
+	}
+	if request.Price == 0 {
+		return nil, fmt.Errorf("product has no price")
+	}
+
+	return request, nil
+}
+
+func (p *Etsy) defaultShippingProfile(ctx context.Context, cred *EtsyCredential) (int64, error) {
+	var data shippingProfilesResponse
+	if err := p.call(ctx, http.MethodGet, fmt.Sprintf("/v3/application/shops/%d/shipping-profiles", cred.ShopID), nil, nil, cred, &data); err != nil {
+		return 0, fmt.Errorf("failed to get shipping profiles: %w", err)
+	}
+	if len(data.Results) == 0 {
+		return 0, fmt.Errorf("shop has no shipping profile")
+	}
+	return data.Results[0].ShippingProfileID, nil
+}
+
+// variationProperties 将产品选项映射到Etsy变体属性
+// 优先使用类目中同名且支持变体的属性，否则使用自定义属性，Etsy最多支持两个变体属性
+func (p *Etsy) variationProperties(ctx context.Context, cred *EtsyCredential, taxonomyID int64, options []types.ProductOption) ([]variationProperty, error) {
+	if len(options) == 0 {
+		return nil, nil
+	}
+	if len(options) > len(customPropertyIDs) {
+		return nil, fmt.Errorf("Etsy supports at most %d variation options, got %d", len(customPropertyIDs), len(options))
+	}
+
+	var data taxonomyPropertiesResponse
+	if err := p.call(ctx, http.MethodGet, fmt.Sprintf("/v3/application/seller-taxonomy/nodes/%d/properties", taxonomyID), nil, nil, cred, &data); err != nil {
+		return nil, fmt.Errorf("failed to get taxonomy properties: %w", err)
+	}
+
+	used := make(map[int64]bool)
+	result := make([]variationProperty, 0, len(options))
+	for _, option := range options {
+		property := variationProperty{Name: option.Name}
+		for _, candidate := range data.Results {
+			// 需要刻度（尺码体系等）的属性无法直接映射，交给自定义属性
+			if !candidate.SupportsVariations || len(candidate.Scales) > 0 || used[candidate.PropertyID] {
+				continue
+			}
+			if strings.EqualFold(candidate.Name, option.Name) || strings.EqualFold(candidate.DisplayName, option.Name) {
+				property.PropertyID = candidate.PropertyID
+				property.ValueIDs = make(map[string]int64)
+				for _, value := range candidate.PossibleValues {
+					property.ValueIDs[strings.ToLower(value.Name)] = value.ValueID
+				}
+				break
+			}
+		}
+		if property.PropertyID == 0 {
+			for _, id := range customPropertyIDs {
+				if !used[id] {
+					property.PropertyID = id
+					break
+				}
+			}
+		}
+		used[property.PropertyID] = true
+		result = append(result, property)
+	}
+	return result, nil
+}
+
+// buildInventory 每个变体对应一个Etsy库存product
+func buildInventory(product *types.ProductData, properties []variationProperty) *inventoryRequest {
+	request := &inventoryRequest{
+		PriceOnProperty:    []int64{},
+		QuantityOnProperty: []int64{},
+		SkuOnProperty:      []int64{},
+	}
+	for _, property := range properties {
+		request.PriceOnProperty = append(request.PriceOnProperty, property.PropertyID)
+		request.QuantityOnProperty = append(request.QuantityOnProperty, property.PropertyID)
+		request.SkuOnProperty = append(request.SkuOnProperty, property.PropertyID)
+	}
+
+	for _, v := range product.Variants {
+		item := inventoryProduct{
+			Sku:            v.Sku,
+			PropertyValues: []propertyValue{},
+		}
+		if item.Sku == "" {
+			item.Sku = fmt.Sprintf("aira-%d", v.ID)
+		}
+
+		for i, value := range []string{v.Option1, v.Option2} {
+			if i >= len(properties) || value == "" {
+				continue
+			}
+			pv := propertyValue{
+				PropertyID:   properties[i].PropertyID,
+				PropertyName: properties[i].Name,
+				ValueIDs:     []int64{},
+				Values:       []string{value},
+			}
+			if id, ok := properties[i].ValueIDs[strings.ToLower(value)]; ok {
+				pv.ValueIDs = append(pv.ValueIDs, id)
+			}
+			item.PropertyValues = append(item.PropertyValues, pv)
+		}
+
+		price := 0.0
+		if v.Price != nil {
+			price, _ = v.Price.Float64()
+		}
+		item.Offerings = []offering{{
+			Price:     price,
+			Quantity:  inventoryQuantity(v.InventoryQuantity),
+			IsEnabled: true,
+		}}
+		request.Products = append(request.Products, item)
+	}
+	return request
+}
+
+// uploadListingImages 依次上传已校验的产品图片，rank从1开始
+func (p *Etsy) uploadListingImages(ctx context.Context, cred *EtsyCredential, listingID int64, prepared []*utils.PreparedImage, results []types.ImageResult) ([]int64, error) {
+	var ids []int64
+	for _, img := range prepared {
+		if len(ids) >= maxListingImages {
+			break
+		}
+
+		filename := img.Image.Filename
+		if filename == "" {
+			filename = path.Base(img.Image.Src)
+		}
+
+		fields := map[string]string{"rank": strconv.Itoa(len(ids) + 1)}
+		if img.Image.Alt != "" {
+			fields["alt_text"] = img.Image.Alt
+		}
+
+		var uploaded listingImage
+		err := p.upload(ctx, fmt.Sprintf("/v3/application/shops/%d/listings/%d/images", cred.ShopID, listingID), fields, "image", filename, img.Data, cred, &uploaded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload image %s: %w", img.Image.Src, err)
+		}
+		results[img.ResultIndex].RemoteID = strconv.FormatInt(uploaded.ListingImageID, 10)
+		ids = append(ids, uploaded.ListingImageID)
+	}
+	return ids, nil
+}
+
+func inventoryQuantity(quantity int) int {
+	if quantity > 0 {
+		return quantity
+	}
+	return config.Config.Etsy.DefaultInventory
+}
+
+// listingTags Etsy最多13个标签，每个不超过20个字符
+func listingTags(tags string) []string {
+	var result []string
+	for _, tag := range strings.Split(tags, ",") {
+		tag = truncate(strings.TrimSpace(tag), maxTagLength)
+		if tag == "" {
+			continue
+		}
+		result = append(result, tag)
+		if len(result) >= maxTags {
+			break
+		}
+	}
+	return result
+}
+
+func isEtsyWeightUnit(unit string) bool {
+	switch strings.ToLower(unit) {
+	case "oz", "lb", "g", "kg":
+		return true
+	}
+	return false
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
+
+// htmlToText Etsy描述只支持纯文本
+func htmlToText(html string) string {
+	replacer := strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n", "</p>", "\n\n", "</li>", "\n", "</h1>", "\n", "</h2>", "\n", "</h3>", "\n")
+	text := replacer.Replace(html)
+
+	var b strings.Builder
+	inTag := false
+	for _, r := range text {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>':
+			inTag = false
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
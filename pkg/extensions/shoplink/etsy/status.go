@@ -0,0 +1,53 @@
+package etsy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/flaboy/aira-shop/pkg/errors"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
+	"github.com/flaboy/pin/usererrors"
+	"github.com/spf13/cast"
+)
+
+// SetProductStatus 激活或停用listing，Etsy已激活的listing不能退回草稿，草稿和归档都以停用处理
+func (p *Etsy) SetProductStatus(credential *types.ShopCredential, productID string, status string) error {
+	cred, err := decodeCredential(credential)
+	if err != nil {
+		return err
+	}
+
+	listingID := cast.ToInt64(productID)
+	if listingID == 0 {
+		return usererrors.New("Invalid listing ID")
+	}
+
+	var state string
+	switch status {
+	case models.ShopProductStatusActive:
+		state = "active"
+	case models.ShopProductStatusDraft, models.ShopProductStatusArchived:
+		state = "inactive"
+	default:
+		return errors.ErrInvalidPublishMode
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	if err := p.ensureToken(ctx, cred); err != nil {
+		return fmt.Errorf("failed to refresh access token: %w", err)
+	}
+
+	var updated listing
+	err = p.call(ctx, http.MethodPatch, fmt.Sprintf("/v3/application/shops/%d/listings/%d", cred.ShopID, listingID), nil, map[string]string{
+		"state": state,
+	}, cred, &updated)
+	if err != nil {
+		return fmt.Errorf("failed to set listing %d state to %s: %w", listingID, state, err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package etsy
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// https://developers.etsy.com/documentation/reference
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type meResponse struct {
+	UserID int64 `json:"user_id"`
+	ShopID int64 `json:"shop_id"`
+}
+
+type shop struct {
+	ShopID       int64  `json:"shop_id"`
+	ShopName     string `json:"shop_name"`
+	Url          string `json:"url"`
+	CurrencyCode string `json:"currency_code"`
+}
+
+type shippingProfile struct {
+	ShippingProfileID int64  `json:"shipping_profile_id"`
+	Title             string `json:"title"`
+}
+
+type shippingProfilesResponse struct {
+	Count   int               `json:"count"`
+	Results []shippingProfile `json:"results"`
+}
+
+type taxonomyProperty struct {
+	PropertyID         int64                   `json:"property_id"`
+	Name               string                  `json:"name"`
+	DisplayName        string                  `json:"display_name"`
+	SupportsVariations bool                    `json:"supports_variations"`
+	PossibleValues     []taxonomyPropertyValue `json:"possible_values"`
+	Scales             []struct {
+		ScaleID int64 `json:"scale_id"`
+	} `json:"scales"`
+}
+
+type taxonomyPropertyValue struct {
+	ValueID int64  `json:"value_id"`
+	Name    string `json:"name"`
+}
+
+type taxonomyPropertiesResponse struct {
+	Count   int                `json:"count"`
+	Results []taxonomyProperty `json:"results"`
+}
+
+type createListingRequest struct {
+	Quantity          int      `json:"quantity"`
+	Title             string   `json:"title"`
+	Description       string   `json:"description"`
+	Price             float64  `json:"price"`
+	WhoMade           string   `json:"who_made"`
+	WhenMade          string   `json:"when_made"`
+	TaxonomyID        int64    `json:"taxonomy_id"`
+	ShippingProfileID int64    `json:"shipping_profile_id,omitempty"`
+	Tags              []string `json:"tags,omitempty"`
+	Type              string   `json:"type"`
+	ItemWeight        float64  `json:"item_weight,omitempty"`
+	ItemWeightUnit    string   `json:"item_weight_unit,omitempty"`
+}
+
+type listing struct {
+	ListingID int64  `json:"listing_id"`
+	State     string `json:"state"`
+	Url       string `json:"url"`
+}
+
+type listingImage struct {
+	ListingImageID int64 `json:"listing_image_id"`
+	Rank           int   `json:"rank"`
+}
+
+// inventoryRequest 更新listing库存，变体通过属性ID表达
+type inventoryRequest struct {
+	Products           []inventoryProduct `json:"products"`
+	PriceOnProperty    []int64            `json:"price_on_property"`
+	QuantityOnProperty []int64            `json:"quantity_on_property"`
+	SkuOnProperty      []int64            `json:"sku_on_property"`
+}
+
+type inventoryProduct struct {
+	ProductID      int64           `json:"product_id,omitempty"`
+	Sku            string          `json:"sku"`
+	PropertyValues []propertyValue `json:"property_values"`
+	Offerings      []offering      `json:"offerings"`
+}
+
+type propertyValue struct {
+	PropertyID   int64    `json:"property_id"`
+	PropertyName string   `json:"property_name,omitempty"`
+	ValueIDs     []int64  `json:"value_ids"`
+	Values       []string `json:"values"`
+}
+
+type offering struct {
+	Price     float64 `json:"price"`
+	Quantity  int     `json:"quantity"`
+	IsEnabled bool    `json:"is_enabled"`
+}
+
+type inventoryResponse struct {
+	Products []inventoryProduct `json:"products"`
+}
+
+type money struct {
+	Amount       int64  `json:"amount"`
+	Divisor      int64  `json:"divisor"`
+	CurrencyCode string `json:"currency_code"`
+}
+
+// Decimal 将Etsy的金额（amount/divisor）转换为decimal
+func (m *money) Decimal() *decimal.Decimal {
+	if m == nil || m.Divisor == 0 {
+		return nil
+	}
+	d := decimal.NewFromInt(m.Amount).Div(decimal.NewFromInt(m.Divisor))
+	return &d
+}
+
+type receiptsResponse struct {
+	Count   int       `json:"count"`
+	Results []receipt `json:"results"`
+}
+
+type receipt struct {
+	ReceiptID         int64         `json:"receipt_id"`
+	BuyerUserID       int64         `json:"buyer_user_id"`
+	BuyerEmail        string        `json:"buyer_email"`
+	Name              string        `json:"name"`
+	FirstLine         string        `json:"first_line"`
+	SecondLine        string        `json:"second_line"`
+	City              string        `json:"city"`
+	State             string        `json:"state"`
+	Zip               string        `json:"zip"`
+	CountryIso        string        `json:"country_iso"`
+	Status            string        `json:"status"`
+	IsPaid            bool          `json:"is_paid"`
+	IsShipped         bool          `json:"is_shipped"`
+	CreateTimestamp   int64         `json:"create_timestamp"`
+	UpdateTimestamp   int64         `json:"update_timestamp"`
+	Grandtotal        *money        `json:"grandtotal"`
+	Subtotal          *money        `json:"subtotal"`
+	TotalShippingCost *money        `json:"total_shipping_cost"`
+	TotalTaxCost      *money        `json:"total_tax_cost"`
+	Transactions      []transaction `json:"transactions"`
+}
+
+type transaction struct {
+	TransactionID int64  `json:"transaction_id"`
+	Title         string `json:"title"`
+	ListingID     int64  `json:"listing_id"`
+	ProductID     int64  `json:"product_id"`
+	Sku           string `json:"sku"`
+	Quantity      int    `json:"quantity"`
+	Price         *money `json:"price"`
+	Variations    []struct {
+		PropertyID     int64  `json:"property_id"`
+		FormattedName  string `json:"formatted_name"`
+		FormattedValue string `json:"formatted_value"`
+	} `json:"variations"`
+}
+
+type trackingRequest struct {
+	TrackingCode string `json:"tracking_code"`
+	CarrierName  string `json:"carrier_name"`
+	SendBcc      bool   `json:"send_bcc"`
+}
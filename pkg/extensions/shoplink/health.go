@@ -0,0 +1,111 @@
+package shoplink
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/errors"
+	"github.com/flaboy/aira-shop/pkg/events"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
+	"gorm.io/gorm"
+)
+
+// StartHealthChecker 定期检查所有店铺的连接状态
+func StartHealthChecker(interval time.Duration) {
+	slog.Info("Starting shop health checker", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		checkAllShops()
+		<-ticker.C
+	}
+}
+
+func checkAllShops() {
+	var shops []models.ShopLink
+	if err := database.Database().Find(&shops).Error; err != nil {
+		slog.Error("Failed to load shops for health check", "error", err)
+		return
+	}
+
+	for i := range shops {
+		if Get(shops[i].Platform) == nil {
+			continue
+		}
+		if _, err := checkShop(&shops[i]); err != nil {
+			slog.Error("Shop health check failed", "shopID", shops[i].ID, "error", err)
+		}
+	}
+}
+
+// CheckShopHealth 立即检查指定店铺并记录结果
+func CheckShopHealth(shopID uint) (*types.HealthCheckResult, error) {
+	var shop models.ShopLink
+	if err := database.Database().First(&shop, shopID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.ErrShopNotFound
+		}
+		return nil, err
+	}
+	return checkShop(&shop)
+}
+
+func checkShop(shop *models.ShopLink) (*types.HealthCheckResult, error) {
+	platform := Get(shop.Platform)
+	if platform == nil {
+		return nil, errors.ErrPlatformNotFound
+	}
+
+	credential, err := GetShopCredential(shop)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result, err := platform.CheckHealth(credential)
+	if err != nil {
+		// 网络等临时错误不代表店铺失效，仅记录错误，保留之前的状态
+		if saveErr := database.Database().Model(shop).Updates(map[string]interface{}{
+			"health_checked_at": now,
+			"health_error":      err.Error(),
+		}).Error; saveErr != nil {
+			slog.Error("Failed to save shop health error", "shopID", shop.ID, "error", saveErr)
+		}
+		return nil, err
+	}
+
+	status := "healthy"
+	if !result.Healthy {
+		status = "unhealthy"
+	}
+
+	previousStatus := shop.HealthStatus
+	updates := map[string]interface{}{
+		"health_status":     status,
+		"health_checked_at": now,
+		"health_error":      result.Message,
+	}
+	if len(result.GrantedScopes) > 0 {
+		updates["scopes"] = strings.Join(result.GrantedScopes, ",")
+	}
+	if err := database.Database().Model(shop).Updates(updates).Error; err != nil {
+		return result, err
+	}
+
+	if !result.Healthy && previousStatus != "unhealthy" {
+		slog.Warn("Shop became unhealthy", "shopID", shop.ID, "platform", shop.Platform, "reason", result.Message)
+		events.EmitShopUnhealthy(&types.ShopUnhealthyEvent{
+			ShopID:    shop.ID,
+			Platform:  shop.Platform,
+			Reason:    result.Message,
+			Result:    result,
+			CreatedAt: now,
+		})
+	}
+
+	return result, nil
+}
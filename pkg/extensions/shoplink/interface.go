@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/url"
 
+	"github.com/flaboy/aira-shop/pkg/models"
 	"github.com/flaboy/aira-shop/pkg/types"
 	"github.com/flaboy/pin"
 )
@@ -15,6 +16,9 @@ type ShopPlatform interface {
 	// 发布产品到平台 - 使用BusinessContext参数
 	PutProduct(credential *types.ShopCredential, product *types.ProductData, businessContext json.RawMessage) (*types.PutProductResult, error)
 
+	// 检查店铺连接状态（凭证、权限、webhook），并尝试修复缺失的webhook
+	CheckHealth(credential *types.ShopCredential) (*types.HealthCheckResult, error)
+
 	// 处理公开请求（如OAuth授权）
 	HandleRequest(c *pin.Context, path string) (*types.HandleRequestResult, error)
 
@@ -24,3 +28,32 @@ type ShopPlatform interface {
 	// 获取平台名称
 	GetPlatformName() string
 }
+
+// TrackingSubmitter 支持回传物流单号的平台实现该接口
+type TrackingSubmitter interface {
+	SubmitTracking(credential *types.ShopCredential, tracking *types.ShipmentTracking) error
+}
+
+// CollectionManager 支持商品集合的平台实现该接口
+type CollectionManager interface {
+	ListCollections(credential *types.ShopCredential) ([]types.Collection, error)
+	CreateCollection(credential *types.ShopCredential, title string) (*types.Collection, error)
+	AddProductToCollections(credential *types.ShopCredential, productID string, collectionIDs []string) error
+}
+
+// SalesChannelManager 支持选择销售渠道的平台实现该接口
+type SalesChannelManager interface {
+	ListSalesChannels(credential *types.ShopCredential) ([]types.SalesChannel, error)
+	PublishToSalesChannels(credential *types.ShopCredential, productID string, channelIDs []string) error
+}
+
+// ProductStatusUpdater 支持上架、下架和归档商品的平台实现该接口，草稿和定时发布依赖该接口上架
+type ProductStatusUpdater interface {
+	SetProductStatus(credential *types.ShopCredential, productID string, status string) error
+}
+
+// DriftDetector 支持检测商品被直接修改并重新应用发布版本的平台实现该接口
+type DriftDetector interface {
+	DetectDrift(credential *types.ShopCredential, shopProduct *models.ShopProduct) (*types.DriftReport, error)
+	ReapplyProduct(credential *types.ShopCredential, shopProduct *models.ShopProduct) error
+}
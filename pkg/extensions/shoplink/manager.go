@@ -2,69 +2,153 @@ package shoplink
 
 import (
 	"encoding/json"
+	"log/slog"
+	"time"
 
 	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/flaboy/aira-shop/pkg/errors"
+	"github.com/flaboy/aira-shop/pkg/extensions/shoplink/amazon"
+	"github.com/flaboy/aira-shop/pkg/extensions/shoplink/etsy"
 	"github.com/flaboy/aira-shop/pkg/extensions/shoplink/shopify"
+	"github.com/flaboy/aira-shop/pkg/extensions/shoplink/tiktokshop"
+	"github.com/flaboy/aira-shop/pkg/extensions/shoplink/utils"
 	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
 	"gorm.io/gorm"
 )
 
-var platforms map[string]ShopPlatform
-
+// Init 注册内置平台并初始化所有已注册平台，初始化失败的平台被禁用而不影响启动
 func Init() error {
-	platforms = make(map[string]ShopPlatform)
+	registerBuiltin(&shopify.Shopify{}, config.Config.Shopify.Enabled)
+	registerBuiltin(&tiktokshop.TikTokShop{}, config.Config.TikTokShop.Enabled)
+	registerBuiltin(&etsy.Etsy{}, config.Config.Etsy.Enabled)
+	registerBuiltin(&amazon.Amazon{}, config.Config.Amazon.Enabled)
+	initPlatforms()
 
-	// 注册Shopify平台
-	shopifyPlatform := &shopify.Shopify{}
-	if err := shopifyPlatform.Init(); err != nil {
-		return err
+	// 恢复进程重启前未完成的批量发布任务
+	if err := ResumePublishJobs(); err != nil {
+		slog.Error("Failed to resume publish jobs", "error", err)
 	}
-	platforms[shopifyPlatform.GetPlatformName()] = shopifyPlatform
-
-	return nil
-}
 
-func Get(platformName string) ShopPlatform {
-	return platforms[platformName]
-}
+	// 定期检查店铺凭证和webhook
+	if config.Config.ShopHealthCheckMinutes > 0 {
+		go StartHealthChecker(time.Duration(config.Config.ShopHealthCheckMinutes) * time.Minute)
+	}
 
-func GetSupportedPlatforms() []string {
-	var names []string
-	for name := range platforms {
-		names = append(names, name)
+	// 定时上架到期的商品
+	if config.Config.ScheduledPublishMinutes > 0 {
+		go StartPublishScheduler(time.Duration(config.Config.ScheduledPublishMinutes) * time.Minute)
 	}
-	return names
+
+	return nil
 }
 
-// 新增函数
-func CreateShop(platform, name, url string, credentials json.RawMessage) (*models.ShopLink, error) {
-	shopLink := &models.ShopLink{
+// CreateShop 为商户创建或更新店铺，同一平台同一域名只保留一条记录
+func CreateShop(ownerID, platform, name, url string, credentials json.RawMessage) (*models.ShopLink, error) {
+	return utils.SaveShopLink(&models.ShopLink{
+		OwnerID:     ownerID,
 		Platform:    platform,
+		Domain:      url,
 		Name:        name,
 		Url:         url,
 		Credentials: credentials,
-	}
-
-	db := database.Database()
+	})
+}
 
-	// 检查是否已存在
-	var existing models.ShopLink
-	err := db.Where("name = ? AND platform = ?", name, platform).First(&existing).Error
-	if err == nil {
-		// 更新现有记录
-		existing.Credentials = credentials
-		existing.Url = url
-		return &existing, db.Save(&existing).Error
+// ListShops 获取商户的所有店铺，platform为空时不限平台
+func ListShops(ownerID, platform string) ([]models.ShopLink, error) {
+	var shops []models.ShopLink
+	tx := database.Database().Where("owner_id = ?", ownerID)
+	if platform != "" {
+		tx = tx.Where("platform = ?", platform)
+	}
+	if err := tx.Order("id").Find(&shops).Error; err != nil {
+		return nil, err
 	}
+	return shops, nil
+}
 
-	if err != gorm.ErrRecordNotFound {
+// GetShop 获取商户的指定店铺，不属于该商户时视为不存在
+func GetShop(ownerID string, shopID uint) (*models.ShopLink, error) {
+	var shop models.ShopLink
+	err := database.Database().Where("id = ? AND owner_id = ?", shopID, ownerID).First(&shop).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrShopNotFound
+	} else if err != nil {
 		return nil, err
 	}
+	return &shop, nil
+}
 
-	// 创建新记录
-	if err := db.Create(shopLink).Error; err != nil {
+// GetShopByDomain 按平台和店铺域名获取商户的店铺
+func GetShopByDomain(ownerID, platform, domain string) (*models.ShopLink, error) {
+	shop, err := utils.FindShopByDomain(platform, domain)
+	if err != nil {
 		return nil, err
 	}
+	if shop.OwnerID != ownerID {
+		return nil, errors.ErrShopNotFound
+	}
+	return shop, nil
+}
+
+// GetShopCredential 从ShopLink构造平台调用所需的凭证
+func GetShopCredential(shop *models.ShopLink) (*types.ShopCredential, error) {
+	data := map[string]interface{}{}
+	if err := json.Unmarshal(shop.Credentials, &data); err != nil {
+		return nil, errors.ErrCredentialsMarshal
+	}
+	return &types.ShopCredential{
+		Platform: shop.Platform,
+		Data:     data,
+	}, nil
+}
+
+// SetShopOverrides 设置店铺的产品覆盖规则，传nil清除
+func SetShopOverrides(shopID uint, overrides *types.ProductOverrides) error {
+	var data json.RawMessage
+	if overrides != nil {
+		var err error
+		if data, err = json.Marshal(overrides); err != nil {
+			return err
+		}
+	}
+	return database.Database().Model(&models.ShopLink{}).
+		Where("id = ?", shopID).
+		Update("overrides", data).Error
+}
+
+// loadShopPlatform 加载店铺及其平台实现和凭证
+func loadShopPlatform(shopID uint) (ShopPlatform, *types.ShopCredential, error) {
+	var shop models.ShopLink
+	if err := database.Database().First(&shop, shopID).Error; err == gorm.ErrRecordNotFound {
+		return nil, nil, errors.ErrShopNotFound
+	} else if err != nil {
+		return nil, nil, err
+	}
 
-	return shopLink, nil
+	platform := Get(shop.Platform)
+	if platform == nil {
+		return nil, nil, errors.ErrPlatformNotFound
+	}
+
+	credential, err := GetShopCredential(&shop)
+	if err != nil {
+		return nil, nil, err
+	}
+	return platform, credential, nil
+}
+
+// SubmitTracking 将物流单号回传到店铺所在平台
+func SubmitTracking(shopID uint, tracking *types.ShipmentTracking) error {
+	platform, credential, err := loadShopPlatform(shopID)
+	if err != nil {
+		return err
+	}
+	submitter, ok := platform.(TrackingSubmitter)
+	if !ok {
+		return errors.ErrTrackingNotSupported
+	}
+	return submitter.SubmitTracking(credential, tracking)
 }
@@ -0,0 +1,37 @@
+package shoplink
+
+import "github.com/flaboy/aira-shop/pkg/extensions/registry"
+
+// PlatformStatus 平台的启用和初始化状态
+type PlatformStatus = registry.Status
+
+var platforms = registry.New("shop platform", ShopPlatform.GetPlatformName)
+
+// RegisterPlatform 注册店铺平台实现，同名平台会替换已注册的实现（包括内置平台）
+// 在Init之后注册的平台会立即初始化
+func RegisterPlatform(platform ShopPlatform, enabled bool) {
+	platforms.Register(platform, enabled)
+}
+
+func registerBuiltin(platform ShopPlatform, enabled bool) {
+	platforms.RegisterBuiltin(platform, enabled)
+}
+
+func initPlatforms() {
+	platforms.Init()
+}
+
+// Get 获取已启用的平台，未启用或初始化失败时返回nil
+func Get(platformName string) ShopPlatform {
+	return platforms.Get(platformName)
+}
+
+// GetSupportedPlatforms 获取所有已启用的平台名称
+func GetSupportedPlatforms() []string {
+	return platforms.Names()
+}
+
+// GetPlatformStatuses 获取所有已注册平台的状态，包括未启用和初始化失败的平台
+func GetPlatformStatuses() []PlatformStatus {
+	return platforms.Statuses()
+}
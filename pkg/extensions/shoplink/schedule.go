@@ -0,0 +1,107 @@
+package shoplink
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/errors"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"gorm.io/gorm"
+)
+
+// StartPublishScheduler 定期上架到期的定时发布商品
+func StartPublishScheduler(interval time.Duration) {
+	slog.Info("Starting scheduled publish checker", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		publishDueProducts()
+		<-ticker.C
+	}
+}
+
+func publishDueProducts() {
+	var products []models.ShopProduct
+	err := database.Database().
+		Where("status = ? AND publish_at <= ?", models.ShopProductStatusScheduled, time.Now()).
+		Order("publish_at").
+		Find(&products).Error
+	if err != nil {
+		slog.Error("Failed to load scheduled products", "error", err)
+		return
+	}
+
+	for i := range products {
+		if err := setProductStatus(&products[i], models.ShopProductStatusActive); err != nil {
+			// 保持scheduled状态，下一轮重试
+			slog.Error("Failed to publish scheduled product", "shopProductID", products[i].ID, "error", err)
+		}
+	}
+}
+
+// SetShopProductStatus 上架、下架为草稿或归档已发布的商品，用于商户审核草稿后上架
+func SetShopProductStatus(shopProductID uint, status string) error {
+	product, err := loadShopProduct(shopProductID)
+	if err != nil {
+		return err
+	}
+	return setProductStatus(product, status)
+}
+
+// ScheduleShopProduct 为草稿或定时发布的商品设置上架时间，到期后由定时任务上架
+func ScheduleShopProduct(shopProductID uint, publishAt time.Time) error {
+	if !publishAt.After(time.Now()) {
+		return errors.ErrInvalidPublishAt
+	}
+
+	product, err := loadShopProduct(shopProductID)
+	if err != nil {
+		return err
+	}
+	if product.Status != models.ShopProductStatusDraft && product.Status != models.ShopProductStatusScheduled {
+		return errors.ErrInvalidPublishMode
+	}
+
+	return database.Database().Model(product).Updates(map[string]interface{}{
+		"status":     models.ShopProductStatusScheduled,
+		"publish_at": publishAt,
+	}).Error
+}
+
+func loadShopProduct(shopProductID uint) (*models.ShopProduct, error) {
+	var product models.ShopProduct
+	if err := database.Database().First(&product, shopProductID).Error; err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrShopProductNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func setProductStatus(product *models.ShopProduct, status string) error {
+	switch status {
+	case models.ShopProductStatusActive, models.ShopProductStatusDraft, models.ShopProductStatusArchived:
+	default:
+		return errors.ErrInvalidPublishMode
+	}
+
+	platform, credential, err := loadShopPlatform(product.ShopID)
+	if err != nil {
+		return err
+	}
+	updater, ok := platform.(ProductStatusUpdater)
+	if !ok {
+		return errors.ErrProductStatusNotSupported
+	}
+	if err := updater.SetProductStatus(credential, product.OuterID, status); err != nil {
+		return err
+	}
+
+	// 手动变更状态后取消定时上架
+	return database.Database().Model(product).Updates(map[string]interface{}{
+		"status":     status,
+		"publish_at": nil,
+	}).Error
+}
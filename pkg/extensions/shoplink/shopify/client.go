@@ -0,0 +1,280 @@
+package shopify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	shopify "github.com/bold-commerce/go-shopify/v4"
+)
+
+const (
+	// Shopify REST接口的漏桶默认容量，收到响应头后以实际值为准
+	defaultBucketSize = 40
+	// 漏桶排空所需时间，标准店铺 40/2s，Plus 店铺 80/4s，均为20秒排空
+	bucketDrainSeconds = 20
+	// 发起请求时保留的空位，避免与其他应用共享额度时触发429
+	bucketReserve = 2
+
+	maxRetries     = 5
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+)
+
+// callLimitBucket 按 X-Shopify-Shop-Api-Call-Limit 响应头维护的店铺漏桶
+type callLimitBucket struct {
+	mu       sync.Mutex
+	used     float64
+	size     float64
+	lastLeak time.Time
+}
+
+func newCallLimitBucket() *callLimitBucket {
+	return &callLimitBucket{
+		size:     defaultBucketSize,
+		lastLeak: time.Now(),
+	}
+}
+
+// leak 按时间流逝排出已用额度，调用方需持有锁
+func (b *callLimitBucket) leak(now time.Time) {
+	rate := b.size / bucketDrainSeconds
+	b.used = math.Max(0, b.used-now.Sub(b.lastLeak).Seconds()*rate)
+	b.lastLeak = now
+}
+
+// take 占用一个请求额度，额度不足时等待漏桶排出
+func (b *callLimitBucket) take(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.leak(now)
+	var wait time.Duration
+	if over := b.used + 1 - (b.size - bucketReserve); over > 0 {
+		wait = time.Duration(over / (b.size / bucketDrainSeconds) * float64(time.Second))
+	}
+	b.used++
+	b.mu.Unlock()
+
+	return sleepContext(ctx, wait)
+}
+
+// update 使用Shopify返回的实际用量校准漏桶
+func (b *callLimitBucket) update(header http.Header) {
+	parts := strings.Split(header.Get("X-Shopify-Shop-Api-Call-Limit"), "/")
+	if len(parts) != 2 {
+		return
+	}
+	used, err1 := strconv.Atoi(parts[0])
+	size, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || size <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.leak(time.Now())
+	b.size = float64(size)
+	b.used = math.Max(b.used, float64(used))
+}
+
+// fill 收到429时视为漏桶已满
+func (b *callLimitBucket) fill() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.leak(time.Now())
+	b.used = b.size
+}
+
+// throttledTransport 在请求前按漏桶限流，并按shouldRetry进行带抖动的退避重试
+type throttledTransport struct {
+	base   http.RoundTripper
+	bucket *callLimitBucket
+}
+
+func (t *throttledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// 缓存请求体以便重试
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := t.bucket.take(req.Context()); err != nil {
+			return nil, err
+		}
+
+		r := req.Clone(req.Context())
+		if body != nil {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+		}
+
+		resp, err := t.base.RoundTrip(r)
+		if err == nil {
+			t.bucket.update(resp.Header)
+		}
+
+		if attempt >= maxRetries || !shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		wait := retryDelay(resp, attempt)
+		if resp != nil {
+			if resp.StatusCode == http.StatusTooManyRequests {
+				t.bucket.fill()
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			fmt.Printf("Shopify request %s %s returned %d, retrying in %s (attempt %d/%d)\n",
+				req.Method, req.URL.Path, resp.StatusCode, wait, attempt+1, maxRetries)
+		} else {
+			fmt.Printf("Shopify request %s %s failed: %v, retrying in %s (attempt %d/%d)\n",
+				req.Method, req.URL.Path, err, wait, attempt+1, maxRetries)
+		}
+
+		if err := sleepContext(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// shouldRetry 429和请求发出前的连接错误总是重试
+// 5xx和请求发出后的网络错误只对幂等请求重试，避免POST重复创建资源
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		if req.Context().Err() != nil {
+			return false
+		}
+		return isIdempotent(req.Method) || isDialError(err)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return resp.StatusCode >= 500 && isIdempotent(req.Method)
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isDialError 建立连接失败时请求还没有发出
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// retryDelay 指数退避加随机抖动，若有Retry-After则不少于该值
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	backoff := retryBaseDelay << attempt
+	if backoff > retryMaxDelay {
+		backoff = retryMaxDelay
+	}
+	wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+
+	if resp != nil {
+		if seconds, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); err == nil {
+			if retryAfter := time.Duration(seconds * float64(time.Second)); retryAfter > wait {
+				wait = retryAfter
+			}
+		}
+	}
+	return wait
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type cachedClient struct {
+	token  string
+	client *shopify.Client
+}
+
+var (
+	clientMu    sync.Mutex
+	clientCache = map[string]*cachedClient{}
+	bucketCache = map[string]*callLimitBucket{}
+)
+
+// normalizeShopDomain 统一店铺域名作为缓存键
+func normalizeShopDomain(shopUrl string) string {
+	shopUrl = strings.TrimPrefix(strings.TrimPrefix(shopUrl, "https://"), "http://")
+	return strings.ToLower(strings.TrimSuffix(shopUrl, "/"))
+}
+
+// getClient 获取店铺的Shopify客户端，同一店铺共享漏桶和HTTP连接
+func (p *Shopify) getClient(shopUrl, token string) (*shopify.Client, error) {
+	key := normalizeShopDomain(shopUrl)
+
+	clientMu.Lock()
+	defer clientMu.Unlock()
+
+	if cached, ok := clientCache[key]; ok && cached.token == token {
+		return cached.client, nil
+	}
+
+	bucket, ok := bucketCache[key]
+	if !ok {
+		bucket = newCallLimitBucket()
+		bucketCache[key] = bucket
+	}
+
+	httpClient := &http.Client{
+		Timeout: p.httpClient.Timeout,
+		Transport: &throttledTransport{
+			base:   p.httpClient.Transport,
+			bucket: bucket,
+		},
+	}
+
+	client, err := shopify.NewClient(*app, key, token, shopify.WithHTTPClient(httpClient))
+	if err != nil {
+		return nil, err
+	}
+
+	// 获取access token时使用空token的临时客户端，不缓存
+	if token != "" {
+		clientCache[key] = &cachedClient{token: token, client: client}
+	}
+	return client, nil
+}
+
+// appForShop 返回使用店铺客户端的App副本，使OAuth请求也经过限流和重试
+func (p *Shopify) appForShop(shopUrl string) (shopify.App, error) {
+	client, err := p.getClient(shopUrl, "")
+	if err != nil {
+		return shopify.App{}, err
+	}
+	a := *app
+	a.Client = client
+	return a, nil
+}
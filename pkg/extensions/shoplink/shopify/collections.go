@@ -0,0 +1,354 @@
+package shopify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	shopify "github.com/bold-commerce/go-shopify/v4"
+	"github.com/flaboy/aira-shop/pkg/types"
+	"github.com/flaboy/pin/usererrors"
+	"github.com/spf13/cast"
+)
+
+// 集合列表每页数量，REST接口上限为250
+const collectionPageSize = 250
+
+// 网店渠道名称，指定销售渠道但不包含网店时从网店下架
+const onlineStoreChannel = "Online Store"
+
+func (p *Shopify) clientForCredential(credential *types.ShopCredential) (*shopify.Client, error) {
+	creds, err := decodeCredential(credential)
+	if err != nil {
+		return nil, err
+	}
+	client, err := p.getClient(creds.Url, creds.AccessToken)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to create Shopify client: %s", err.Error()))
+	}
+	return client, nil
+}
+
+// ListCollections 获取店铺的手动集合和智能集合
+func (p *Shopify) ListCollections(credential *types.ShopCredential) ([]types.Collection, error) {
+	client, err := p.clientForCredential(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+	return listCollections(ctx, client)
+}
+
+func listCollections(ctx context.Context, client *shopify.Client) ([]types.Collection, error) {
+	var collections []types.Collection
+
+	var sinceID uint64
+	for {
+		page, err := client.CustomCollection.List(ctx, shopify.ListOptions{Limit: collectionPageSize, SinceId: &sinceID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list custom collections: %w", err)
+		}
+		for _, c := range page {
+			collections = append(collections, types.Collection{
+				ID:     fmt.Sprintf("%d", c.Id),
+				Title:  c.Title,
+				Handle: c.Handle,
+			})
+			sinceID = c.Id
+		}
+		if len(page) < collectionPageSize {
+			break
+		}
+	}
+
+	sinceID = 0
+	for {
+		page, err := client.SmartCollection.List(ctx, shopify.ListOptions{Limit: collectionPageSize, SinceId: &sinceID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list smart collections: %w", err)
+		}
+		for _, c := range page {
+			collections = append(collections, types.Collection{
+				ID:     fmt.Sprintf("%d", c.Id),
+				Title:  c.Title,
+				Handle: c.Handle,
+				Smart:  true,
+			})
+			sinceID = c.Id
+		}
+		if len(page) < collectionPageSize {
+			break
+		}
+	}
+
+	return collections, nil
+}
+
+// CreateCollection 创建手动集合，智能集合按规则自动归集商品，不支持手动加入
+func (p *Shopify) CreateCollection(credential *types.ShopCredential, title string) (*types.Collection, error) {
+	client, err := p.clientForCredential(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	return createCollection(ctx, client, title)
+}
+
+func createCollection(ctx context.Context, client *shopify.Client, title string) (*types.Collection, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return nil, usererrors.New("Collection title is required")
+	}
+
+	c, err := client.CustomCollection.Create(ctx, shopify.CustomCollection{
+		Title:     title,
+		Published: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create collection %s: %w", title, err)
+	}
+	return &types.Collection{
+		ID:     fmt.Sprintf("%d", c.Id),
+		Title:  c.Title,
+		Handle: c.Handle,
+	}, nil
+}
+
+// AddProductToCollections 将商品加入手动集合
+func (p *Shopify) AddProductToCollections(credential *types.ShopCredential, productID string, collectionIDs []string) error {
+	client, err := p.clientForCredential(credential)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+	return addProductToCollections(ctx, client, cast.ToUint64(productID), collectionIDs)
+}
+
+func addProductToCollections(ctx context.Context, client *shopify.Client, productID uint64, collectionIDs []string) error {
+	if productID == 0 {
+		return usererrors.New("Invalid product ID")
+	}
+
+	var failed []string
+	for _, id := range collectionIDs {
+		_, err := client.Collect.Create(ctx, shopify.Collect{
+			CollectionId: cast.ToUint64(id),
+			ProductId:    productID,
+		})
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", id, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to add product to collections: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// resolveCollections 按标题匹配已有集合（不区分大小写），不存在时创建手动集合
+func resolveCollections(ctx context.Context, client *shopify.Client, titles []string) ([]types.Collection, error) {
+	existing, err := listCollections(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	byTitle := make(map[string]types.Collection)
+	for _, c := range existing {
+		key := strings.ToLower(c.Title)
+		// 同名时优先使用手动集合
+		if prev, ok := byTitle[key]; ok && !prev.Smart {
+			continue
+		}
+		byTitle[key] = c
+	}
+
+	var result []types.Collection
+	for _, title := range titles {
+		title = strings.TrimSpace(title)
+		if title == "" {
+			continue
+		}
+		if c, ok := byTitle[strings.ToLower(title)]; ok {
+			if c.Smart {
+				return nil, fmt.Errorf("collection %s is a smart collection", title)
+			}
+			result = append(result, c)
+			continue
+		}
+		c, err := createCollection(ctx, client, title)
+		if err != nil {
+			return nil, err
+		}
+		byTitle[strings.ToLower(title)] = *c
+		result = append(result, *c)
+	}
+	return result, nil
+}
+
+type publicationsResponse struct {
+	Publications struct {
+		Edges []struct {
+			Node struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"node"`
+		} `json:"edges"`
+	} `json:"publications"`
+}
+
+type userError struct {
+	Field   []string `json:"field"`
+	Message string   `json:"message"`
+}
+
+type publishResponse struct {
+	PublishablePublish struct {
+		UserErrors []userError `json:"userErrors"`
+	} `json:"publishablePublish"`
+	PublishableUnpublish struct {
+		UserErrors []userError `json:"userErrors"`
+	} `json:"publishableUnpublish"`
+}
+
+// ListSalesChannels 获取店铺的销售渠道，需要read_publications权限
+func (p *Shopify) ListSalesChannels(credential *types.ShopCredential) ([]types.SalesChannel, error) {
+	client, err := p.clientForCredential(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	return listSalesChannels(ctx, client)
+}
+
+func listSalesChannels(ctx context.Context, client *shopify.Client) ([]types.SalesChannel, error) {
+	var resp publicationsResponse
+	if err := client.GraphQL.Query(ctx, `{ publications(first: 100) { edges { node { id name } } } }`, nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to list publications: %w", err)
+	}
+
+	channels := make([]types.SalesChannel, 0, len(resp.Publications.Edges))
+	for _, edge := range resp.Publications.Edges {
+		channels = append(channels, types.SalesChannel{
+			ID:   edge.Node.ID,
+			Name: edge.Node.Name,
+		})
+	}
+	return channels, nil
+}
+
+// PublishToSalesChannels 将商品发布到指定销售渠道，需要write_publications权限
+func (p *Shopify) PublishToSalesChannels(credential *types.ShopCredential, productID string, channelIDs []string) error {
+	client, err := p.clientForCredential(credential)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	return publishProduct(ctx, client, cast.ToUint64(productID), channelIDs, false)
+}
+
+// publishProduct 发布或下架商品在指定渠道上的展示
+func publishProduct(ctx context.Context, client *shopify.Client, productID uint64, channelIDs []string, unpublish bool) error {
+	if productID == 0 {
+		return usererrors.New("Invalid product ID")
+	}
+	if len(channelIDs) == 0 {
+		return nil
+	}
+
+	input := make([]map[string]string, 0, len(channelIDs))
+	for _, id := range channelIDs {
+		input = append(input, map[string]string{"publicationId": id})
+	}
+
+	mutation := "publishablePublish"
+	if unpublish {
+		mutation = "publishableUnpublish"
+	}
+	query := fmt.Sprintf(`mutation($id: ID!, $input: [PublicationInput!]!) {
+  %s(id: $id, input: $input) { userErrors { field message } }
+}`, mutation)
+
+	var resp publishResponse
+	err := client.GraphQL.Query(ctx, query, map[string]interface{}{
+		"id":    fmt.Sprintf("gid://shopify/Product/%d", productID),
+		"input": input,
+	}, &resp)
+	if err != nil {
+		return fmt.Errorf("failed to %s product: %w", mutation, err)
+	}
+
+	userErrors := resp.PublishablePublish.UserErrors
+	if unpublish {
+		userErrors = resp.PublishableUnpublish.UserErrors
+	}
+	if len(userErrors) > 0 {
+		var messages []string
+		for _, e := range userErrors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("%s rejected: %s", mutation, strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// applySalesChannels 按渠道ID或名称发布商品，未包含网店时从网店下架
+// 返回实际发布的渠道
+func applySalesChannels(ctx context.Context, client *shopify.Client, productID uint64, wanted []string) ([]types.SalesChannel, error) {
+	channels, err := listSalesChannels(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []types.SalesChannel
+	var selectedIDs []string
+	var missing []string
+	includesOnlineStore := false
+	for _, want := range wanted {
+		found := false
+		for _, ch := range channels {
+			if ch.ID == want || strings.EqualFold(ch.Name, want) {
+				selected = append(selected, ch)
+				selectedIDs = append(selectedIDs, ch.ID)
+				if strings.EqualFold(ch.Name, onlineStoreChannel) {
+					includesOnlineStore = true
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, want)
+		}
+	}
+
+	if err := publishProduct(ctx, client, productID, selectedIDs, false); err != nil {
+		return nil, err
+	}
+
+	// 商品创建时默认发布到网店
+	if !includesOnlineStore {
+		for _, ch := range channels {
+			if strings.EqualFold(ch.Name, onlineStoreChannel) {
+				if err := publishProduct(ctx, client, productID, []string{ch.ID}, true); err != nil {
+					return selected, err
+				}
+				break
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return selected, fmt.Errorf("sales channels not found: %s", strings.Join(missing, ", "))
+	}
+	return selected, nil
+}
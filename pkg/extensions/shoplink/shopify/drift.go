@@ -0,0 +1,318 @@
+package shopify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	shopify "github.com/bold-commerce/go-shopify/v4"
+	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/extensions/shoplink/utils"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
+	"github.com/flaboy/pin/usererrors"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cast"
+)
+
+// DetectDrift 获取Shopify商品并与最后发布的数据比较
+func (p *Shopify) DetectDrift(credential *types.ShopCredential, shopProduct *models.ShopProduct) (*types.DriftReport, error) {
+	client, err := p.clientForCredential(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	actual, err := client.Product.Get(ctx, cast.ToUint64(shopProduct.OuterID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product %s: %w", shopProduct.OuterID, err)
+	}
+	return detectDrift(shopProduct, actual)
+}
+
+// ReapplyProduct 将最后发布的标题、价格、SKU重新写回Shopify，并恢复被删除的变体和图片
+// 商户在Shopify新增的变体和图片保留不动
+func (p *Shopify) ReapplyProduct(credential *types.ShopCredential, shopProduct *models.ShopProduct) error {
+	client, err := p.clientForCredential(credential)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	actual, err := client.Product.Get(ctx, cast.ToUint64(shopProduct.OuterID), nil)
+	if err != nil {
+		return fmt.Errorf("failed to get product %s: %w", shopProduct.OuterID, err)
+	}
+	return reapplyProduct(ctx, client, shopProduct, actual)
+}
+
+// handleProductUpdate 商户在Shopify修改商品时检查漂移，只处理本系统发布的商品
+// 已连接的店铺缺少products/update订阅时，由健康检查补充订阅
+func (p *Shopify) handleProductUpdate(event json.RawMessage) error {
+	var actual shopify.Product
+	if err := json.Unmarshal(event, &actual); err != nil {
+		return fmt.Errorf("error unmarshaling product: %v", err)
+	}
+
+	shopProduct, ok, err := utils.GetShopProduct("shopify", cast.ToString(actual.Id))
+	if err != nil || !ok {
+		return err
+	}
+
+	report, err := detectDrift(shopProduct, &actual)
+	if err != nil {
+		return err
+	}
+
+	record, err := utils.ProcessDrift(shopProduct, report, func() error {
+		var shop models.ShopLink
+		if err := database.Database().First(&shop, shopProduct.ShopID).Error; err != nil {
+			return err
+		}
+		var creds ShopifyCredential
+		if err := json.Unmarshal(shop.Credentials, &creds); err != nil {
+			return err
+		}
+		client, err := p.getClient(creds.Url, creds.AccessToken)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+		defer cancel()
+		return reapplyProduct(ctx, client, shopProduct, &actual)
+	})
+	if err != nil {
+		return err
+	}
+	if record != nil {
+		fmt.Printf("Shopify product %d drifted (%d changes), action: %s\n", actual.Id, len(report.Changes), record.Action)
+	}
+	return nil
+}
+
+func decodeShopProduct(shopProduct *models.ShopProduct) (*types.ProductData, *ShopifyRemoteData, error) {
+	var expected types.ProductData
+	if err := json.Unmarshal(shopProduct.Data, &expected); err != nil {
+		return nil, nil, fmt.Errorf("invalid product data: %w", err)
+	}
+	var remote ShopifyRemoteData
+	if err := json.Unmarshal(shopProduct.RemoteData, &remote); err != nil {
+		return nil, nil, fmt.Errorf("invalid remote data: %w", err)
+	}
+	return &expected, &remote, nil
+}
+
+// detectDrift 比较标题、变体价格/SKU、变体和图片的增删
+func detectDrift(shopProduct *models.ShopProduct, actual *shopify.Product) (*types.DriftReport, error) {
+	expected, remote, err := decodeShopProduct(shopProduct)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &types.DriftReport{
+		ShopProductID: shopProduct.ID,
+		CheckedAt:     time.Now(),
+	}
+	change := func(field string, variantID uint, want, got string) {
+		report.Changes = append(report.Changes, types.DriftChange{
+			Field:     field,
+			VariantID: variantID,
+			Expected:  want,
+			Actual:    got,
+		})
+	}
+
+	if actual.Title != expected.ProductName {
+		change("title", 0, expected.ProductName, actual.Title)
+	}
+
+	actualVariants := make(map[uint64]*shopify.Variant)
+	for i := range actual.Variants {
+		actualVariants[actual.Variants[i].Id] = &actual.Variants[i]
+	}
+	shopifyIDs := reverseVariantMapper(remote.VariantMapper)
+
+	for _, v := range expected.Variants {
+		sv, ok := actualVariants[shopifyIDs[v.ID]]
+		if !ok {
+			change("variant", v.ID, variantLabel(&v), "deleted")
+			continue
+		}
+		if !decimalEqual(v.Price, sv.Price) {
+			change("price", v.ID, decimalString(v.Price), decimalString(sv.Price))
+		}
+		if !decimalEqual(v.CompareAtPrice, sv.CompareAtPrice) {
+			change("compare_at_price", v.ID, decimalString(v.CompareAtPrice), decimalString(sv.CompareAtPrice))
+		}
+		if v.Sku != sv.Sku {
+			change("sku", v.ID, v.Sku, sv.Sku)
+		}
+	}
+	for _, sv := range actual.Variants {
+		if _, ok := remote.VariantMapper[sv.Id]; !ok {
+			change("variant", 0, "", "added: "+sv.Title)
+		}
+	}
+
+	// 早期发布的商品没有记录图片，无法判断增删，跳过图片比较
+	if len(remote.Images) == 0 {
+		return report, nil
+	}
+
+	actualImages := make(map[string]bool)
+	for _, img := range actual.Images {
+		actualImages[fmt.Sprintf("%d", img.Id)] = true
+	}
+	publishedImages := make(map[string]bool)
+	for _, img := range remote.Images {
+		if img.RemoteID == "" {
+			continue
+		}
+		publishedImages[img.RemoteID] = true
+		if !actualImages[img.RemoteID] {
+			change("image", 0, img.Src, "deleted")
+		}
+	}
+	for _, img := range actual.Images {
+		if !publishedImages[fmt.Sprintf("%d", img.Id)] {
+			change("image", 0, "", "added: "+img.Src)
+		}
+	}
+
+	return report, nil
+}
+
+// reapplyProduct 按最后发布的数据修正actual，变更后的变体和图片ID写回RemoteData
+func reapplyProduct(ctx context.Context, client *shopify.Client, shopProduct *models.ShopProduct, actual *shopify.Product) error {
+	expected, remote, err := decodeShopProduct(shopProduct)
+	if err != nil {
+		return err
+	}
+	if actual.Id == 0 {
+		return usererrors.New("Invalid product ID")
+	}
+
+	if actual.Title != expected.ProductName {
+		if _, err := client.Product.Update(ctx, shopify.Product{Id: actual.Id, Title: expected.ProductName}); err != nil {
+			return fmt.Errorf("failed to restore title: %w", err)
+		}
+	}
+
+	actualVariants := make(map[uint64]*shopify.Variant)
+	for i := range actual.Variants {
+		actualVariants[actual.Variants[i].Id] = &actual.Variants[i]
+	}
+	shopifyIDs := reverseVariantMapper(remote.VariantMapper)
+	remoteChanged := false
+
+	for _, v := range expected.Variants {
+		shopifyID := shopifyIDs[v.ID]
+		sv, ok := actualVariants[shopifyID]
+		if !ok {
+			created, err := client.Variant.Create(ctx, actual.Id, shopify.Variant{
+				Sku:             v.Sku,
+				Title:           v.Title,
+				Price:           v.Price,
+				CompareAtPrice:  v.CompareAtPrice,
+				Weight:          v.Weight,
+				WeightUnit:      v.WeightUnit,
+				Option1:         v.Option1,
+				Option2:         v.Option2,
+				Option3:         v.Option3,
+				Barcode:         v.Barcode,
+				RequireShipping: true,
+				Metafields: []shopify.Metafield{
+					{
+						Namespace: reservedMetafieldNamespace,
+						Key:       "origin",
+						Type:      shopify.MetafieldTypeSingleLineTextField,
+						Value:     v.ID,
+					},
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to restore variant %d: %w", v.ID, err)
+			}
+			delete(remote.VariantMapper, shopifyID)
+			remote.VariantMapper[created.Id] = v.ID
+			remoteChanged = true
+			continue
+		}
+
+		if decimalEqual(v.Price, sv.Price) && decimalEqual(v.CompareAtPrice, sv.CompareAtPrice) && v.Sku == sv.Sku {
+			continue
+		}
+		_, err := client.Variant.Update(ctx, shopify.Variant{
+			Id:              sv.Id,
+			Sku:             v.Sku,
+			Price:           v.Price,
+			CompareAtPrice:  v.CompareAtPrice,
+			RequireShipping: sv.RequireShipping,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to restore variant %d: %w", v.ID, err)
+		}
+	}
+
+	actualImages := make(map[string]bool)
+	for _, img := range actual.Images {
+		actualImages[fmt.Sprintf("%d", img.Id)] = true
+	}
+	for i := range remote.Images {
+		img := &remote.Images[i]
+		if img.RemoteID == "" || actualImages[img.RemoteID] {
+			continue
+		}
+		created, err := client.Image.Create(ctx, actual.Id, shopify.Image{Src: img.Src})
+		if err != nil {
+			return fmt.Errorf("failed to restore image %s: %w", img.Src, err)
+		}
+		img.RemoteID = fmt.Sprintf("%d", created.Id)
+		remoteChanged = true
+	}
+
+	if !remoteChanged {
+		return nil
+	}
+	remoteData, err := json.Marshal(remote)
+	if err != nil {
+		return err
+	}
+	shopProduct.RemoteData = remoteData
+	return database.Database().Model(shopProduct).Update("remote_data", remoteData).Error
+}
+
+func reverseVariantMapper(mapper map[uint64]uint) map[uint]uint64 {
+	result := make(map[uint]uint64, len(mapper))
+	for shopifyID, variantID := range mapper {
+		result[variantID] = shopifyID
+	}
+	return result
+}
+
+func variantLabel(v *types.ProductVariant) string {
+	if v.Sku != "" {
+		return v.Sku
+	}
+	return v.Title
+}
+
+func decimalEqual(a, b *decimal.Decimal) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+func decimalString(d *decimal.Decimal) string {
+	if d == nil {
+		return ""
+	}
+	return d.String()
+}
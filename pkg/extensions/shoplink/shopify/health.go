@@ -0,0 +1,128 @@
+package shopify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	shopify "github.com/bold-commerce/go-shopify/v4"
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/flaboy/aira-shop/pkg/types"
+)
+
+// CheckHealth 检查访问令牌、授权范围和webhook订阅，缺失的webhook会重新创建
+func (p *Shopify) CheckHealth(credential *types.ShopCredential) (*types.HealthCheckResult, error) {
+	creds, err := decodeCredential(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := p.getClient(creds.Url, creds.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Shopify client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	result := &types.HealthCheckResult{}
+
+	// 验证访问令牌
+	if _, err := client.Shop.Get(ctx, nil); err != nil {
+		if status := responseStatus(err); status == http.StatusUnauthorized || status == http.StatusForbidden || status == http.StatusPaymentRequired || status == http.StatusNotFound {
+			result.Message = fmt.Sprintf("access token rejected (%d): %v", status, err)
+			return result, nil
+		}
+		return nil, fmt.Errorf("failed to get shop info: %w", err)
+	}
+	result.CredentialsValid = true
+
+	// 验证授权范围
+	scopes, err := client.AccessScopes.List(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access scopes: %w", err)
+	}
+	for _, scope := range scopes {
+		result.GrantedScopes = append(result.GrantedScopes, scope.Handle)
+	}
+	result.MissingScopes = missingScopes(requiredScopes(), result.GrantedScopes)
+
+	// 验证webhook订阅
+	webhooks, err := client.Webhook.List(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	subscribed := make(map[string]bool)
+	for _, webhook := range webhooks {
+		if webhook.Address == config.Config.Shopify.EventBridgeARN {
+			subscribed[webhook.Topic] = true
+		}
+	}
+	for _, topic := range webhookTopics {
+		if !subscribed[topic] {
+			result.MissingWebhooks = append(result.MissingWebhooks, topic)
+		}
+	}
+
+	if len(result.MissingWebhooks) > 0 {
+		if err := p.subscribeWebhooks(client); err != nil {
+			result.Message = fmt.Sprintf("failed to re-create webhooks: %v", err)
+		} else {
+			result.RepairedWebhooks = result.MissingWebhooks
+			result.MissingWebhooks = nil
+		}
+	}
+
+	result.Healthy = len(result.MissingScopes) == 0 && len(result.MissingWebhooks) == 0
+	if len(result.MissingScopes) > 0 {
+		result.Message = "missing scopes: " + strings.Join(result.MissingScopes, ",")
+	}
+
+	return result, nil
+}
+
+// requiredScopes 应用申请的授权范围
+func requiredScopes() []string {
+	var scopes []string
+	for _, scope := range strings.Split(app.Scope, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// missingScopes 返回未授予的范围，write_xxx 隐含 read_xxx
+func missingScopes(required []string, granted []string) []string {
+	has := make(map[string]bool)
+	for _, scope := range granted {
+		scope = strings.TrimSpace(scope)
+		has[scope] = true
+		if strings.HasPrefix(scope, "write_") {
+			has["read_"+strings.TrimPrefix(scope, "write_")] = true
+		}
+	}
+
+	var missing []string
+	for _, scope := range required {
+		if !has[scope] {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// responseStatus 获取Shopify接口错误的HTTP状态码
+func responseStatus(err error) int {
+	switch e := err.(type) {
+	case shopify.ResponseError:
+		return e.Status
+	case shopify.RateLimitError:
+		return e.Status
+	case shopify.ResponseDecodingError:
+		return e.Status
+	}
+	return 0
+}
@@ -0,0 +1,47 @@
+package shopify
+
+import (
+	"context"
+	"fmt"
+
+	shopify "github.com/bold-commerce/go-shopify/v4"
+	"github.com/flaboy/aira-shop/pkg/extensions/shoplink/utils"
+	"github.com/flaboy/aira-shop/pkg/types"
+)
+
+// assignVariantImages 记录Shopify图片ID，并将变体图片关联到对应的Shopify变体
+// Shopify按提交顺序返回图片，与prepared一一对应
+func (p *Shopify) assignVariantImages(ctx context.Context, client *shopify.Client, product *shopify.Product, prepared []*utils.PreparedImage, results []types.ImageResult, variantMapper map[uint64]uint) {
+	shopifyVariants := make(map[uint]uint64)
+	for shopifyID, variantID := range variantMapper {
+		shopifyVariants[variantID] = shopifyID
+	}
+
+	for i, img := range prepared {
+		if i >= len(product.Images) {
+			break
+		}
+		result := &results[img.ResultIndex]
+		image := product.Images[i]
+		result.RemoteID = fmt.Sprintf("%d", image.Id)
+
+		var variantIds []uint64
+		for _, variantID := range img.VariantIDs {
+			if shopifyID, ok := shopifyVariants[variantID]; ok {
+				variantIds = append(variantIds, shopifyID)
+			}
+		}
+		if len(variantIds) == 0 {
+			continue
+		}
+
+		_, err := client.Image.Update(ctx, product.Id, shopify.Image{
+			Id:         image.Id,
+			VariantIds: variantIds,
+		})
+		if err != nil {
+			fmt.Printf("Failed to assign image %d to variants %v: %v\n", image.Id, variantIds, err)
+			result.Error = fmt.Sprintf("failed to assign variants: %v", err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package shopify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	shopify "github.com/bold-commerce/go-shopify/v4"
+	"github.com/flaboy/aira-shop/pkg/types"
+)
+
+// 系统内部使用的命名空间，用于记录变体来源，不允许业务数据写入
+const reservedMetafieldNamespace = "aira-shop"
+
+// toShopifyMetafields 转换自定义字段，Type为空时按单行文本处理
+func toShopifyMetafields(fields []types.ProductMetafield) ([]shopify.Metafield, error) {
+	var result []shopify.Metafield
+	for _, f := range fields {
+		if f.Namespace == "" || f.Key == "" {
+			return nil, fmt.Errorf("metafield namespace and key are required")
+		}
+		if f.Namespace == reservedMetafieldNamespace {
+			return nil, fmt.Errorf("metafield namespace %s is reserved", reservedMetafieldNamespace)
+		}
+		metaType := shopify.MetafieldType(f.Type)
+		if metaType == "" {
+			metaType = shopify.MetafieldTypeSingleLineTextField
+		}
+		result = append(result, shopify.Metafield{
+			Namespace: f.Namespace,
+			Key:       f.Key,
+			Type:      metaType,
+			Value:     f.Value,
+		})
+	}
+	return result, nil
+}
+
+// applyCustomsInfo 海关编码和原产国属于库存项，商品创建后逐个变体更新，需要write_inventory权限
+func (p *Shopify) applyCustomsInfo(ctx context.Context, client *shopify.Client, created *shopify.Product, product *types.ProductData, remote *ShopifyRemoteData) {
+	variants := make(map[uint]*types.ProductVariant)
+	for i := range product.Variants {
+		variants[product.Variants[i].ID] = &product.Variants[i]
+	}
+
+	for _, sv := range created.Variants {
+		v, ok := variants[remote.VariantMapper[sv.Id]]
+		if !ok || (v.HSCode == "" && v.CountryOfOrigin == "") || sv.InventoryItemId == 0 {
+			continue
+		}
+
+		item := shopify.InventoryItem{Id: sv.InventoryItemId}
+		if v.HSCode != "" {
+			hsCode := v.HSCode
+			item.HarmonizedSystemCode = &hsCode
+		}
+		if v.CountryOfOrigin != "" {
+			country := strings.ToUpper(v.CountryOfOrigin)
+			item.CountryCodeOfOrigin = &country
+		}
+		if _, err := client.InventoryItem.Update(ctx, item); err != nil {
+			remote.Errors = append(remote.Errors, fmt.Sprintf("failed to set customs info for variant %d: %v", v.ID, err))
+		}
+	}
+}
@@ -2,8 +2,6 @@ package shopify
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -30,7 +28,6 @@ import (
 	goshopify "github.com/bold-commerce/go-shopify/v4"
 	shopify "github.com/bold-commerce/go-shopify/v4"
 	"github.com/flaboy/aira-web/pkg/helper"
-	"gorm.io/gorm"
 )
 
 var app *shopify.App
@@ -60,7 +57,7 @@ func (p *Shopify) Init() error {
 		ApiKey:      config.Config.Shopify.ApiKey,
 		ApiSecret:   config.Config.Shopify.ApiSecret,
 		RedirectUrl: helper.BuildUrl("stores/callback/shopify"),
-		Scope:       "read_products,write_products,read_orders,write_orders",
+		Scope:       config.Config.Shopify.Scopes,
 	}
 
 	go p.StartEventListener()
@@ -75,16 +72,20 @@ type Shopify struct {
 	httpClient *http.Client
 }
 
+// 店铺需要订阅的webhook主题
+var webhookTopics = []string{
+	"orders/create",
+	"orders/updated",
+	"orders/paid",
+	"orders/cancelled",
+	"orders/fulfilled",
+	"products/update",
+}
+
 // subscribeWebhooks 为店铺订阅所需的webhook
 func (p *Shopify) subscribeWebhooks(client *shopify.Client) error {
 	ctx := context.Background()
-	topics := []string{
-		"orders/create",
-		"orders/updated",
-		"orders/paid",
-		"orders/cancelled",
-		"orders/fulfilled",
-	}
+	topics := webhookTopics
 
 	// 先获取现有的webhooks
 	existingWebhooks, err := client.Webhook.List(ctx, nil)
@@ -136,11 +137,30 @@ type ShopifyCredential struct {
 	AccessToken string
 }
 
+func decodeCredential(credential *types.ShopCredential) (*ShopifyCredential, error) {
+	var creds ShopifyCredential
+	credData, err := json.Marshal(credential.Data)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to marshal credentials: %s", err.Error()))
+	}
+
+	if err := json.Unmarshal(credData, &creds); err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to unmarshal credentials: %s", err.Error()))
+	}
+	return &creds, nil
+}
+
 func (p *Shopify) HandleCallback(c *pin.Context, businessContext json.RawMessage, callbackUrl *url.URL) (*types.CallbackResponse, error) {
 	if ok, _ := app.VerifyAuthorizationURL(callbackUrl); !ok {
 		return nil, errors.ErrInvalidCallbackSignature
 	}
 
+	// 店铺归属于发起连接的商户
+	ownerID, err := utils.ResolveOwner(businessContext)
+	if err != nil {
+		return nil, errors.ErrShopCreation
+	}
+
 	query := callbackUrl.Query()
 	shopUrl := query.Get("shop")
 	code := query.Get("code")
@@ -148,13 +168,17 @@ func (p *Shopify) HandleCallback(c *pin.Context, businessContext json.RawMessage
 	defer cancel()
 
 	// 获取access token
-	token, err := app.GetAccessToken(ctx, shopUrl, code)
+	shopApp, err := p.appForShop(shopUrl)
+	if err != nil {
+		return nil, errors.ErrShopifyClientCreation
+	}
+	token, err := shopApp.GetAccessToken(ctx, shopUrl, code)
 	if err != nil {
 		return nil, errors.ErrAccessTokenFailed
 	}
 
-	// 使用自定义HTTP客户端创建Shopify客户端
-	client, err := shopify.NewClient(*app, shopUrl, token, shopify.WithHTTPClient(p.httpClient))
+	// 使用带限流和重试的店铺客户端
+	client, err := p.getClient(shopUrl, token)
 	if err != nil {
 		return nil, errors.ErrShopifyClientCreation
 	}
@@ -164,6 +188,16 @@ func (p *Shopify) HandleCallback(c *pin.Context, businessContext json.RawMessage
 		return nil, errors.ErrShopInfoFailed
 	}
 
+	// 记录实际授予的权限
+	var grantedScopes []string
+	if scopes, err := client.AccessScopes.List(ctx, nil); err != nil {
+		fmt.Printf("Failed to list access scopes for shop %s: %v\n", shopUrl, err)
+	} else {
+		for _, scope := range scopes {
+			grantedScopes = append(grantedScopes, scope.Handle)
+		}
+	}
+
 	// 订阅webhook
 	if err := p.subscribeWebhooks(client); err != nil {
 		fmt.Printf("Shopify webhook subscription failed for shop %s: %v\n", shopUrl, err)
@@ -183,33 +217,19 @@ func (p *Shopify) HandleCallback(c *pin.Context, businessContext json.RawMessage
 	}
 
 	// 直接创建ShopLink模型
-	shopLink := &models.ShopLink{
+	shopLink, err := utils.SaveShopLink(&models.ShopLink{
+		OwnerID:     ownerID,
 		Platform:    "shopify",
+		Domain:      shopUrl,
 		Name:        shopName,
 		Url:         "https://" + shopUrl,
 		Credentials: credentialsJson,
-	}
-
-	db := database.Database()
-
-	// 检查是否已存在
-	var existing models.ShopLink
-	err = db.Where("name = ? AND platform = ?", shopName, "shopify").First(&existing).Error
-	if err == nil {
-		// 更新现有记录
-		existing.Credentials = credentialsJson
-		existing.Url = "https://" + shopUrl
-		if err := db.Save(&existing).Error; err != nil {
-			return nil, errors.ErrShopCreation
-		}
-		shopLink = &existing
-	} else if err != gorm.ErrRecordNotFound {
+		Scopes:      strings.Join(grantedScopes, ","),
+	})
+	if err == errors.ErrShopOwnedByOther {
+		return nil, err
+	} else if err != nil {
 		return nil, errors.ErrShopCreation
-	} else {
-		// 创建新记录
-		if err := db.Create(shopLink).Error; err != nil {
-			return nil, errors.ErrShopCreation
-		}
 	}
 
 	// 触发店铺连接事件
@@ -234,20 +254,15 @@ func (p *Shopify) HandleCallback(c *pin.Context, businessContext json.RawMessage
 	}, nil
 }
 
-func generateNonce() (string, error) {
-	nonce := make([]byte, 16)
-	if _, err := rand.Read(nonce); err != nil {
-		return "", err
-	}
-	return hex.EncodeToString(nonce), nil
-}
-
 func (p *Shopify) HandleRequest(c *pin.Context, path string) (*types.HandleRequestResult, error) {
 	shopName := c.Query("shop")
 	if shopName == "" {
 		return nil, errors.ErrShopNameEmpty
 	}
-	state, err := generateNonce()
+	if path == "upgrade" {
+		return p.handleScopeUpgrade(c, shopName)
+	}
+	state, err := utils.GenerateNonce()
 	if err != nil {
 		return nil, errors.ErrNonceGeneration
 	}
@@ -261,30 +276,91 @@ func (p *Shopify) HandleRequest(c *pin.Context, path string) (*types.HandleReque
 	}, nil
 }
 
+// handleScopeUpgrade 检查已连接店铺缺少的权限，需要时生成重新授权URL
+// 只有店铺所属商户可以发起，没有所属商户的旧店铺需要重新安装，授权回调会更新原有ShopLink
+func (p *Shopify) handleScopeUpgrade(c *pin.Context, shopName string) (*types.HandleRequestResult, error) {
+	shop, err := utils.FindShopByDomain("shopify", shopName)
+	if err != nil {
+		return nil, err
+	}
+
+	ownerID, err := utils.ResolveRequestOwner(c)
+	if err != nil {
+		return nil, err
+	}
+	if ownerID == "" || shop.OwnerID != ownerID {
+		return nil, errors.ErrShopNotFound
+	}
+
+	missing := missingScopes(requiredScopes(), strings.Split(shop.Scopes, ","))
+	if len(missing) == 0 {
+		return &types.HandleRequestResult{
+			Status: types.HandleRequestStatusNoUpgradeNeeded,
+		}, nil
+	}
+
+	state, err := utils.GenerateNonce()
+	if err != nil {
+		return nil, errors.ErrNonceGeneration
+	}
+	authUrl, err := app.AuthorizeUrl(shopName, state)
+	if err != nil {
+		return nil, errors.ErrAuthURLGeneration
+	}
+
+	return &types.HandleRequestResult{
+		AuthURL:         authUrl,
+		UpgradeRequired: true,
+		MissingScopes:   missing,
+		Status:          types.HandleRequestStatusUpgradeRequired,
+	}, nil
+}
+
 type ShopifyRemoteData struct {
 	VariantMapper map[uint64]uint
+	Images        []types.ImageResult
+	Collections   []types.Collection   `json:",omitempty"`
+	SalesChannels []types.SalesChannel `json:",omitempty"`
+	// 集合和渠道设置失败不影响发布，错误记录在这里
+	Errors []string `json:",omitempty"`
 }
 
 func (p *Shopify) PutProduct(credential *types.ShopCredential, product *types.ProductData, businessContext json.RawMessage) (*types.PutProductResult, error) {
 	// Unmarshal the credentials
-	var creds ShopifyCredential
-	credData, err := json.Marshal(credential.Data)
+	creds, err := decodeCredential(credential)
 	if err != nil {
-		return nil, usererrors.New(fmt.Sprintf("Failed to marshal credentials: %s", err.Error()))
+		return nil, err
 	}
 
-	if err := json.Unmarshal(credData, &creds); err != nil {
-		return nil, usererrors.New(fmt.Sprintf("Failed to unmarshal credentials: %s", err.Error()))
+	// Get the cached, rate-limited Shopify client for this shop
+	client, err := p.getClient(creds.Url, creds.AccessToken)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to create Shopify client: %s", err.Error()))
 	}
 
-	// Create a new Shopify client
-	client, err := shopify.NewClient(*app, creds.Url, creds.AccessToken)
+	// 获取店铺ID
+	shop, err := utils.FindShopByDomain("shopify", creds.Url)
 	if err != nil {
-		return nil, usererrors.New(fmt.Sprintf("Failed to create Shopify client: %s", err.Error()))
+		return nil, usererrors.New(fmt.Sprintf("Failed to find shop: %s", err.Error()))
 	}
+	db := database.Database()
+
+	// 应用店铺覆盖规则，后续保存的是实际发布的数据
+	product, err = utils.ApplyShopOverrides(shop, product)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to apply shop overrides: %s", err.Error()))
+	}
+
+	status, publishAt, err := utils.PublishStatus(product)
+	if err != nil {
+		return nil, err
+	}
+
+	// 校验、去重图片，失败的图片记录在结果中
+	prepared, imageResults := utils.PrepareImages(context.Background(), product)
 
 	// Create a new product
-	newProduct, err := p.toShopifyProduct(product)
+	newProduct, err := p.toShopifyProduct(product, prepared)
 	if err != nil {
 		return nil, usererrors.New(fmt.Sprintf("Failed to convert product: %s", err.Error()))
 	}
@@ -324,22 +400,20 @@ func (p *Shopify) PutProduct(credential *types.ShopCredential, product *types.Pr
 		}
 	}
 
-	// 获取店铺ID
-	var shop models.ShopLink
-	db := database.Database()
-	err = db.Where("platform = ? AND url = ?", "shopify", "https://"+creds.Url).First(&shop).Error
-	if err != nil {
-		return nil, usererrors.New(fmt.Sprintf("Failed to find shop: %s", err.Error()))
-	}
+	p.assignVariantImages(ctx, client, productResp, prepared, imageResults, ShopifyRemoteData.VariantMapper)
+	ShopifyRemoteData.Images = imageResults
+	p.applyCustomsInfo(ctx, client, productResp, product, ShopifyRemoteData)
+	p.applyCollectionsAndChannels(ctx, client, productResp.Id, product, ShopifyRemoteData)
 
 	// 保存产品信息
 	shopProduct := models.ShopProduct{
-		ShopID:   shop.ID,
-		OuterID:  fmt.Sprintf("%d", productResp.Id),
-		Status:   "active",
-		Url:      fmt.Sprintf("https://%s/admin/products/%d", creds.Url, productResp.Id),
-		Name:     productResp.Title,
-		Platform: "shopify",
+		ShopID:    shop.ID,
+		OuterID:   fmt.Sprintf("%d", productResp.Id),
+		Status:    status,
+		Url:       fmt.Sprintf("https://%s/admin/products/%d", creds.Url, productResp.Id),
+		Name:      productResp.Title,
+		Platform:  "shopify",
+		PublishAt: publishAt,
 	}
 
 	// 序列化产品数据
@@ -388,6 +462,41 @@ func (p *Shopify) PutProduct(credential *types.ShopCredential, product *types.Pr
 	}, nil
 }
 
+// applyCollectionsAndChannels 将新商品加入集合并发布到指定销售渠道
+func (p *Shopify) applyCollectionsAndChannels(ctx context.Context, client *shopify.Client, productID uint64, product *types.ProductData, remote *ShopifyRemoteData) {
+	collectionIDs := append([]string{}, product.CollectionIDs...)
+	for _, id := range product.CollectionIDs {
+		remote.Collections = append(remote.Collections, types.Collection{ID: id})
+	}
+	if len(product.CollectionTitles) > 0 {
+		resolved, err := resolveCollections(ctx, client, product.CollectionTitles)
+		if err != nil {
+			remote.Errors = append(remote.Errors, err.Error())
+		}
+		for _, c := range resolved {
+			collectionIDs = append(collectionIDs, c.ID)
+			remote.Collections = append(remote.Collections, c)
+		}
+	}
+	if len(collectionIDs) > 0 {
+		if err := addProductToCollections(ctx, client, productID, collectionIDs); err != nil {
+			remote.Errors = append(remote.Errors, err.Error())
+		}
+	}
+
+	if len(product.SalesChannels) > 0 {
+		channels, err := applySalesChannels(ctx, client, productID, product.SalesChannels)
+		if err != nil {
+			remote.Errors = append(remote.Errors, err.Error())
+		}
+		remote.SalesChannels = channels
+	}
+
+	for _, e := range remote.Errors {
+		fmt.Printf("Shopify product %d: %s\n", productID, e)
+	}
+}
+
 func (p *Shopify) variantUniqId(v *shopify.Variant) string {
 	return strings.Join([]string{
 		v.Option1,
@@ -401,9 +510,16 @@ func convertDecimal(v int64) *decimal.Decimal {
 	return &v2
 }
 
-func (p *Shopify) toShopifyProduct(product *types.ProductData) (shopify.Product, error) {
-	// 创建发布时间
-	publishedAt := time.Now()
+func (p *Shopify) toShopifyProduct(product *types.ProductData, prepared []*utils.PreparedImage) (shopify.Product, error) {
+	// 草稿和定时发布的商品先以草稿创建，定时任务到期后上架
+	status := shopify.ProductStatusActive
+	var publishedAt *time.Time
+	if product.PublishMode == types.PublishModeDraft || product.PublishMode == types.PublishModeScheduled {
+		status = shopify.ProductStatusDraft
+	} else {
+		now := time.Now()
+		publishedAt = &now
+	}
 
 	options := []shopify.ProductOption{}
 	for _, opt := range product.Options {
@@ -425,6 +541,7 @@ func (p *Shopify) toShopifyProduct(product *types.ProductData) (shopify.Product,
 			CompareAtPrice:  v.CompareAtPrice,
 			Weight:          v.Weight,
 			WeightUnit:      v.WeightUnit,
+			Barcode:         v.Barcode,
 			Metafields: []shopify.Metafield{
 				{
 					Namespace: "aira-shop",
@@ -434,6 +551,11 @@ func (p *Shopify) toShopifyProduct(product *types.ProductData) (shopify.Product,
 				},
 			},
 		}
+		variantMetafields, err := toShopifyMetafields(v.Metafields)
+		if err != nil {
+			return shopify.Product{}, fmt.Errorf("variant %d: %w", v.ID, err)
+		}
+		variant.Metafields = append(variant.Metafields, variantMetafields...)
 
 		// 设置选项
 		if v.Option1 != "" {
@@ -449,40 +571,43 @@ func (p *Shopify) toShopifyProduct(product *types.ProductData) (shopify.Product,
 		variants = append(variants, variant)
 	}
 
-	// 转换图片信息
+	// 图片已经过校验和去重，附件方式时不再传URL
 	var images []shopify.Image
-	if product.Image.Src != "" {
-		images = append(images, shopify.Image{
-			Width:    product.Image.Width,
-			Height:   product.Image.Height,
-			Src:      product.Image.Src,
-			Alt:      product.Image.Alt,
-			Filename: product.Image.Filename,
-		})
-	}
-	for _, img := range product.Images {
-		if img.Src == "" {
-			continue
+	for _, img := range prepared {
+		image := shopify.Image{
+			Width:    img.Image.Width,
+			Height:   img.Image.Height,
+			Alt:      img.Image.Alt,
+			Filename: img.Image.Filename,
 		}
-		images = append(images, shopify.Image{
-			Width:    img.Width,
-			Height:   img.Height,
-			Src:      img.Src,
-			Alt:      img.Alt,
-			Filename: img.Filename,
-		})
+		if img.Attachment != "" {
+			image.Attachment = img.Attachment
+		} else {
+			image.Src = img.Image.Src
+		}
+		images = append(images, image)
+	}
+
+	metafields, err := toShopifyMetafields(product.Metafields)
+	if err != nil {
+		return shopify.Product{}, err
 	}
 
 	shopifyProduct := shopify.Product{
-		Title:          product.ProductName,
-		BodyHTML:       product.BodyHTML,
-		Status:         shopify.ProductStatusActive,
-		PublishedAt:    &publishedAt,
-		PublishedScope: "web",
-		Options:        options,
-		Tags:           product.Tags,
-		Variants:       variants,
-		Images:         images,
+		Title:                          product.ProductName,
+		BodyHTML:                       product.BodyHTML,
+		Vendor:                         product.Vendor,
+		ProductType:                    product.ProductType,
+		Status:                         status,
+		PublishedAt:                    publishedAt,
+		PublishedScope:                 "web",
+		Options:                        options,
+		Tags:                           product.Tags,
+		Variants:                       variants,
+		Images:                         images,
+		MetafieldsGlobalTitleTag:       product.SEOTitle,
+		MetafieldsGlobalDescriptionTag: product.SEODescription,
+		Metafields:                     metafields,
 	}
 
 	return shopifyProduct, nil
@@ -615,6 +740,10 @@ func (p *Shopify) StartEventListener() {
 				} else {
 					fmt.Println("Successfully handled orders/fulfilled event")
 				}
+			case "products/update":
+				if err := p.handleProductUpdate(payload); err != nil {
+					fmt.Printf("Error handling products/update event: %v\n", err)
+				}
 			default:
 				fmt.Printf("Unknown webhook topic: %s, skipping\n", topic)
 			}
@@ -0,0 +1,46 @@
+package shopify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	shopify "github.com/bold-commerce/go-shopify/v4"
+	"github.com/flaboy/aira-shop/pkg/errors"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
+	"github.com/flaboy/pin/usererrors"
+	"github.com/spf13/cast"
+)
+
+// SetProductStatus 上架、下架为草稿或归档商品
+func (p *Shopify) SetProductStatus(credential *types.ShopCredential, productID string, status string) error {
+	client, err := p.clientForCredential(credential)
+	if err != nil {
+		return err
+	}
+
+	update := shopify.Product{Id: cast.ToUint64(productID)}
+	if update.Id == 0 {
+		return usererrors.New("Invalid product ID")
+	}
+	switch status {
+	case models.ShopProductStatusActive:
+		now := time.Now()
+		update.Status = shopify.ProductStatusActive
+		update.PublishedAt = &now
+	case models.ShopProductStatusDraft:
+		update.Status = shopify.ProductStatusDraft
+	case models.ShopProductStatusArchived:
+		update.Status = shopify.ProductStatusArchived
+	default:
+		return errors.ErrInvalidPublishMode
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	if _, err := client.Product.Update(ctx, update); err != nil {
+		return fmt.Errorf("failed to set product %s status to %s: %w", productID, status, err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package tiktokshop
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flaboy/aira-shop/pkg/config"
+)
+
+// apiError TikTok Shop接口返回的业务错误
+type apiError struct {
+	Code      int
+	Message   string
+	RequestID string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("tiktok shop api error %d: %s (request_id: %s)", e.Code, e.Message, e.RequestID)
+}
+
+// 访问令牌无效或过期
+func isAuthError(err error) bool {
+	if e, ok := err.(*apiError); ok {
+		return e.Code == 105001 || e.Code == 105002 || e.Code == 105005
+	}
+	return false
+}
+
+// sign 按TikTok Shop签名规则计算请求签名
+// 参数按key排序拼接（排除sign和access_token），前加path，非multipart时后加body，再以app_secret包裹做HMAC-SHA256
+func sign(path string, query url.Values, body []byte, secret string) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		if k == "sign" || k == "access_token" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(secret)
+	b.WriteString(path)
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteString(query.Get(k))
+	}
+	b.Write(body)
+	b.WriteString(secret)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(b.String()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// call 发送签名的API请求，body为nil时不发送请求体
+func (p *TikTokShop) call(ctx context.Context, method, path string, query url.Values, body interface{}, cred *TikTokShopCredential, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := p.newSignedRequest(ctx, method, path, query, payload, "application/json", cred, true)
+	if err != nil {
+		return err
+	}
+	return p.do(req, out)
+}
+
+// upload 以multipart方式上传文件，multipart请求体不参与签名
+func (p *TikTokShop) upload(ctx context.Context, path string, fields map[string]string, fileField, filename string, data []byte, cred *TikTokShopCredential, out interface{}) error {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		if err := writer.WriteField(k, v); err != nil {
+			return err
+		}
+	}
+	part, err := writer.CreateFormFile(fileField, filename)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(data); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := p.newSignedRequest(ctx, http.MethodPost, path, nil, buf.Bytes(), writer.FormDataContentType(), cred, false)
+	if err != nil {
+		return err
+	}
+	return p.do(req, out)
+}
+
+func (p *TikTokShop) newSignedRequest(ctx context.Context, method, path string, query url.Values, payload []byte, contentType string, cred *TikTokShopCredential, signBody bool) (*http.Request, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("app_key", config.Config.TikTokShop.AppKey)
+	query.Set("timestamp", strconv.FormatInt(time.Now().Unix(), 10))
+
+	signedBody := payload
+	if !signBody {
+		signedBody = nil
+	}
+	query.Set("sign", sign(path, query, signedBody, config.Config.TikTokShop.AppSecret))
+
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(config.Config.TikTokShop.ApiBaseUrl, "/")+path+"?"+query.Encode(), reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if cred != nil {
+		req.Header.Set("x-tts-access-token", cred.AccessToken)
+	}
+	return req, nil
+}
+
+func (p *TikTokShop) do(req *http.Request, out interface{}) error {
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var apiResp apiResponse
+	if err := json.Unmarshal(respBody, &apiResp); err != nil {
+		return fmt.Errorf("failed to decode tiktok shop response (status %d): %w", resp.StatusCode, err)
+	}
+	if apiResp.Code != 0 {
+		return &apiError{Code: apiResp.Code, Message: apiResp.Message, RequestID: apiResp.RequestID}
+	}
+
+	if out != nil && len(apiResp.Data) > 0 {
+		return json.Unmarshal(apiResp.Data, out)
+	}
+	return nil
+}
+
+// shopQuery 店铺级接口需要携带shop_cipher
+func shopQuery(cred *TikTokShopCredential) url.Values {
+	return url.Values{"shop_cipher": {cred.ShopCipher}}
+}
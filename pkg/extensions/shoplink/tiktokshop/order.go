@@ -0,0 +1,261 @@
+package tiktokshop
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/flaboy/aira-shop/pkg/errors"
+	"github.com/flaboy/aira-shop/pkg/events"
+	"github.com/flaboy/aira-shop/pkg/types"
+	"github.com/flaboy/pin"
+)
+
+// webhook事件类型
+const webhookTypeOrderStatusChange = 1
+
+// handleWebhook 校验签名后处理TikTok Shop推送，响应由调用方按返回值输出
+// 处理失败时返回错误，TikTok Shop收到非200响应后会重新推送
+func (p *TikTokShop) handleWebhook(c *pin.Context) (*types.HandleRequestResult, error) {
+	body, err := c.GetRawData()
+	if err != nil {
+		return nil, errors.ErrInvalidWebhookPayload
+	}
+
+	if !verifyWebhook(body, c.GetHeader("Authorization")) {
+		return nil, errors.ErrInvalidWebhookSignature
+	}
+
+	var event webhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, errors.ErrInvalidWebhookPayload
+	}
+
+	if event.Type == webhookTypeOrderStatusChange {
+		if err := p.handleOrderStatusChange(&event); err != nil {
+			fmt.Printf("Error handling TikTok Shop order status change: %v\n", err)
+			return nil, err
+		}
+	}
+
+	return &types.HandleRequestResult{
+		Status: types.HandleRequestStatusWebhookAccepted,
+	}, nil
+}
+
+// verifyWebhook 签名为 HMAC-SHA256(app_secret, app_key + body)
+func verifyWebhook(body []byte, signature string) bool {
+	if signature == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(config.Config.TikTokShop.AppSecret))
+	mac.Write([]byte(config.Config.TikTokShop.AppKey))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
+
+// handleOrderStatusChange 订单进入待发货状态（已支付）时通知业务系统
+func (p *TikTokShop) handleOrderStatusChange(event *webhookEvent) error {
+	var data orderStatusChangeData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		return err
+	}
+	fmt.Printf("TikTok Shop order %s status changed to %s (shop %s)\n", data.OrderID, data.OrderStatus, event.ShopID)
+
+	if data.OrderStatus != "AWAITING_SHIPMENT" {
+		return nil
+	}
+
+	shop, err := p.store.FindShopByDomain(platformName, event.ShopID)
+	if err != nil {
+		return err
+	}
+	credential, err := decodeShopLinkCredential(shop.Credentials)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	if err := p.ensureToken(ctx, credential); err != nil {
+		return err
+	}
+
+	query := shopQuery(credential)
+	query.Set("ids", data.OrderID)
+	var orders ordersData
+	if err := p.call(ctx, http.MethodGet, "/order/202309/orders", query, nil, credential, &orders); err != nil {
+		return err
+	}
+	if len(orders.Orders) == 0 {
+		return fmt.Errorf("order %s not found", data.OrderID)
+	}
+
+	orderData, err := p.convertOrder(&orders.Orders[0])
+	if err != nil {
+		return err
+	}
+
+	return events.EmitOrderReceived(&types.OrderReceivedEvent{
+		Platform:  platformName,
+		OrderData: *orderData,
+		ShopID:    shop.ID,
+		CreatedAt: time.Now(),
+	})
+}
+
+func decodeShopLinkCredential(data json.RawMessage) (*TikTokShopCredential, error) {
+	var cred TikTokShopCredential
+	if err := json.Unmarshal(data, &cred); err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+// convertOrder 将TikTok订单转换为统一订单结构，只保留本系统发布的商品
+func (p *TikTokShop) convertOrder(o *order) (*types.OrderData, error) {
+	createdAt := time.Unix(o.CreateTime, 0)
+	updatedAt := time.Unix(o.UpdateTime, 0)
+
+	orderData := &types.OrderData{
+		ID:                o.ID,
+		Name:              o.ID,
+		Email:             o.BuyerEmail,
+		Phone:             o.RecipientAddress.PhoneNumber,
+		FinancialStatus:   convertFinancialStatus(o.Status),
+		FulfillmentStatus: convertFulfillmentStatus(o.Status),
+		CreatedAt:         &createdAt,
+		UpdatedAt:         &updatedAt,
+		TotalPrice:        parseDecimal(o.Payment.TotalAmount),
+		SubtotalPrice:     parseDecimal(o.Payment.SubTotal),
+		TotalShipping:     parseDecimal(o.Payment.ShippingFee),
+		TotalTax:          parseDecimal(o.Payment.Tax),
+		Currency:          o.Payment.Currency,
+		Customer: &types.OrderCustomer{
+			ID:        o.UserID,
+			Email:     o.BuyerEmail,
+			FirstName: o.RecipientAddress.FirstName,
+			LastName:  o.RecipientAddress.LastName,
+			Phone:     o.RecipientAddress.PhoneNumber,
+		},
+		ShippingAddress: convertAddress(&o.RecipientAddress),
+		RawData: map[string]interface{}{
+			"source_name": platformName,
+			"order":       o,
+		},
+	}
+
+	// TikTok每个行项目数量为1，同一SKU合并计数
+	itemIndex := make(map[string]int)
+	for _, item := range o.LineItems {
+		product, ok, err := p.store.GetShopProduct(platformName, item.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			fmt.Printf("Shop product not found for TikTok product %s, skipping line item %s\n", item.ProductID, item.ID)
+			continue
+		}
+
+		rm := TikTokShopRemoteData{}
+		if err := json.Unmarshal(product.RemoteData, &rm); err != nil {
+			return nil, err
+		}
+		variantID, ok := rm.VariantMapper[item.SkuID]
+		if !ok {
+			fmt.Printf("TikTok SKU %s not found in variant mapper, skipping line item\n", item.SkuID)
+			continue
+		}
+
+		if idx, ok := itemIndex[item.SkuID]; ok {
+			orderData.LineItems[idx].Quantity++
+			continue
+		}
+
+		itemIndex[item.SkuID] = len(orderData.LineItems)
+		orderData.LineItems = append(orderData.LineItems, types.OrderLineItem{
+			ID:           item.ID,
+			ProductID:    item.ProductID,
+			VariantID:    variantID,
+			Title:        item.ProductName,
+			SKU:          item.SellerSku,
+			Quantity:     1,
+			Price:        parseDecimal(item.SalePrice),
+			VariantTitle: item.SkuName,
+			RawData:      map[string]interface{}{"line_item": item},
+		})
+	}
+
+	if o.ShippingProvider != "" || o.DeliveryOption != "" {
+		orderData.ShippingLines = append(orderData.ShippingLines, types.OrderShippingLine{
+			Code:    o.ShippingType,
+			Title:   o.DeliveryOption,
+			Price:   parseDecimal(o.Payment.ShippingFee),
+			Source:  platformName,
+			Carrier: o.ShippingProvider,
+		})
+	}
+
+	return orderData, nil
+}
+
+func convertAddress(addr *recipientAddress) *types.OrderAddress {
+	result := &types.OrderAddress{
+		FirstName:   addr.FirstName,
+		LastName:    addr.LastName,
+		Address1:    addr.AddressLine1,
+		Address2:    addr.AddressLine2,
+		CountryCode: addr.RegionCode,
+		Zip:         addr.PostalCode,
+		Phone:       addr.PhoneNumber,
+	}
+	if result.FirstName == "" && result.LastName == "" {
+		result.FirstName = addr.Name
+	}
+
+	// district_info 按层级给出国家/州/城市
+	for _, d := range addr.DistrictInfo {
+		switch d.AddressLevel {
+		case "L0":
+			result.Country = d.AddressName
+		case "L1":
+			result.Province = d.AddressName
+		case "L2", "L3":
+			if result.City == "" {
+				result.City = d.AddressName
+			}
+		}
+	}
+	return result
+}
+
+func convertFinancialStatus(status string) types.OrderFinancialStatus {
+	switch status {
+	case "UNPAID":
+		return types.OrderFinancialStatusPending
+	case "CANCELLED":
+		return types.OrderFinancialStatusVoided
+	default:
+		return types.OrderFinancialStatusPaid
+	}
+}
+
+func convertFulfillmentStatus(status string) types.OrderFulfillmentStatus {
+	switch status {
+	case "PARTIALLY_SHIPPING":
+		return types.OrderFulfillmentStatusPartial
+	case "AWAITING_COLLECTION", "IN_TRANSIT", "DELIVERED", "COMPLETED":
+		return types.OrderFulfillmentStatusFulfilled
+	default:
+		return types.OrderFulfillmentStatusUnfulfilled
+	}
+}
@@ -0,0 +1,180 @@
+package tiktokshop
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flaboy/aira-shop/pkg/errors"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
+	"github.com/flaboy/pin"
+	"github.com/gin-gonic/gin"
+)
+
+// webhookRequest 构造带签名的webhook请求，signature为空时按app_key + body计算
+func webhookRequest(t *testing.T, event webhookEvent, signature string) (*pin.Context, *httptest.ResponseRecorder) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signature == "" {
+		mac := hmac.New(sha256.New, []byte(testAppSecret))
+		mac.Write([]byte(testAppKey))
+		mac.Write(body)
+		signature = hex.EncodeToString(mac.Sum(nil))
+	}
+
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/stores/request/tiktokshop/webhook", bytes.NewReader(body))
+	c.Request.Header.Set("Authorization", signature)
+	return &pin.Context{Context: c}, recorder
+}
+
+func orderStatusEvent(orderID, status string) webhookEvent {
+	data, _ := json.Marshal(orderStatusChangeData{OrderID: orderID, OrderStatus: status, UpdateTime: 1700000100})
+	return webhookEvent{Type: webhookTypeOrderStatusChange, ShopID: "7001", Timestamp: 1700000100, Data: data}
+}
+
+// stubOrderShop 保存一个令牌未过期的店铺和一个已发布的商品
+func stubOrderShop(s *standIn) {
+	credentials, _ := json.Marshal(TikTokShopCredential{
+		ShopID:              "7001",
+		ShopCipher:          "cipher-us",
+		AccessToken:         testAccessToken,
+		AccessTokenExpireAt: 4102444800,
+	})
+	s.store.links["7001"] = &models.ShopLink{ID: 9, Platform: platformName, Domain: "7001", Credentials: credentials}
+
+	remoteData, _ := json.Marshal(TikTokShopRemoteData{VariantMapper: map[string]uint{"sku-white": 11}})
+	s.store.products["p-mug"] = &models.ShopProduct{ID: 3, ShopID: 9, OuterID: "p-mug", RemoteData: remoteData}
+}
+
+func TestWebhookOrderAwaitingShipment(t *testing.T) {
+	s := newStandIn(t)
+	stubOrderShop(s)
+	recorder := recordEvents(t)
+
+	s.handle(http.MethodGet, "/order/202309/orders", func(r *http.Request, _ []byte) (interface{}, int) {
+		if q := r.URL.Query(); q.Get("ids") != "576" || q.Get("shop_cipher") != "cipher-us" {
+			t.Errorf("unexpected order query: %s", r.URL.RawQuery)
+		}
+		return ordersData{Orders: []order{{
+			ID:         "576",
+			Status:     "AWAITING_SHIPMENT",
+			BuyerEmail: "buyer@example.com",
+			UserID:     "u-1",
+			CreateTime: 1700000000,
+			UpdateTime: 1700000100,
+			Payment: orderPayment{
+				Currency:    "USD",
+				TotalAmount: "43.80",
+				SubTotal:    "39.80",
+				ShippingFee: "4.00",
+				Tax:         "0",
+			},
+			RecipientAddress: recipientAddress{
+				Name:         "Jane Doe",
+				PhoneNumber:  "+1 555 0100",
+				AddressLine1: "1 Main St",
+				PostalCode:   "94105",
+				RegionCode:   "US",
+				DistrictInfo: []districtInfo{
+					{AddressLevel: "L0", AddressName: "United States"},
+					{AddressLevel: "L1", AddressName: "California"},
+					{AddressLevel: "L3", AddressName: "San Francisco"},
+				},
+			},
+			LineItems: []orderLineItem{
+				{ID: "li-1", ProductID: "p-mug", ProductName: "Ceramic Mug", SkuID: "sku-white", SkuName: "White", SellerSku: "MUG-W", SalePrice: "19.90"},
+				{ID: "li-2", ProductID: "p-mug", ProductName: "Ceramic Mug", SkuID: "sku-white", SkuName: "White", SellerSku: "MUG-W", SalePrice: "19.90"},
+				{ID: "li-3", ProductID: "p-other", ProductName: "Not ours", SkuID: "sku-x", SalePrice: "5.00"},
+			},
+			ShippingProvider: "USPS",
+			ShippingType:     "TIKTOK",
+			DeliveryOption:   "Standard shipping",
+		}}}, 0
+	})
+
+	c, resp := webhookRequest(t, orderStatusEvent("576", "AWAITING_SHIPMENT"), "")
+	result, err := s.platform().HandleRequest(c, "webhook")
+	if err != nil {
+		t.Fatalf("HandleRequest: %v", err)
+	}
+	if result.Status != types.HandleRequestStatusWebhookAccepted || resp.Body.Len() != 0 {
+		t.Fatalf("result = %+v, body = %s", result, resp.Body.String())
+	}
+
+	if len(recorder.ordersReceived) != 1 {
+		t.Fatalf("order events = %d, want 1", len(recorder.ordersReceived))
+	}
+	event := recorder.ordersReceived[0]
+	if event.Platform != platformName || event.ShopID != 9 {
+		t.Fatalf("unexpected event: %+v", event)
+	}
+
+	o := event.OrderData
+	if o.ID != "576" || o.Email != "buyer@example.com" || o.Currency != "USD" ||
+		o.FinancialStatus != types.OrderFinancialStatusPaid || o.FulfillmentStatus != types.OrderFulfillmentStatusUnfulfilled {
+		t.Fatalf("unexpected order: %+v", o)
+	}
+	if o.TotalPrice.String() != "43.8" || o.TotalShipping.String() != "4" || o.CreatedAt.Unix() != 1700000000 {
+		t.Fatalf("unexpected order amounts or times: %+v", o)
+	}
+
+	addr := o.ShippingAddress
+	if addr.FirstName != "Jane Doe" || addr.Country != "United States" || addr.Province != "California" ||
+		addr.City != "San Francisco" || addr.CountryCode != "US" || addr.Zip != "94105" {
+		t.Fatalf("unexpected shipping address: %+v", addr)
+	}
+
+	// 同一SKU的行项目合并数量，未由本系统发布的商品跳过
+	if len(o.LineItems) != 1 {
+		t.Fatalf("line items = %+v", o.LineItems)
+	}
+	item := o.LineItems[0]
+	if item.VariantID != 11 || item.Quantity != 2 || item.SKU != "MUG-W" || item.Price.String() != "19.9" {
+		t.Fatalf("unexpected line item: %+v", item)
+	}
+
+	if len(o.ShippingLines) != 1 || o.ShippingLines[0].Carrier != "USPS" || o.ShippingLines[0].Title != "Standard shipping" {
+		t.Fatalf("unexpected shipping lines: %+v", o.ShippingLines)
+	}
+}
+
+func TestWebhookIgnoresOtherOrderStatuses(t *testing.T) {
+	s := newStandIn(t)
+	stubOrderShop(s)
+	recorder := recordEvents(t)
+
+	c, _ := webhookRequest(t, orderStatusEvent("576", "UNPAID"), "")
+	result, err := s.platform().HandleRequest(c, "webhook")
+	if err != nil {
+		t.Fatalf("HandleRequest: %v", err)
+	}
+	if result.Status != types.HandleRequestStatusWebhookAccepted || len(recorder.ordersReceived) != 0 {
+		t.Fatalf("result = %+v, order events = %d", result, len(recorder.ordersReceived))
+	}
+}
+
+func TestWebhookRejectsInvalidSignature(t *testing.T) {
+	s := newStandIn(t)
+	stubOrderShop(s)
+	recorder := recordEvents(t)
+
+	c, _ := webhookRequest(t, orderStatusEvent("576", "AWAITING_SHIPMENT"), "deadbeef")
+	result, err := s.platform().HandleRequest(c, "webhook")
+	if err != errors.ErrInvalidWebhookSignature || result != nil {
+		t.Fatalf("result = %+v, err = %v", result, err)
+	}
+	if len(recorder.ordersReceived) != 0 {
+		t.Fatalf("order events = %d, want 0", len(recorder.ordersReceived))
+	}
+}
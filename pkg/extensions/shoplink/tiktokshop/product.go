@@ -0,0 +1,357 @@
+package tiktokshop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/flaboy/aira-shop/pkg/events"
+	"github.com/flaboy/aira-shop/pkg/extensions/shoplink/utils"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
+	"github.com/flaboy/pin/usererrors"
+	"github.com/shopspring/decimal"
+)
+
+// TikTok Shop最多9张主图
+const maxMainImages = 9
+
+type TikTokShopRemoteData struct {
+	CategoryID string
+	// TikTok SKU ID -> 内部变体ID
+	VariantMapper map[string]uint
+	Images        []types.ImageResult
+}
+
+func (p *TikTokShop) PutProduct(credential *types.ShopCredential, product *types.ProductData, businessContext json.RawMessage) (*types.PutProductResult, error) {
+	cred, err := decodeCredential(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	// 商品创建后即上架，不支持草稿和定时发布
+	if err := utils.RequireImmediatePublish(product); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	defer cancel()
+
+	if err := p.ensureToken(ctx, cred); err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to refresh access token: %s", err.Error()))
+	}
+
+	shop, err := p.store.FindShopByDomain(platformName, cred.ShopID)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to find shop: %s", err.Error()))
+	}
+
+	// 应用店铺覆盖规则，后续保存的是实际发布的数据
+	product, err = utils.ApplyShopOverrides(shop, product)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to apply shop overrides: %s", err.Error()))
+	}
+
+	prepared, imageResults := utils.PrepareImages(ctx, product)
+	request, err := p.toTikTokProduct(ctx, cred, product, prepared, imageResults)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to convert product: %s", err.Error()))
+	}
+
+	var created createProductData
+	if err := p.call(ctx, http.MethodPost, "/product/202309/products", shopQuery(cred), request, cred, &created); err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to create product: %s", err.Error()))
+	}
+
+	// 通过seller_sku将TikTok SKU映射回内部变体
+	skuToVariant := make(map[string]uint)
+	for i, sku := range request.Skus {
+		skuToVariant[sku.SellerSku] = product.Variants[i].ID
+	}
+	remote := &TikTokShopRemoteData{
+		CategoryID:    request.CategoryID,
+		VariantMapper: make(map[string]uint),
+		Images:        imageResults,
+	}
+	for _, sku := range created.Skus {
+		if variantID, ok := skuToVariant[sku.SellerSku]; ok {
+			remote.VariantMapper[sku.ID] = variantID
+		}
+	}
+
+	productUrl := fmt.Sprintf("https://seller.tiktokshop.com/product/edit/%s", created.ProductID)
+
+	shopProduct := models.ShopProduct{
+		ShopID:   shop.ID,
+		OuterID:  created.ProductID,
+		Status:   models.ShopProductStatusActive,
+		Url:      productUrl,
+		Name:     product.ProductName,
+		Platform: platformName,
+	}
+
+	productData, err := json.Marshal(product)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to marshal product data: %s", err.Error()))
+	}
+	shopProduct.Data = productData
+
+	remoteData, err := json.Marshal(remote)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to marshal remote data: %s", err.Error()))
+	}
+	shopProduct.RemoteData = remoteData
+
+	if err := database.Database().Create(&shopProduct).Error; err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to create shop product: %s", err.Error()))
+	}
+
+	events.EmitProductPublished(&types.ProductPublishedEvent{
+		ShopProductID: shopProduct.ID,
+		ShopID:        shop.ID,
+		Platform:      platformName,
+		OuterID:       created.ProductID,
+		ProductData: map[string]interface{}{
+			"product_name": product.ProductName,
+			"body_html":    product.BodyHTML,
+			"tags":         product.Tags,
+		},
+		BusinessContext: businessContext,
+		CreatedAt:       time.Now(),
+	})
+
+	return &types.PutProductResult{
+		CommandResult: types.CommandResult{
+			Success: true,
+			Message: "Product created successfully",
+		},
+		OuterID:    created.ProductID,
+		Url:        productUrl,
+		RemoteData: remote,
+	}, nil
+}
+
+func (p *TikTokShop) toTikTokProduct(ctx context.Context, cred *TikTokShopCredential, product *types.ProductData, prepared []*utils.PreparedImage, imageResults []types.ImageResult) (*createProductRequest, error) {
+	if len(product.Variants) == 0 {
+		return nil, fmt.Errorf("product has no variants")
+	}
+
+	// 类目未指定时使用平台推荐的叶子类目
+	categoryID := product.CategoryID
+	if categoryID == "" {
+		var recommended recommendCategoryData
+		err := p.call(ctx, http.MethodPost, "/product/202309/categories/recommend", shopQuery(cred), map[string]string{
+			"product_title": product.ProductName,
+			"description":   product.BodyHTML,
+		}, cred, &recommended)
+		if err != nil {
+			return nil, fmt.Errorf("failed to recommend category: %w", err)
+		}
+		categoryID = recommended.LeafCategoryID
+	}
+
+	attributes, err := p.categoryAttributes(ctx, cred, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	productAttributes, err := buildProductAttributes(attributes, product.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	warehouseID, err := p.defaultWarehouse(ctx, cred)
+	if err != nil {
+		return nil, err
+	}
+
+	mainImages, err := p.uploadProductImages(ctx, cred, prepared, imageResults)
+	if err != nil {
+		return nil, err
+	}
+	if len(mainImages) == 0 {
+		return nil, fmt.Errorf("product has no images")
+	}
+
+	request := &createProductRequest{
+		Title:             product.ProductName,
+		Description:       product.BodyHTML,
+		CategoryID:        categoryID,
+		MainImages:        mainImages,
+		ProductAttributes: productAttributes,
+	}
+
+	var maxWeight *decimal.Decimal
+	for _, v := range product.Variants {
+		sku := productSku{
+			SellerSku: v.Sku,
+			Inventory: []skuInventory{{
+				WarehouseID: warehouseID,
+				Quantity:    inventoryQuantity(v.InventoryQuantity),
+			}},
+		}
+		if sku.SellerSku == "" {
+			sku.SellerSku = fmt.Sprintf("aira-%d", v.ID)
+		}
+		if v.Price != nil {
+			sku.Price = skuPrice{Amount: v.Price.StringFixed(2), Currency: cred.Currency}
+		}
+
+		for i, value := range []string{v.Option1, v.Option2, v.Option3} {
+			if value == "" || i >= len(product.Options) {
+				continue
+			}
+			sku.SalesAttributes = append(sku.SalesAttributes, salesAttribute{
+				Name:      product.Options[i].Name,
+				ValueName: value,
+			})
+		}
+		request.Skus = append(request.Skus, sku)
+
+		// 包裹重量取最重的变体
+		if v.Weight != nil {
+			weight := toKilogram(*v.Weight, v.WeightUnit)
+			if maxWeight == nil || weight.GreaterThan(*maxWeight) {
+				maxWeight = &weight
+			}
+		}
+	}
+
+	if maxWeight != nil {
+		request.PackageWeight = &packageWeight{
+			Value: maxWeight.StringFixed(3),
+			Unit:  "KILOGRAM",
+		}
+	}
+
+	return request, nil
+}
+
+// buildProductAttributes 按名称匹配类目属性，缺少必填属性时返回错误
+func buildProductAttributes(categoryAttributes []categoryAttribute, attributes []types.ProductAttribute) ([]productAttribute, error) {
+	provided := make(map[string][]string)
+	for _, attr := range attributes {
+		provided[strings.ToLower(attr.Name)] = attr.Values
+	}
+
+	var result []productAttribute
+	var missing []string
+	for _, attr := range categoryAttributes {
+		if attr.Type != "PRODUCT_PROPERTY" {
+			continue
+		}
+		values, ok := provided[strings.ToLower(attr.Name)]
+		if !ok || len(values) == 0 {
+			if attr.IsRequired {
+				missing = append(missing, attr.Name)
+			}
+			continue
+		}
+
+		item := productAttribute{ID: attr.ID}
+		for _, value := range values {
+			matched := false
+			for _, candidate := range attr.Values {
+				if strings.EqualFold(candidate.Name, value) {
+					item.Values = append(item.Values, productAttributeValue{ID: candidate.ID})
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				if !attr.IsCustomizable && len(attr.Values) > 0 {
+					return nil, fmt.Errorf("invalid value %q for attribute %s", value, attr.Name)
+				}
+				item.Values = append(item.Values, productAttributeValue{Name: value})
+			}
+		}
+		result = append(result, item)
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required attributes: %s", strings.Join(missing, ", "))
+	}
+	return result, nil
+}
+
+func (p *TikTokShop) categoryAttributes(ctx context.Context, cred *TikTokShopCredential, categoryID string) ([]categoryAttribute, error) {
+	var data categoryAttributesData
+	if err := p.call(ctx, http.MethodGet, "/product/202309/categories/"+categoryID+"/attributes", shopQuery(cred), nil, cred, &data); err != nil {
+		return nil, fmt.Errorf("failed to get category attributes: %w", err)
+	}
+	return data.Attributes, nil
+}
+
+func (p *TikTokShop) defaultWarehouse(ctx context.Context, cred *TikTokShopCredential) (string, error) {
+	var data warehousesData
+	if err := p.call(ctx, http.MethodGet, "/logistics/202309/warehouses", shopQuery(cred), nil, cred, &data); err != nil {
+		return "", fmt.Errorf("failed to get warehouses: %w", err)
+	}
+
+	var fallback string
+	for _, w := range data.Warehouses {
+		if w.Type != "SALES_WAREHOUSE" {
+			continue
+		}
+		if w.IsDefault {
+			return w.ID, nil
+		}
+		if fallback == "" {
+			fallback = w.ID
+		}
+	}
+	if fallback == "" {
+		return "", fmt.Errorf("no sales warehouse found")
+	}
+	return fallback, nil
+}
+
+// uploadProductImages TikTok不接受外部图片URL，使用已校验的图片内容上传获取uri
+func (p *TikTokShop) uploadProductImages(ctx context.Context, cred *TikTokShopCredential, prepared []*utils.PreparedImage, results []types.ImageResult) ([]imageRef, error) {
+	var refs []imageRef
+	for _, img := range prepared {
+		if len(refs) >= maxMainImages {
+			break
+		}
+
+		filename := img.Image.Filename
+		if filename == "" {
+			filename = path.Base(img.Image.Src)
+		}
+
+		var uploaded uploadImageData
+		err := p.upload(ctx, "/product/202309/images/upload", map[string]string{"use_case": "MAIN_IMAGE"}, "data", filename, img.Data, cred, &uploaded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload image %s: %w", img.Image.Src, err)
+		}
+		results[img.ResultIndex].RemoteID = uploaded.Uri
+		refs = append(refs, imageRef{Uri: uploaded.Uri})
+	}
+	return refs, nil
+}
+
+func inventoryQuantity(quantity int) int {
+	if quantity > 0 {
+		return quantity
+	}
+	return config.Config.TikTokShop.DefaultInventory
+}
+
+// toKilogram 将Shopify风格的重量单位(g, kg, lb, oz)换算为千克
+func toKilogram(weight decimal.Decimal, unit string) decimal.Decimal {
+	switch strings.ToLower(unit) {
+	case "g":
+		return weight.Div(decimal.NewFromInt(1000))
+	case "lb":
+		return weight.Mul(decimal.RequireFromString("0.45359237"))
+	case "oz":
+		return weight.Mul(decimal.RequireFromString("0.028349523125"))
+	default:
+		return weight
+	}
+}
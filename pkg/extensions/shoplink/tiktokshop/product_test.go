@@ -0,0 +1,180 @@
+package tiktokshop
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/flaboy/aira-shop/pkg/extensions/shoplink/utils"
+	"github.com/flaboy/aira-shop/pkg/types"
+	"github.com/shopspring/decimal"
+)
+
+func decimalPtr(v string) *decimal.Decimal {
+	d := decimal.RequireFromString(v)
+	return &d
+}
+
+// handleProductCatalog 注册发布商品时查询的类目、属性、仓库和图片上传接口
+func handleProductCatalog(t *testing.T, s *standIn) {
+	s.handle(http.MethodPost, "/product/202309/categories/recommend", func(_ *http.Request, body []byte) (interface{}, int) {
+		var req map[string]string
+		json.Unmarshal(body, &req)
+		if req["product_title"] != "Ceramic Mug" {
+			t.Errorf("recommend product_title = %q", req["product_title"])
+		}
+		return recommendCategoryData{LeafCategoryID: "600001"}, 0
+	})
+	s.handle(http.MethodGet, "/product/202309/categories/600001/attributes", func(*http.Request, []byte) (interface{}, int) {
+		return categoryAttributesData{Attributes: []categoryAttribute{
+			{ID: "a-brand", Name: "Brand", Type: "PRODUCT_PROPERTY", IsRequired: true,
+				Values: []categoryAttributeValue{{ID: "v-aira", Name: "Aira"}}},
+			{ID: "a-material", Name: "Material", Type: "PRODUCT_PROPERTY", IsCustomizable: true},
+			{ID: "a-origin", Name: "Origin", Type: "PRODUCT_PROPERTY"},
+			{ID: "a-color", Name: "Color", Type: "SALES_PROPERTY", IsRequired: true},
+		}}, 0
+	})
+	s.handle(http.MethodGet, "/logistics/202309/warehouses", func(*http.Request, []byte) (interface{}, int) {
+		return warehousesData{Warehouses: []warehouse{
+			{ID: "w-return", Type: "RETURN_WAREHOUSE", IsDefault: true},
+			{ID: "w-sales", Type: "SALES_WAREHOUSE"},
+			{ID: "w-default", Type: "SALES_WAREHOUSE", IsDefault: true},
+		}}, 0
+	})
+	s.handle(http.MethodPost, "/product/202309/images/upload", func(r *http.Request, body []byte) (interface{}, int) {
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			t.Errorf("upload content type: %v", err)
+			return nil, 1
+		}
+		form, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(1 << 20)
+		if err != nil {
+			t.Errorf("upload form: %v", err)
+			return nil, 1
+		}
+		if form.Value["use_case"][0] != "MAIN_IMAGE" || len(form.File["data"]) != 1 {
+			t.Errorf("unexpected upload form: %v", form.Value)
+		}
+		return uploadImageData{Uri: "tos/" + form.File["data"][0].Filename}, 0
+	})
+}
+
+func TestToTikTokProduct(t *testing.T) {
+	s := newStandIn(t)
+	handleProductCatalog(t, s)
+	p := s.platform()
+
+	product := &types.ProductData{
+		ProductName: "Ceramic Mug",
+		BodyHTML:    "<p>Handmade</p>",
+		Attributes: []types.ProductAttribute{
+			{Name: "brand", Values: []string{"AIRA"}},
+			{Name: "Material", Values: []string{"Ceramic"}},
+		},
+		Options: []types.ProductOption{{Name: "Color"}, {Name: "Size"}},
+		Variants: []types.ProductVariant{
+			{ID: 11, Sku: "MUG-W-S", Price: decimalPtr("19.9"), Weight: decimalPtr("300"), WeightUnit: "g",
+				Option1: "White", Option2: "S", InventoryQuantity: 5},
+			{ID: 12, Price: decimalPtr("24"), Weight: decimalPtr("1"), WeightUnit: "lb",
+				Option1: "Black"},
+		},
+	}
+	imageResults := []types.ImageResult{{Src: "https://cdn.example.com/a.jpg"}, {Src: "https://cdn.example.com/b.jpg"}}
+	prepared := []*utils.PreparedImage{
+		{Image: types.ProductImage{Src: "https://cdn.example.com/a.jpg"}, Data: []byte("a"), ResultIndex: 0},
+		{Image: types.ProductImage{Src: "https://cdn.example.com/b.jpg", Filename: "back.jpg"}, Data: []byte("b"), ResultIndex: 1},
+	}
+	cred := &TikTokShopCredential{ShopCipher: "cipher-us", Currency: "USD", AccessToken: testAccessToken}
+
+	request, err := p.toTikTokProduct(t.Context(), cred, product, prepared, imageResults)
+	if err != nil {
+		t.Fatalf("toTikTokProduct: %v", err)
+	}
+
+	if request.Title != "Ceramic Mug" || request.Description != "<p>Handmade</p>" || request.CategoryID != "600001" {
+		t.Fatalf("unexpected product fields: %+v", request)
+	}
+
+	// 品牌匹配到类目预设值，材质为自定义值，未提供的可选属性和销售属性不提交
+	attrs, _ := json.Marshal(request.ProductAttributes)
+	wantAttrs := `[{"id":"a-brand","values":[{"id":"v-aira"}]},{"id":"a-material","values":[{"name":"Ceramic"}]}]`
+	if string(attrs) != wantAttrs {
+		t.Fatalf("product attributes = %s, want %s", attrs, wantAttrs)
+	}
+
+	if len(request.MainImages) != 2 || request.MainImages[0].Uri != "tos/a.jpg" || request.MainImages[1].Uri != "tos/back.jpg" {
+		t.Fatalf("main images = %+v", request.MainImages)
+	}
+	if imageResults[0].RemoteID != "tos/a.jpg" || imageResults[1].RemoteID != "tos/back.jpg" {
+		t.Fatalf("image results should record uploaded uris: %+v", imageResults)
+	}
+
+	if len(request.Skus) != 2 {
+		t.Fatalf("skus = %+v", request.Skus)
+	}
+	first, second := request.Skus[0], request.Skus[1]
+	if first.SellerSku != "MUG-W-S" || first.Price != (skuPrice{Amount: "19.90", Currency: "USD"}) {
+		t.Fatalf("first sku = %+v", first)
+	}
+	if first.Inventory[0] != (skuInventory{WarehouseID: "w-default", Quantity: 5}) {
+		t.Fatalf("first sku inventory = %+v", first.Inventory)
+	}
+	if len(first.SalesAttributes) != 2 || first.SalesAttributes[0] != (salesAttribute{Name: "Color", ValueName: "White"}) ||
+		first.SalesAttributes[1] != (salesAttribute{Name: "Size", ValueName: "S"}) {
+		t.Fatalf("first sku sales attributes = %+v", first.SalesAttributes)
+	}
+	if second.SellerSku != "aira-12" || second.Inventory[0].Quantity != 100 || len(second.SalesAttributes) != 1 {
+		t.Fatalf("second sku = %+v", second)
+	}
+
+	// 包裹重量取最重的变体：1lb > 300g
+	if request.PackageWeight == nil || *request.PackageWeight != (packageWeight{Value: "0.454", Unit: "KILOGRAM"}) {
+		t.Fatalf("package weight = %+v", request.PackageWeight)
+	}
+}
+
+func TestToTikTokProductUsesGivenCategory(t *testing.T) {
+	s := newStandIn(t)
+	handleProductCatalog(t, s)
+
+	product := &types.ProductData{
+		ProductName: "Ceramic Mug",
+		CategoryID:  "600001",
+		Attributes:  []types.ProductAttribute{{Name: "Brand", Values: []string{"Aira"}}},
+		Variants:    []types.ProductVariant{{ID: 11, Sku: "MUG"}},
+	}
+	prepared := []*utils.PreparedImage{{Image: types.ProductImage{Src: "https://cdn.example.com/a.jpg"}, Data: []byte("a")}}
+	cred := &TikTokShopCredential{AccessToken: testAccessToken}
+
+	if _, err := s.platform().toTikTokProduct(t.Context(), cred, product, prepared, make([]types.ImageResult, 1)); err != nil {
+		t.Fatalf("toTikTokProduct: %v", err)
+	}
+	if n := len(s.requestsTo(http.MethodPost, "/product/202309/categories/recommend")); n != 0 {
+		t.Fatalf("category recommended %d times, want 0", n)
+	}
+}
+
+func TestBuildProductAttributesErrors(t *testing.T) {
+	categoryAttributes := []categoryAttribute{
+		{ID: "a-brand", Name: "Brand", Type: "PRODUCT_PROPERTY", IsRequired: true,
+			Values: []categoryAttributeValue{{ID: "v-aira", Name: "Aira"}}},
+		{ID: "a-warranty", Name: "Warranty", Type: "PRODUCT_PROPERTY", IsRequired: true},
+	}
+
+	_, err := buildProductAttributes(categoryAttributes, []types.ProductAttribute{{Name: "Brand", Values: []string{"Aira"}}})
+	if err == nil || !strings.Contains(err.Error(), "Warranty") {
+		t.Fatalf("missing required attribute: err = %v", err)
+	}
+
+	_, err = buildProductAttributes(categoryAttributes, []types.ProductAttribute{
+		{Name: "Brand", Values: []string{"Other"}},
+		{Name: "Warranty", Values: []string{"1 year"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), `"Other"`) {
+		t.Fatalf("value outside preset list: err = %v", err)
+	}
+}
@@ -0,0 +1,324 @@
+package tiktokshop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/flaboy/aira-shop/pkg/errors"
+	"github.com/flaboy/aira-shop/pkg/events"
+	"github.com/flaboy/aira-shop/pkg/extensions/shoplink/utils"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
+	"github.com/flaboy/pin"
+	"github.com/flaboy/pin/usererrors"
+	"github.com/shopspring/decimal"
+)
+
+const platformName = "tiktokshop"
+
+// 访问令牌剩余有效期低于该值时刷新
+const tokenRefreshBefore = time.Hour
+
+type TikTokShop struct {
+	httpClient *http.Client
+	// 店铺和商品记录的读写，Init时使用数据库，测试中替换为内存实现
+	store shopStore
+}
+
+// shopStore 店铺和商品记录的读写
+type shopStore interface {
+	FindShopByDomain(platform, domain string) (*models.ShopLink, error)
+	SaveShopLink(shopLink *models.ShopLink) (*models.ShopLink, error)
+	GetShopProduct(platform, outerID string) (*models.ShopProduct, bool, error)
+}
+
+// dbStore 通过shoplink/utils读写数据库
+type dbStore struct{}
+
+func (dbStore) FindShopByDomain(platform, domain string) (*models.ShopLink, error) {
+	return utils.FindShopByDomain(platform, domain)
+}
+
+func (dbStore) SaveShopLink(shopLink *models.ShopLink) (*models.ShopLink, error) {
+	return utils.SaveShopLink(shopLink)
+}
+
+func (dbStore) GetShopProduct(platform, outerID string) (*models.ShopProduct, bool, error) {
+	return utils.GetShopProduct(platform, outerID)
+}
+
+type TikTokShopCredential struct {
+	ShopID               string
+	ShopCipher           string
+	ShopName             string
+	Region               string
+	Currency             string
+	OpenID               string
+	AccessToken          string
+	AccessTokenExpireAt  int64
+	RefreshToken         string
+	RefreshTokenExpireAt int64
+}
+
+func (p *TikTokShop) Init() error {
+	if !config.Config.TikTokShop.Enabled {
+		return nil
+	}
+
+	p.httpClient = &http.Client{
+		Timeout: 120 * time.Second,
+	}
+	p.store = dbStore{}
+	return nil
+}
+
+func (p *TikTokShop) GetPlatformName() string {
+	return platformName
+}
+
+// HandleRequest 生成卖家授权URL，或处理TikTok Shop推送的webhook
+func (p *TikTokShop) HandleRequest(c *pin.Context, path string) (*types.HandleRequestResult, error) {
+	if path == "webhook" {
+		return p.handleWebhook(c)
+	}
+
+	state, err := utils.GenerateNonce()
+	if err != nil {
+		return nil, errors.ErrNonceGeneration
+	}
+
+	query := url.Values{}
+	query.Set("service_id", config.Config.TikTokShop.ServiceID)
+	query.Set("state", state)
+
+	return &types.HandleRequestResult{
+		AuthURL: config.Config.TikTokShop.AuthorizeUrl + "?" + query.Encode(),
+	}, nil
+}
+
+func (p *TikTokShop) HandleCallback(c *pin.Context, businessContext json.RawMessage, callbackUrl *url.URL) (*types.CallbackResponse, error) {
+	ownerID, err := utils.ResolveOwner(businessContext)
+	if err != nil {
+		return nil, errors.ErrShopCreation
+	}
+
+	code := callbackUrl.Query().Get("code")
+	if code == "" {
+		return nil, errors.ErrInvalidCallbackSignature
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	token, err := p.requestToken(ctx, "/api/v2/token/get", url.Values{
+		"auth_code":  {code},
+		"grant_type": {"authorized_code"},
+	})
+	if err != nil {
+		fmt.Printf("TikTok Shop token exchange failed: %v\n", err)
+		return nil, errors.ErrAccessTokenFailed
+	}
+
+	cred := &TikTokShopCredential{OpenID: token.OpenID}
+	applyToken(cred, token)
+
+	var shops authorizedShopsData
+	if err := p.call(ctx, http.MethodGet, "/authorization/202309/shops", nil, nil, cred, &shops); err != nil {
+		fmt.Printf("TikTok Shop authorized shops query failed: %v\n", err)
+		return nil, errors.ErrShopInfoFailed
+	}
+	if len(shops.Shops) == 0 {
+		return nil, errors.ErrShopInfoFailed
+	}
+
+	// 一次授权可能包含多个地区店铺，每个店铺一条ShopLink
+	var first *models.ShopLink
+	for _, shop := range shops.Shops {
+		shopCred := *cred
+		shopCred.ShopID = shop.ID
+		shopCred.ShopCipher = shop.Cipher
+		shopCred.ShopName = shop.Name
+		shopCred.Region = shop.Region
+		shopCred.Currency = regionCurrency(shop.Region)
+
+		credentialsJson, err := json.Marshal(shopCred)
+		if err != nil {
+			return nil, errors.ErrCredentialsMarshal
+		}
+
+		shopLink, err := p.store.SaveShopLink(&models.ShopLink{
+			OwnerID:     ownerID,
+			Platform:    platformName,
+			Domain:      shop.ID,
+			Name:        shop.Name,
+			Credentials: credentialsJson,
+		})
+		if err == errors.ErrShopOwnedByOther {
+			return nil, err
+		} else if err != nil {
+			return nil, errors.ErrShopCreation
+		}
+		if first == nil {
+			first = shopLink
+		}
+
+		events.EmitShopConnected(&types.ShopConnectedEvent{
+			ShopID:   shopLink.ID,
+			Platform: platformName,
+			ShopData: map[string]interface{}{
+				"name":   shop.Name,
+				"region": shop.Region,
+			},
+			BusinessContext: businessContext,
+			CreatedAt:       time.Now(),
+		})
+	}
+
+	return &types.CallbackResponse{
+		Type: types.CallbackResponseTypeShopLinked,
+		ShopLinkedData: &types.ShopLinkedData{
+			ShopLink: first,
+		},
+	}, nil
+}
+
+// CheckHealth 验证访问令牌仍可查询授权店铺，webhook由开放平台统一配置
+func (p *TikTokShop) CheckHealth(credential *types.ShopCredential) (*types.HealthCheckResult, error) {
+	cred, err := decodeCredential(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	result := &types.HealthCheckResult{}
+	if err := p.ensureToken(ctx, cred); err != nil {
+		result.Message = fmt.Sprintf("failed to refresh access token: %v", err)
+		return result, nil
+	}
+
+	var shops authorizedShopsData
+	if err := p.call(ctx, http.MethodGet, "/authorization/202309/shops", nil, nil, cred, &shops); err != nil {
+		if isAuthError(err) {
+			result.Message = err.Error()
+			return result, nil
+		}
+		return nil, err
+	}
+
+	for _, shop := range shops.Shops {
+		if shop.ID == cred.ShopID {
+			result.CredentialsValid = true
+			result.Healthy = true
+			return result, nil
+		}
+	}
+	result.Message = "shop is no longer authorized"
+	return result, nil
+}
+
+func decodeCredential(credential *types.ShopCredential) (*TikTokShopCredential, error) {
+	var cred TikTokShopCredential
+	credData, err := json.Marshal(credential.Data)
+	if err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to marshal credentials: %s", err.Error()))
+	}
+	if err := json.Unmarshal(credData, &cred); err != nil {
+		return nil, usererrors.New(fmt.Sprintf("Failed to unmarshal credentials: %s", err.Error()))
+	}
+	return &cred, nil
+}
+
+// requestToken 调用授权服务获取或刷新令牌，该接口不需要签名
+func (p *TikTokShop) requestToken(ctx context.Context, path string, query url.Values) (*tokenData, error) {
+	query.Set("app_key", config.Config.TikTokShop.AppKey)
+	query.Set("app_secret", config.Config.TikTokShop.AppSecret)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, config.Config.TikTokShop.AuthBaseUrl+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var token tokenData
+	if err := p.do(req, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func applyToken(cred *TikTokShopCredential, token *tokenData) {
+	cred.AccessToken = token.AccessToken
+	cred.AccessTokenExpireAt = token.AccessTokenExpireIn
+	cred.RefreshToken = token.RefreshToken
+	cred.RefreshTokenExpireAt = token.RefreshTokenExpireIn
+}
+
+// ensureToken 访问令牌即将过期时刷新并保存到ShopLink
+func (p *TikTokShop) ensureToken(ctx context.Context, cred *TikTokShopCredential) error {
+	if time.Until(time.Unix(cred.AccessTokenExpireAt, 0)) > tokenRefreshBefore {
+		return nil
+	}
+
+	token, err := p.requestToken(ctx, "/api/v2/token/refresh", url.Values{
+		"refresh_token": {cred.RefreshToken},
+		"grant_type":    {"refresh_token"},
+	})
+	if err != nil {
+		return err
+	}
+	applyToken(cred, token)
+
+	shop, err := p.store.FindShopByDomain(platformName, cred.ShopID)
+	if err != nil {
+		return err
+	}
+	credentialsJson, err := json.Marshal(cred)
+	if err != nil {
+		return err
+	}
+	return database.Database().Model(shop).Update("credentials", credentialsJson).Error
+}
+
+// regionCurrency 店铺地区对应的结算币种
+func regionCurrency(region string) string {
+	switch region {
+	case "GB":
+		return "GBP"
+	case "ID":
+		return "IDR"
+	case "MY":
+		return "MYR"
+	case "PH":
+		return "PHP"
+	case "SG":
+		return "SGD"
+	case "TH":
+		return "THB"
+	case "VN":
+		return "VND"
+	case "MX":
+		return "MXN"
+	case "ES", "DE", "FR", "IT", "IE":
+		return "EUR"
+	default:
+		return "USD"
+	}
+}
+
+func parseDecimal(v string) *decimal.Decimal {
+	if v == "" {
+		return nil
+	}
+	d, err := decimal.NewFromString(v)
+	if err != nil {
+		return nil
+	}
+	return &d
+}
@@ -0,0 +1,348 @@
+package tiktokshop
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/flaboy/aira-shop/pkg/errors"
+	"github.com/flaboy/aira-shop/pkg/events"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
+)
+
+const (
+	testAppKey      = "test-app-key"
+	testAppSecret   = "test-app-secret"
+	testAccessToken = "test-access-token"
+)
+
+// standIn 代替TikTok Shop授权服务和开放接口，校验签名后返回各路径注册的数据
+type standIn struct {
+	*httptest.Server
+	t *testing.T
+
+	mu       sync.Mutex
+	routes   map[string]func(r *http.Request, body []byte) (interface{}, int)
+	requests map[string][]recordedRequest
+
+	store *memStore
+}
+
+type recordedRequest struct {
+	Query url.Values
+	Body  []byte
+}
+
+func newStandIn(t *testing.T) *standIn {
+	s := &standIn{
+		t:        t,
+		routes:   map[string]func(r *http.Request, body []byte) (interface{}, int){},
+		requests: map[string][]recordedRequest{},
+		store:    &memStore{links: map[string]*models.ShopLink{}, products: map[string]*models.ShopProduct{}},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+
+	prev := config.Config
+	t.Cleanup(func() { config.Config = prev })
+	config.Config = &config.CommenceConfig{}
+	config.Config.TikTokShop.Enabled = true
+	config.Config.TikTokShop.AppKey = testAppKey
+	config.Config.TikTokShop.AppSecret = testAppSecret
+	config.Config.TikTokShop.AuthBaseUrl = s.URL
+	config.Config.TikTokShop.ApiBaseUrl = s.URL + "/"
+	config.Config.TikTokShop.DefaultInventory = 100
+	return s
+}
+
+// handle 注册路径返回的data，返回值中的int为业务错误码，0表示成功
+func (s *standIn) handle(method, path string, fn func(r *http.Request, body []byte) (interface{}, int)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[method+" "+path] = fn
+}
+
+func (s *standIn) requestsTo(method, path string) []recordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[method+" "+path]
+}
+
+func (s *standIn) platform() *TikTokShop {
+	return &TikTokShop{httpClient: s.Client(), store: s.store}
+}
+
+func (s *standIn) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	key := r.Method + " " + r.URL.Path
+
+	s.mu.Lock()
+	fn := s.routes[key]
+	s.requests[key] = append(s.requests[key], recordedRequest{Query: r.URL.Query(), Body: body})
+	s.mu.Unlock()
+
+	if fn == nil {
+		s.t.Errorf("unexpected request %s", key)
+		writeResponse(w, nil, 404)
+		return
+	}
+
+	// 授权服务不签名，其它接口必须带正确的签名和访问令牌
+	if !strings.HasPrefix(r.URL.Path, "/api/v2/token/") {
+		signedBody := body
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+			signedBody = nil
+		}
+		if got, want := r.URL.Query().Get("sign"), expectedSign(r.URL.Path, r.URL.Query(), signedBody); got != want {
+			s.t.Errorf("%s: sign = %s, want %s", key, got, want)
+			writeResponse(w, nil, 106001)
+			return
+		}
+		if got := r.Header.Get("x-tts-access-token"); got != testAccessToken {
+			s.t.Errorf("%s: access token header = %q", key, got)
+		}
+	}
+
+	data, code := fn(r, body)
+	writeResponse(w, data, code)
+}
+
+func writeResponse(w http.ResponseWriter, data interface{}, code int) {
+	raw, _ := json.Marshal(data)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(apiResponse{
+		Code:      code,
+		Message:   "stand-in",
+		RequestID: "req-1",
+		Data:      raw,
+	})
+}
+
+// expectedSign 按开放平台文档独立拼接签名串：secret + path + 排序后的参数 + body + secret
+func expectedSign(path string, query url.Values, body []byte) string {
+	var keys []string
+	for k := range query {
+		if k != "sign" && k != "access_token" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	base := testAppSecret + path
+	for _, k := range keys {
+		base += k + query.Get(k)
+	}
+	base += string(body) + testAppSecret
+
+	mac := hmac.New(sha256.New, []byte(testAppSecret))
+	mac.Write([]byte(base))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// eventRecorder 记录发出的事件，recordEvents在测试结束后清除处理器
+type eventRecorder struct {
+	mu             sync.Mutex
+	shopsConnected []*types.ShopConnectedEvent
+	ordersReceived []*types.OrderReceivedEvent
+}
+
+func recordEvents(t *testing.T) *eventRecorder {
+	r := &eventRecorder{}
+	events.SetEventHandler(r)
+	t.Cleanup(func() { events.SetEventHandler(nil) })
+	return r
+}
+
+func (r *eventRecorder) OnShopConnected(event *types.ShopConnectedEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.shopsConnected = append(r.shopsConnected, event)
+	return nil
+}
+
+func (r *eventRecorder) OnProductPublished(*types.ProductPublishedEvent) error { return nil }
+
+func (r *eventRecorder) OnOrderReceived(event *types.OrderReceivedEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ordersReceived = append(r.ordersReceived, event)
+	return nil
+}
+
+func (r *eventRecorder) OnPaymentCompleted(*types.PaymentCompletedEvent) error { return nil }
+
+func TestSign(t *testing.T) {
+	query := url.Values{
+		"timestamp":    {"1700000000"},
+		"app_key":      {testAppKey},
+		"shop_cipher":  {"cipher-1"},
+		"access_token": {"ignored"},
+		"sign":         {"ignored"},
+	}
+	body := []byte(`{"title":"Mug"}`)
+
+	base := testAppSecret + "/product/202309/products" +
+		"app_key" + testAppKey + "shop_cipher" + "cipher-1" + "timestamp" + "1700000000" +
+		`{"title":"Mug"}` + testAppSecret
+	mac := hmac.New(sha256.New, []byte(testAppSecret))
+	mac.Write([]byte(base))
+	want := hex.EncodeToString(mac.Sum(nil))
+
+	if got := sign("/product/202309/products", query, body, testAppSecret); got != want {
+		t.Fatalf("sign = %s, want %s", got, want)
+	}
+	if got := sign("/product/202309/products", query, nil, testAppSecret); got == want {
+		t.Fatal("sign should cover the request body")
+	}
+}
+
+func TestCallSignsRequestAndDecodesData(t *testing.T) {
+	s := newStandIn(t)
+	s.handle(http.MethodPost, "/product/202309/categories/recommend", func(r *http.Request, body []byte) (interface{}, int) {
+		if r.URL.Query().Get("app_key") != testAppKey || r.URL.Query().Get("timestamp") == "" {
+			t.Errorf("missing app_key or timestamp: %s", r.URL.RawQuery)
+		}
+		return recommendCategoryData{LeafCategoryID: "600001"}, 0
+	})
+
+	cred := &TikTokShopCredential{ShopCipher: "cipher-1", AccessToken: testAccessToken}
+	var data recommendCategoryData
+	err := s.platform().call(t.Context(), http.MethodPost, "/product/202309/categories/recommend", shopQuery(cred), map[string]string{"product_title": "Mug"}, cred, &data)
+	if err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	if data.LeafCategoryID != "600001" {
+		t.Fatalf("leaf category = %q", data.LeafCategoryID)
+	}
+	if got := s.requestsTo(http.MethodPost, "/product/202309/categories/recommend")[0].Query.Get("shop_cipher"); got != "cipher-1" {
+		t.Fatalf("shop_cipher = %q", got)
+	}
+}
+
+func TestCallReturnsAPIError(t *testing.T) {
+	s := newStandIn(t)
+	s.handle(http.MethodGet, "/authorization/202309/shops", func(*http.Request, []byte) (interface{}, int) {
+		return nil, 105002
+	})
+
+	cred := &TikTokShopCredential{AccessToken: testAccessToken}
+	err := s.platform().call(t.Context(), http.MethodGet, "/authorization/202309/shops", nil, nil, cred, nil)
+	if !isAuthError(err) {
+		t.Fatalf("err = %v, want auth error", err)
+	}
+}
+
+// memStore 以内存代替店铺和商品记录的读写，店铺按域名、商品按平台商品ID保存
+type memStore struct {
+	links    map[string]*models.ShopLink
+	products map[string]*models.ShopProduct
+}
+
+func (m *memStore) FindShopByDomain(platform, domain string) (*models.ShopLink, error) {
+	if link, ok := m.links[domain]; ok && platform == platformName {
+		return link, nil
+	}
+	return nil, errors.ErrShopNotFound
+}
+
+func (m *memStore) SaveShopLink(link *models.ShopLink) (*models.ShopLink, error) {
+	if existing, ok := m.links[link.Domain]; ok {
+		link.ID = existing.ID
+	} else {
+		link.ID = uint(len(m.links) + 1)
+	}
+	m.links[link.Domain] = link
+	return link, nil
+}
+
+func (m *memStore) GetShopProduct(platform, outerID string) (*models.ShopProduct, bool, error) {
+	if product, ok := m.products[outerID]; ok && platform == platformName {
+		return product, true, nil
+	}
+	return nil, false, nil
+}
+
+func TestHandleCallback(t *testing.T) {
+	s := newStandIn(t)
+	links := s.store.links
+	recorder := recordEvents(t)
+
+	s.handle(http.MethodGet, "/api/v2/token/get", func(r *http.Request, _ []byte) (interface{}, int) {
+		q := r.URL.Query()
+		if q.Get("app_key") != testAppKey || q.Get("app_secret") != testAppSecret ||
+			q.Get("auth_code") != "auth-code-1" || q.Get("grant_type") != "authorized_code" {
+			t.Errorf("unexpected token request: %s", r.URL.RawQuery)
+		}
+		return tokenData{
+			AccessToken:          testAccessToken,
+			AccessTokenExpireIn:  1900000000,
+			RefreshToken:         "refresh-1",
+			RefreshTokenExpireIn: 1900000000,
+			OpenID:               "open-1",
+		}, 0
+	})
+	s.handle(http.MethodGet, "/authorization/202309/shops", func(*http.Request, []byte) (interface{}, int) {
+		return authorizedShopsData{Shops: []authorizedShop{
+			{ID: "7001", Cipher: "cipher-us", Name: "Aira US", Region: "US"},
+			{ID: "7002", Cipher: "cipher-gb", Name: "Aira UK", Region: "GB"},
+		}}, 0
+	})
+
+	callbackUrl, _ := url.Parse("https://example.com/stores/callback/tiktokshop?code=auth-code-1&state=s")
+	resp, err := s.platform().HandleCallback(nil, json.RawMessage(`{"owner_id":"owner-1"}`), callbackUrl)
+	if err != nil {
+		t.Fatalf("HandleCallback: %v", err)
+	}
+
+	if len(links) != 2 {
+		t.Fatalf("saved %d shop links, want 2", len(links))
+	}
+	if resp.Type != types.CallbackResponseTypeShopLinked || resp.ShopLinkedData.ShopLink != links["7001"] {
+		t.Fatalf("callback response should carry the first shop link: %+v", resp)
+	}
+
+	uk := links["7002"]
+	if uk.OwnerID != "owner-1" || uk.Platform != platformName || uk.Name != "Aira UK" {
+		t.Fatalf("unexpected shop link: %+v", uk)
+	}
+	var cred TikTokShopCredential
+	if err := json.Unmarshal(uk.Credentials, &cred); err != nil {
+		t.Fatalf("credentials: %v", err)
+	}
+	if cred.ShopCipher != "cipher-gb" || cred.Currency != "GBP" || cred.AccessToken != testAccessToken ||
+		cred.RefreshToken != "refresh-1" || cred.OpenID != "open-1" {
+		t.Fatalf("unexpected credentials: %+v", cred)
+	}
+
+	if len(recorder.shopsConnected) != 2 || recorder.shopsConnected[1].ShopID != uk.ID {
+		t.Fatalf("shop connected events = %+v", recorder.shopsConnected)
+	}
+}
+
+func TestHandleCallbackErrors(t *testing.T) {
+	s := newStandIn(t)
+	s.handle(http.MethodGet, "/api/v2/token/get", func(*http.Request, []byte) (interface{}, int) {
+		return nil, 36004004
+	})
+
+	noCode, _ := url.Parse("https://example.com/stores/callback/tiktokshop?state=s")
+	if _, err := s.platform().HandleCallback(nil, nil, noCode); err != errors.ErrInvalidCallbackSignature {
+		t.Fatalf("missing code: err = %v", err)
+	}
+
+	badCode, _ := url.Parse("https://example.com/stores/callback/tiktokshop?code=expired")
+	if _, err := s.platform().HandleCallback(nil, nil, badCode); err != errors.ErrAccessTokenFailed {
+		t.Fatalf("rejected code: err = %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package tiktokshop
+
+import "encoding/json"
+
+// https://partner.tiktokshop.com/docv2/page/650a99c4b1a23902bebbb5d1
+
+// apiResponse TikTok Shop接口统一响应结构
+type apiResponse struct {
+	Code      int             `json:"code"`
+	Message   string          `json:"message"`
+	RequestID string          `json:"request_id"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type tokenData struct {
+	AccessToken          string `json:"access_token"`
+	AccessTokenExpireIn  int64  `json:"access_token_expire_in"`
+	RefreshToken         string `json:"refresh_token"`
+	RefreshTokenExpireIn int64  `json:"refresh_token_expire_in"`
+	OpenID               string `json:"open_id"`
+	SellerName           string `json:"seller_name"`
+	SellerBaseRegion     string `json:"seller_base_region"`
+}
+
+type authorizedShop struct {
+	Cipher     string `json:"cipher"`
+	Code       string `json:"code"`
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Region     string `json:"region"`
+	SellerType string `json:"seller_type"`
+}
+
+type authorizedShopsData struct {
+	Shops []authorizedShop `json:"shops"`
+}
+
+type warehouse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	IsDefault bool   `json:"is_default"`
+}
+
+type warehousesData struct {
+	Warehouses []warehouse `json:"warehouses"`
+}
+
+type recommendCategoryData struct {
+	LeafCategoryID string `json:"leaf_category_id"`
+}
+
+type categoryAttribute struct {
+	ID             string                   `json:"id"`
+	Name           string                   `json:"name"`
+	Type           string                   `json:"type"` // SALES_PROPERTY, PRODUCT_PROPERTY
+	IsRequired     bool                     `json:"is_requried"`
+	IsCustomizable bool                     `json:"is_customizable"`
+	Values         []categoryAttributeValue `json:"values"`
+}
+
+type categoryAttributeValue struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type categoryAttributesData struct {
+	Attributes []categoryAttribute `json:"attributes"`
+}
+
+type uploadImageData struct {
+	Uri    string `json:"uri"`
+	Url    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+type imageRef struct {
+	Uri string `json:"uri"`
+}
+
+type createProductRequest struct {
+	Title             string             `json:"title"`
+	Description       string             `json:"description"`
+	CategoryID        string             `json:"category_id"`
+	MainImages        []imageRef         `json:"main_images"`
+	Skus              []productSku       `json:"skus"`
+	PackageWeight     *packageWeight     `json:"package_weight,omitempty"`
+	ProductAttributes []productAttribute `json:"product_attributes,omitempty"`
+}
+
+type productSku struct {
+	SalesAttributes []salesAttribute `json:"sales_attributes,omitempty"`
+	Price           skuPrice         `json:"price"`
+	Inventory       []skuInventory   `json:"inventory"`
+	SellerSku       string           `json:"seller_sku"`
+}
+
+type salesAttribute struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	ValueID   string `json:"value_id,omitempty"`
+	ValueName string `json:"value_name,omitempty"`
+}
+
+type skuPrice struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+type skuInventory struct {
+	WarehouseID string `json:"warehouse_id"`
+	Quantity    int    `json:"quantity"`
+}
+
+type packageWeight struct {
+	Value string `json:"value"`
+	Unit  string `json:"unit"` // KILOGRAM, POUND
+}
+
+type productAttribute struct {
+	ID     string                  `json:"id"`
+	Values []productAttributeValue `json:"values"`
+}
+
+type productAttributeValue struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type createProductData struct {
+	ProductID string `json:"product_id"`
+	Skus      []struct {
+		ID        string `json:"id"`
+		SellerSku string `json:"seller_sku"`
+	} `json:"skus"`
+}
+
+// webhookEvent TikTok Shop推送的webhook事件
+type webhookEvent struct {
+	Type              int             `json:"type"`
+	TtsNotificationID string          `json:"tts_notification_id"`
+	ShopID            string          `json:"shop_id"`
+	Timestamp         int64           `json:"timestamp"`
+	Data              json.RawMessage `json:"data"`
+}
+
+type orderStatusChangeData struct {
+	OrderID     string `json:"order_id"`
+	OrderStatus string `json:"order_status"`
+	UpdateTime  int64  `json:"update_time"`
+}
+
+type ordersData struct {
+	Orders []order `json:"orders"`
+}
+
+type order struct {
+	ID               string           `json:"id"`
+	Status           string           `json:"status"`
+	BuyerEmail       string           `json:"buyer_email"`
+	UserID           string           `json:"user_id"`
+	CreateTime       int64            `json:"create_time"`
+	UpdateTime       int64            `json:"update_time"`
+	Payment          orderPayment     `json:"payment"`
+	RecipientAddress recipientAddress `json:"recipient_address"`
+	LineItems        []orderLineItem  `json:"line_items"`
+	ShippingProvider string           `json:"shipping_provider"`
+	ShippingType     string           `json:"shipping_type"`
+	DeliveryOption   string           `json:"delivery_option_name"`
+}
+
+type orderPayment struct {
+	Currency          string `json:"currency"`
+	TotalAmount       string `json:"total_amount"`
+	SubTotal          string `json:"sub_total"`
+	ShippingFee       string `json:"shipping_fee"`
+	Tax               string `json:"tax"`
+	OriginalTotalCost string `json:"original_total_product_price"`
+}
+
+type recipientAddress struct {
+	Name         string         `json:"name"`
+	FirstName    string         `json:"first_name"`
+	LastName     string         `json:"last_name"`
+	PhoneNumber  string         `json:"phone_number"`
+	AddressLine1 string         `json:"address_line1"`
+	AddressLine2 string         `json:"address_line2"`
+	PostalCode   string         `json:"postal_code"`
+	RegionCode   string         `json:"region_code"`
+	DistrictInfo []districtInfo `json:"district_info"`
+}
+
+type districtInfo struct {
+	AddressLevel     string `json:"address_level"`
+	AddressLevelName string `json:"address_level_name"`
+	AddressName      string `json:"address_name"`
+}
+
+type orderLineItem struct {
+	ID          string `json:"id"`
+	ProductID   string `json:"product_id"`
+	ProductName string `json:"product_name"`
+	SkuID       string `json:"sku_id"`
+	SkuName     string `json:"sku_name"`
+	SellerSku   string `json:"seller_sku"`
+	SalePrice   string `json:"sale_price"`
+	Currency    string `json:"currency"`
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 
 	"github.com/flaboy/aira-core/pkg/hashid"
@@ -39,3 +41,12 @@ func SerializeCredential(cred interface{}) ([]byte, error) {
 func DeserializeCredential(data []byte, target interface{}) error {
 	return json.Unmarshal(data, target)
 }
+
+// GenerateNonce 生成OAuth授权使用的随机state
+func GenerateNonce() (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(nonce), nil
+}
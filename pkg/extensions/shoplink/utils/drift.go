@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"encoding/json"
+
+	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
+)
+
+// ProcessDrift 保存漂移报告，店铺策略为reapply时调用reapply重新应用发布的版本
+// 没有差异时不记录，返回nil
+func ProcessDrift(shopProduct *models.ShopProduct, report *types.DriftReport, reapply func() error) (*models.ShopProductDrift, error) {
+	if !report.Drifted() {
+		return nil, nil
+	}
+
+	var shop models.ShopLink
+	if err := database.Database().First(&shop, shopProduct.ShopID).Error; err != nil {
+		return nil, err
+	}
+
+	changes, err := json.Marshal(report.Changes)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &models.ShopProductDrift{
+		ShopProductID: shopProduct.ID,
+		ShopID:        shopProduct.ShopID,
+		Changes:       changes,
+		Action:        models.DriftActionReported,
+	}
+	if types.DriftPolicy(shop.DriftPolicy) == types.DriftPolicyReapply {
+		if err := reapply(); err != nil {
+			record.Action = models.DriftActionReapplyFailed
+			record.Error = err.Error()
+		} else {
+			record.Action = models.DriftActionReapplied
+		}
+	}
+
+	if err := database.Database().Create(record).Error; err != nil {
+		return nil, err
+	}
+	return record, nil
+}
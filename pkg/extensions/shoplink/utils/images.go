@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/flaboy/aira-shop/pkg/types"
+)
+
+// 平台普遍支持的图片格式
+var allowedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+var imageHTTPClient = &http.Client{}
+
+// PreparedImage 通过校验并去重后的图片
+type PreparedImage struct {
+	Image       types.ProductImage
+	ContentType string
+	Hash        string
+	Data        []byte
+	Attachment  string // 开启附件上传时为base64内容
+	VariantIDs  []uint // 使用该图片的内部变体
+	// 在结果列表中的位置，用于回写平台图片ID
+	ResultIndex int
+}
+
+// PrepareImages 下载产品图片并校验可访问性和格式，按内容去重，并收集变体图片
+// 返回可发布的图片和每张图片的处理结果，失败的图片不会阻止发布
+func PrepareImages(ctx context.Context, product *types.ProductData) ([]*PreparedImage, []types.ImageResult) {
+	var sources []types.ProductImage
+	if product.Image.Src != "" {
+		sources = append(sources, product.Image)
+	}
+	sources = append(sources, product.Images...)
+
+	// 变体图片不在产品图片中时追加
+	variantsBySrc := make(map[string][]uint)
+	for _, v := range product.Variants {
+		if v.ImageSrc == "" {
+			continue
+		}
+		if _, ok := variantsBySrc[v.ImageSrc]; !ok {
+			sources = append(sources, types.ProductImage{Src: v.ImageSrc})
+		}
+		variantsBySrc[v.ImageSrc] = append(variantsBySrc[v.ImageSrc], v.ID)
+	}
+
+	var prepared []*PreparedImage
+	var results []types.ImageResult
+	bySrc := make(map[string]*PreparedImage)
+	byHash := make(map[string]*PreparedImage)
+	seenSrc := make(map[string]bool)
+
+	for _, img := range sources {
+		if img.Src == "" || seenSrc[img.Src] {
+			continue
+		}
+		seenSrc[img.Src] = true
+
+		result := types.ImageResult{Src: img.Src}
+		data, contentType, err := fetchImage(ctx, img.Src)
+		if err != nil {
+			result.Status = types.ImageStatusFailed
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		sum := sha256.Sum256(data)
+		result.Hash = hex.EncodeToString(sum[:])
+		result.ContentType = contentType
+		result.Size = int64(len(data))
+
+		if existing, ok := byHash[result.Hash]; ok {
+			result.Status = types.ImageStatusDuplicate
+			result.DuplicateOf = existing.Image.Src
+			results = append(results, result)
+			bySrc[img.Src] = existing
+			continue
+		}
+
+		p := &PreparedImage{
+			Image:       img,
+			ContentType: contentType,
+			Hash:        result.Hash,
+			Data:        data,
+			ResultIndex: len(results),
+		}
+		if config.Config.ProductImages.Attach {
+			p.Attachment = base64.StdEncoding.EncodeToString(data)
+			result.Attached = true
+		}
+		result.Status = types.ImageStatusOK
+
+		results = append(results, result)
+		prepared = append(prepared, p)
+		bySrc[img.Src] = p
+		byHash[result.Hash] = p
+	}
+
+	// 变体图片映射到去重后保留的图片
+	for src, variantIDs := range variantsBySrc {
+		if p, ok := bySrc[src]; ok {
+			p.VariantIDs = append(p.VariantIDs, variantIDs...)
+		}
+	}
+	for _, p := range prepared {
+		results[p.ResultIndex].VariantIDs = p.VariantIDs
+	}
+
+	return prepared, results
+}
+
+// fetchImage 下载图片并校验状态码、大小和MIME类型
+func fetchImage(ctx context.Context, src string) ([]byte, string, error) {
+	timeout := time.Duration(config.Config.ProductImages.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid image url: %w", err)
+	}
+	resp, err := imageHTTPClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("image unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("image unreachable: status %d", resp.StatusCode)
+	}
+
+	maxBytes := config.Config.ProductImages.MaxBytes
+	if maxBytes > 0 && resp.ContentLength > maxBytes {
+		return nil, "", fmt.Errorf("image too large: %d bytes", resp.ContentLength)
+	}
+	reader := io.Reader(resp.Body)
+	if maxBytes > 0 {
+		reader = io.LimitReader(resp.Body, maxBytes+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read image: %w", err)
+	}
+	if maxBytes > 0 && int64(len(data)) > maxBytes {
+		return nil, "", fmt.Errorf("image too large: more than %d bytes", maxBytes)
+	}
+	if len(data) == 0 {
+		return nil, "", fmt.Errorf("image is empty")
+	}
+
+	// 以内容识别为准，响应头只作参考
+	contentType := http.DetectContentType(data)
+	if !allowedImageTypes[contentType] {
+		if header, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && allowedImageTypes[strings.ToLower(header)] && contentType == "application/octet-stream" {
+			contentType = strings.ToLower(header)
+		} else {
+			return nil, "", fmt.Errorf("unsupported image type %s", contentType)
+		}
+	}
+	return data, contentType, nil
+}
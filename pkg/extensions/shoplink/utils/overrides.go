@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
+	"github.com/shopspring/decimal"
+)
+
+var dec100 = decimal.NewFromInt(100)
+
+// GetShopOverrides 读取店铺的产品覆盖规则，未配置时返回nil
+func GetShopOverrides(shop *models.ShopLink) (*types.ProductOverrides, error) {
+	if len(shop.Overrides) == 0 || string(shop.Overrides) == "null" {
+		return nil, nil
+	}
+	overrides := &types.ProductOverrides{}
+	if err := json.Unmarshal(shop.Overrides, overrides); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// ApplyShopOverrides 按店铺覆盖规则生成实际发布的产品数据，原数据不会被修改
+func ApplyShopOverrides(shop *models.ShopLink, product *types.ProductData) (*types.ProductData, error) {
+	overrides, err := GetShopOverrides(shop)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyOverrides(product, overrides, shop.Name), nil
+}
+
+// ApplyOverrides 将覆盖规则应用到产品数据的副本上
+func ApplyOverrides(product *types.ProductData, overrides *types.ProductOverrides, shopName string) *types.ProductData {
+	result := *product
+	result.Variants = append([]types.ProductVariant(nil), product.Variants...)
+	if overrides == nil {
+		return &result
+	}
+
+	if overrides.TitleTemplate != "" {
+		result.ProductName = strings.NewReplacer(
+			"{{title}}", product.ProductName,
+			"{{shop_name}}", shopName,
+		).Replace(overrides.TitleTemplate)
+	}
+
+	if overrides.BodyTemplate != "" {
+		result.BodyHTML = strings.NewReplacer(
+			"{{body}}", product.BodyHTML,
+			"{{title}}", product.ProductName,
+			"{{shop_name}}", shopName,
+		).Replace(overrides.BodyTemplate)
+	}
+
+	if len(overrides.ExtraTags) > 0 {
+		result.Tags = mergeTags(product.Tags, overrides.ExtraTags)
+	}
+
+	for i := range result.Variants {
+		v := &result.Variants[i]
+
+		if fixed, ok := overrides.VariantPrices[v.ID]; ok {
+			price := roundPrice(fixed, overrides.Rounding)
+			v.Price = &price
+			// 固定价格后原价不高于售价时不再显示划线价
+			if v.CompareAtPrice != nil && v.CompareAtPrice.LessThanOrEqual(price) {
+				v.CompareAtPrice = nil
+			}
+			continue
+		}
+
+		if v.Price != nil {
+			price := roundPrice(markup(*v.Price, overrides.PriceMarkupPercent), overrides.Rounding)
+			v.Price = &price
+		}
+		if v.CompareAtPrice != nil {
+			compareAt := roundPrice(markup(*v.CompareAtPrice, overrides.PriceMarkupPercent), overrides.Rounding)
+			v.CompareAtPrice = &compareAt
+		}
+	}
+
+	return &result
+}
+
+func markup(price decimal.Decimal, percent *decimal.Decimal) decimal.Decimal {
+	if percent == nil {
+		return price
+	}
+	return price.Mul(dec100.Add(*percent)).Div(dec100)
+}
+
+func roundPrice(price decimal.Decimal, rounding *types.PriceRounding) decimal.Decimal {
+	if rounding == nil {
+		return price.Round(2)
+	}
+	if rounding.Increment != nil && rounding.Increment.IsPositive() {
+		price = price.Div(*rounding.Increment).Round(0).Mul(*rounding.Increment)
+	}
+	if rounding.Ending != nil {
+		price = price.Floor().Add(*rounding.Ending)
+	}
+	return price.Round(2)
+}
+
+// mergeTags 合并逗号分隔的标签并去重
+func mergeTags(tags string, extra []string) string {
+	seen := make(map[string]bool)
+	var merged []string
+	for _, tag := range append(strings.Split(tags, ","), extra...) {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		merged = append(merged, tag)
+	}
+	return strings.Join(merged, ", ")
+}
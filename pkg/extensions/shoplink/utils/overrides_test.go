@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/flaboy/aira-shop/pkg/types"
+	"github.com/shopspring/decimal"
+)
+
+func decimalPtr(v string) *decimal.Decimal {
+	d := decimal.RequireFromString(v)
+	return &d
+}
+
+func priceString(d *decimal.Decimal) string {
+	if d == nil {
+		return "nil"
+	}
+	return d.StringFixed(2)
+}
+
+func TestApplyOverrides(t *testing.T) {
+	product := &types.ProductData{
+		ProductName: "Ceramic Mug",
+		BodyHTML:    "<p>Handmade</p>",
+		Tags:        "mug, Kitchen",
+		Variants: []types.ProductVariant{
+			{ID: 11, Price: decimalPtr("19.99"), CompareAtPrice: decimalPtr("25")},
+			{ID: 12, Price: decimalPtr("10"), CompareAtPrice: decimalPtr("9")},
+		},
+	}
+	overrides := &types.ProductOverrides{
+		PriceMarkupPercent: decimalPtr("20"),
+		VariantPrices:      map[uint]decimal.Decimal{12: decimal.RequireFromString("9.5")},
+		TitleTemplate:      "{{title}} | {{shop_name}}",
+		BodyTemplate:       "<h1>{{title}}</h1>{{body}}",
+		ExtraTags:          []string{"kitchen", "gift"},
+	}
+
+	result := ApplyOverrides(product, overrides, "Aira Store")
+
+	if result.ProductName != "Ceramic Mug | Aira Store" {
+		t.Fatalf("title = %q", result.ProductName)
+	}
+	if result.BodyHTML != "<h1>Ceramic Mug</h1><p>Handmade</p>" {
+		t.Fatalf("body = %q", result.BodyHTML)
+	}
+	// 标签按小写去重，保留先出现的写法
+	if result.Tags != "mug, Kitchen, gift" {
+		t.Fatalf("tags = %q", result.Tags)
+	}
+
+	// 19.99 * 1.2 = 23.988，未配置舍入规则时保留两位小数
+	first := result.Variants[0]
+	if priceString(first.Price) != "23.99" || priceString(first.CompareAtPrice) != "30.00" {
+		t.Fatalf("marked up variant price = %s, compare at = %s", priceString(first.Price), priceString(first.CompareAtPrice))
+	}
+	// 固定价格不加价，原价不高于售价时去掉划线价
+	second := result.Variants[1]
+	if priceString(second.Price) != "9.50" || second.CompareAtPrice != nil {
+		t.Fatalf("fixed variant price = %s, compare at = %s", priceString(second.Price), priceString(second.CompareAtPrice))
+	}
+
+	// 原数据不被修改
+	if product.ProductName != "Ceramic Mug" || product.Tags != "mug, Kitchen" ||
+		priceString(product.Variants[0].Price) != "19.99" || priceString(product.Variants[1].CompareAtPrice) != "9.00" {
+		t.Fatalf("original product modified: %+v", product)
+	}
+}
+
+func TestApplyOverridesRounding(t *testing.T) {
+	cases := []struct {
+		name     string
+		price    string
+		markup   *decimal.Decimal
+		rounding *types.PriceRounding
+		want     string
+	}{
+		{"increment", "12.33", nil, &types.PriceRounding{Increment: decimalPtr("0.05")}, "12.35"},
+		{"ending", "12.30", nil, &types.PriceRounding{Ending: decimalPtr("0.99")}, "12.99"},
+		{"increment then ending", "12.60", nil, &types.PriceRounding{Increment: decimalPtr("1"), Ending: decimalPtr("0.99")}, "13.99"},
+		{"markup then increment", "10", decimalPtr("15"), &types.PriceRounding{Increment: decimalPtr("0.5")}, "11.50"},
+		{"zero increment ignored", "12.345", nil, &types.PriceRounding{Increment: decimalPtr("0")}, "12.35"},
+	}
+	for _, c := range cases {
+		product := &types.ProductData{Variants: []types.ProductVariant{{ID: 1, Price: decimalPtr(c.price)}}}
+		overrides := &types.ProductOverrides{PriceMarkupPercent: c.markup, Rounding: c.rounding}
+		result := ApplyOverrides(product, overrides, "")
+		if got := priceString(result.Variants[0].Price); got != c.want {
+			t.Errorf("%s: price = %s, want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestApplyOverridesNil(t *testing.T) {
+	product := &types.ProductData{
+		ProductName: "Ceramic Mug",
+		Variants:    []types.ProductVariant{{ID: 1, Price: decimalPtr("19.99")}},
+	}
+	result := ApplyOverrides(product, nil, "Aira Store")
+	if result == product || result.ProductName != "Ceramic Mug" || priceString(result.Variants[0].Price) != "19.99" {
+		t.Fatalf("result = %+v", result)
+	}
+	result.Variants[0].ID = 2
+	if product.Variants[0].ID != 1 {
+		t.Fatalf("variants should be copied")
+	}
+}
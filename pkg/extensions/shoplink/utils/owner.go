@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/errors"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/pin"
+	"github.com/spf13/cast"
+	"gorm.io/gorm"
+)
+
+// OwnerResolver 从BusinessContext中解析店铺所属商户
+type OwnerResolver func(businessContext json.RawMessage) (string, error)
+
+var ownerResolver OwnerResolver = defaultOwnerResolver
+
+// SetOwnerResolver 由业务系统设置商户解析方式，默认读取BusinessContext的owner_id字段
+func SetOwnerResolver(resolver OwnerResolver) {
+	ownerResolver = resolver
+}
+
+func defaultOwnerResolver(businessContext json.RawMessage) (string, error) {
+	if len(businessContext) == 0 {
+		return "", nil
+	}
+	var ctx map[string]interface{}
+	if err := json.Unmarshal(businessContext, &ctx); err != nil {
+		return "", err
+	}
+	return cast.ToString(ctx["owner_id"]), nil
+}
+
+// ResolveOwner 解析BusinessContext对应的商户ID
+func ResolveOwner(businessContext json.RawMessage) (string, error) {
+	return ownerResolver(businessContext)
+}
+
+// RequestOwnerResolver 从公开请求中解析发起请求的商户
+type RequestOwnerResolver func(c *pin.Context) (string, error)
+
+var requestOwnerResolver RequestOwnerResolver = defaultRequestOwnerResolver
+
+// SetRequestOwnerResolver 由业务系统设置请求商户的解析方式，需要从已认证的会话中读取
+// 请求参数可以被任意伪造，未设置时拒绝所有需要商户身份的请求
+func SetRequestOwnerResolver(resolver RequestOwnerResolver) {
+	requestOwnerResolver = resolver
+}
+
+func defaultRequestOwnerResolver(c *pin.Context) (string, error) {
+	return "", errors.ErrOwnerResolverNotSet
+}
+
+// ResolveRequestOwner 解析发起请求的商户ID
+func ResolveRequestOwner(c *pin.Context) (string, error) {
+	return requestOwnerResolver(c)
+}
+
+// NormalizeDomain 将店铺URL或域名统一为小写域名
+func NormalizeDomain(shopUrl string) string {
+	shopUrl = strings.TrimSpace(shopUrl)
+	if strings.Contains(shopUrl, "://") {
+		if u, err := url.Parse(shopUrl); err == nil {
+			shopUrl = u.Host
+		}
+	}
+	return strings.ToLower(strings.TrimSuffix(shopUrl, "/"))
+}
+
+// FindShopByDomain 按平台和店铺域名查找，兼容未记录域名的旧数据（domain为NULL）
+func FindShopByDomain(platform, domain string) (*models.ShopLink, error) {
+	domain = NormalizeDomain(domain)
+	var shop models.ShopLink
+	err := database.Database().
+		Where("platform = ? AND (domain = ? OR ((domain IS NULL OR domain = '') AND url = ?))", platform, domain, "https://"+domain).
+		First(&shop).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, errors.ErrShopNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &shop, nil
+}
+
+// SaveShopLink 按平台和域名创建或更新店铺，已被其他商户连接的店铺不能覆盖
+// 没有所属商户的店铺（旧数据或未配置商户的部署）由下一个完成授权的商户认领，
+// 调用方必须在平台授权成功后才调用，授权本身证明了对店铺的控制权
+func SaveShopLink(shopLink *models.ShopLink) (*models.ShopLink, error) {
+	shopLink.Domain = NormalizeDomain(shopLink.Domain)
+	if shopLink.Domain == "" {
+		return nil, errors.ErrShopCreation
+	}
+
+	existing, err := FindShopByDomain(shopLink.Platform, shopLink.Domain)
+	if err == errors.ErrShopNotFound {
+		createErr := database.Database().Create(shopLink).Error
+		if createErr == nil {
+			return shopLink, nil
+		}
+		// 并发授权时另一个请求已创建，唯一索引冲突后按已存在的店铺处理
+		if existing, err = FindShopByDomain(shopLink.Platform, shopLink.Domain); err != nil {
+			return nil, createErr
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if existing.OwnerID != "" && existing.OwnerID != shopLink.OwnerID {
+		return nil, errors.ErrShopOwnedByOther
+	}
+	if existing.OwnerID == "" && shopLink.OwnerID != "" {
+		slog.Info("Unowned shop link claimed by owner", "shopID", existing.ID, "platform", existing.Platform, "ownerID", shopLink.OwnerID)
+	}
+
+	existing.OwnerID = shopLink.OwnerID
+	existing.Domain = shopLink.Domain
+	existing.Name = shopLink.Name
+	existing.Url = shopLink.Url
+	existing.Credentials = shopLink.Credentials
+	if shopLink.Scopes != "" {
+		existing.Scopes = shopLink.Scopes
+	}
+	if err := database.Database().Save(existing).Error; err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/flaboy/aira-shop/pkg/errors"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"github.com/flaboy/aira-shop/pkg/types"
+)
+
+// PublishStatus 根据发布方式返回ShopProduct的初始状态和定时上架时间
+func PublishStatus(product *types.ProductData) (string, *time.Time, error) {
+	switch product.PublishMode {
+	case "", types.PublishModeActive:
+		return models.ShopProductStatusActive, nil, nil
+	case types.PublishModeDraft:
+		return models.ShopProductStatusDraft, nil, nil
+	case types.PublishModeScheduled:
+		if product.PublishAt == nil || !product.PublishAt.After(time.Now()) {
+			return "", nil, errors.ErrInvalidPublishAt
+		}
+		publishAt := *product.PublishAt
+		return models.ShopProductStatusScheduled, &publishAt, nil
+	default:
+		return "", nil, errors.ErrInvalidPublishMode
+	}
+}
+
+// RequireImmediatePublish 不支持草稿和定时发布的平台在发布前调用
+func RequireImmediatePublish(product *types.ProductData) error {
+	if product.PublishMode != "" && product.PublishMode != types.PublishModeActive {
+		return errors.ErrPublishModeNotSupported
+	}
+	return nil
+}
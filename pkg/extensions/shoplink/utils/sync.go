@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetSyncCheckpoint 获取店铺的同步进度，不存在时返回nil
+func GetSyncCheckpoint(shopID uint, kind string) (*models.ShopSyncCheckpoint, error) {
+	var checkpoint models.ShopSyncCheckpoint
+	err := database.Database().Where("shop_id = ? AND kind = ?", shopID, kind).First(&checkpoint).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// SaveSyncCheckpoint 保存店铺的同步进度
+func SaveSyncCheckpoint(shopID uint, kind string, syncedAt time.Time, cursor string) error {
+	return database.Database().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "shop_id"}, {Name: "kind"}},
+		DoUpdates: clause.AssignmentColumns([]string{"synced_at", "cursor", "updated_at"}),
+	}).Create(&models.ShopSyncCheckpoint{
+		ShopID:   shopID,
+		Kind:     kind,
+		SyncedAt: syncedAt,
+		Cursor:   cursor,
+	}).Error
+}
+
+// MarkOrderSynced 记录订单已同步，首次记录时返回true
+func MarkOrderSynced(shopID uint, outerID string) (bool, error) {
+	result := database.Database().Clauses(clause.OnConflict{DoNothing: true}).Create(&models.SyncedOrder{
+		ShopID:  shopID,
+		OuterID: outerID,
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// UnmarkOrderSynced 通知失败时撤销记录，下次轮询重试
+func UnmarkOrderSynced(shopID uint, outerID string) error {
+	return database.Database().Where("shop_id = ? AND outer_id = ?", shopID, outerID).Delete(&models.SyncedOrder{}).Error
+}
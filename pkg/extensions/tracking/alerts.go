@@ -0,0 +1,209 @@
+package tracking
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/flaboy/aira-shop/pkg/extensions/tracking/utils"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	AlertRuleNoEvents       = "no_events"
+	AlertRuleEtaPassed      = "eta_passed"
+	AlertRuleFailedAttempts = "failed_attempts"
+	AlertRuleException      = "exception"
+	AlertRuleReturnToSender = "return_to_sender"
+)
+
+// 每轮检查的包裹数
+const alertBatchSize = 500
+
+// alertRule 检查包裹是否触发规则，返回原因和触发条件
+type alertRule struct {
+	name  string
+	check func(shipment *models.Shipment, now time.Time) (reason, fingerprint string, err error)
+}
+
+var alertRules = []alertRule{
+	{AlertRuleException, checkStatus(utils.StatusException, "Shipment has a carrier exception")},
+	{AlertRuleReturnToSender, checkStatus(utils.StatusReturnToSender, "Shipment is being returned to sender")},
+	{AlertRuleNoEvents, checkNoEvents},
+	{AlertRuleEtaPassed, checkEtaPassed},
+	{AlertRuleFailedAttempts, checkFailedAttempts},
+}
+
+// StartAlertMonitor 定期检查未送达的包裹是否触发预警规则
+func StartAlertMonitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		checkAlerts()
+		<-ticker.C
+	}
+}
+
+func checkAlerts() {
+	now := time.Now()
+	var lastID uint
+	for {
+		var shipments []models.Shipment
+		tx := database.Database().
+			Where("id > ? AND status NOT IN ?", lastID, []string{string(utils.StatusDelivered), string(utils.StatusCancelled)}).
+			Order("id ASC").
+			Limit(alertBatchSize)
+		if days := config.Config.TrackingAlert.MaxAgeDays; days > 0 {
+			tx = tx.Where("created_at > ?", now.AddDate(0, 0, -days))
+		}
+		if err := tx.Find(&shipments).Error; err != nil {
+			slog.Error("Failed to load shipments for alerts", "error", err)
+			return
+		}
+
+		for i := range shipments {
+			evaluateAlerts(&shipments[i], now)
+		}
+		if len(shipments) < alertBatchSize {
+			return
+		}
+		lastID = shipments[len(shipments)-1].ID
+	}
+}
+
+// alertOnUpdate 状态变化时立即检查，异常和退回不必等到下次定期检查
+func alertOnUpdate(update *utils.TrackingUpdate) error {
+	shipment, err := utils.FindShipment(update.TrackingNumber)
+	if err != nil || shipment == nil {
+		return err
+	}
+	evaluateAlerts(shipment, time.Now())
+	return nil
+}
+
+// evaluateAlerts 检查所有规则，新触发的预警记录后通知回调
+func evaluateAlerts(shipment *models.Shipment, now time.Time) {
+	for _, rule := range alertRules {
+		reason, fingerprint, err := rule.check(shipment, now)
+		if err != nil {
+			slog.Error("Failed to check alert rule", "rule", rule.name, "trackingNumber", shipment.TrackingNumber, "error", err)
+			continue
+		}
+		if reason == "" {
+			continue
+		}
+
+		record := &models.ShipmentAlert{
+			ShipmentID:  shipment.ID,
+			Rule:        rule.name,
+			Fingerprint: fingerprint,
+			Reason:      reason,
+		}
+		// 与 ShipmentAlert.Reason 的长度一致
+		if runes := []rune(record.Reason); len(runes) > 500 {
+			record.Reason = string(runes[:500])
+		}
+		result := database.Database().Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		if result.Error != nil {
+			slog.Error("Failed to save shipment alert", "rule", rule.name, "trackingNumber", shipment.TrackingNumber, "error", result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		slog.Warn("Shipment alert", "rule", rule.name, "trackingNumber", shipment.TrackingNumber, "reason", reason)
+		utils.NotifyShipmentAlert(&utils.ShipmentAlert{
+			TrackingNumber: shipment.TrackingNumber,
+			ShipmentID:     shipment.ID,
+			Status:         utils.TrackingStatus(shipment.Status),
+			Rule:           rule.name,
+			Reason:         reason,
+		})
+	}
+}
+
+// ListShipmentAlerts 获取包裹的预警记录，最新的在前
+func ListShipmentAlerts(shipmentID uint) ([]models.ShipmentAlert, error) {
+	var alerts []models.ShipmentAlert
+	err := database.Database().Where("shipment_id = ?", shipmentID).Order("id DESC").Find(&alerts).Error
+	return alerts, err
+}
+
+// checkStatus 包裹进入指定状态时预警，以最新事件时间区分再次进入
+func checkStatus(status utils.TrackingStatus, reason string) func(*models.Shipment, time.Time) (string, string, error) {
+	return func(shipment *models.Shipment, now time.Time) (string, string, error) {
+		if utils.TrackingStatus(shipment.Status) != status {
+			return "", "", nil
+		}
+		msg := reason
+		if shipment.StatusMessage != "" {
+			msg += ": " + shipment.StatusMessage
+		}
+		return msg, formatFingerprint(shipment.LastEventAt), nil
+	}
+}
+
+// checkNoEvents 未到最终状态且超过N天没有新事件，没有事件时从创建时间算起
+func checkNoEvents(shipment *models.Shipment, now time.Time) (string, string, error) {
+	days := config.Config.TrackingAlert.NoEventDays
+	if days <= 0 || utils.TrackingStatus(shipment.Status).IsTerminal() {
+		return "", "", nil
+	}
+
+	since := shipment.CreatedAt
+	if shipment.LastEventAt != nil {
+		since = *shipment.LastEventAt
+	}
+	if now.Sub(since) < time.Duration(days)*24*time.Hour {
+		return "", "", nil
+	}
+	reason := fmt.Sprintf("No tracking events for %d days (since %s)", int(now.Sub(since).Hours()/24), since.Format("2006-01-02"))
+	return reason, formatFingerprint(&since), nil
+}
+
+// checkEtaPassed 超过预计送达时间仍未送达，预计时间变化后可再次预警
+func checkEtaPassed(shipment *models.Shipment, now time.Time) (string, string, error) {
+	if shipment.EstimatedDeliveryAt == nil || utils.TrackingStatus(shipment.Status).IsTerminal() {
+		return "", "", nil
+	}
+	grace := time.Duration(config.Config.TrackingAlert.EtaGraceHours) * time.Hour
+	if now.Before(shipment.EstimatedDeliveryAt.Add(grace)) {
+		return "", "", nil
+	}
+	reason := fmt.Sprintf("Estimated delivery %s has passed without delivery", shipment.EstimatedDeliveryAt.Format("2006-01-02"))
+	return reason, formatFingerprint(shipment.EstimatedDeliveryAt), nil
+}
+
+// checkFailedAttempts 派送失败的事件达到配置次数，各服务商的事件状态不统一，按状态和描述匹配
+func checkFailedAttempts(shipment *models.Shipment, now time.Time) (string, string, error) {
+	threshold := config.Config.TrackingAlert.FailedAttempts
+	if threshold <= 0 || utils.TrackingStatus(shipment.Status) == utils.StatusDelivered {
+		return "", "", nil
+	}
+
+	var count int64
+	err := database.Database().Model(&models.ShipmentEvent{}).
+		Where("shipment_id = ?", shipment.ID).
+		Where("status = ? OR LOWER(status) LIKE ? OR LOWER(status_code) LIKE ? OR LOWER(description) LIKE ? OR LOWER(description) LIKE ?",
+			string(utils.StatusFailure), "%deliveryfailure%", "%deliveryfailure%", "%attempt%", "%notice left%").
+		Count(&count).Error
+	if err != nil {
+		return "", "", err
+	}
+	if count < int64(threshold) {
+		return "", "", nil
+	}
+	return fmt.Sprintf("%d failed delivery attempts", count), fmt.Sprintf("%d", count), nil
+}
+
+func formatFingerprint(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
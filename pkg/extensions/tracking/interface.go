@@ -2,24 +2,41 @@ package tracking
 
 import (
 	"errors"
+	"log/slog"
 	"regexp"
+	"sort"
+	"sync"
+	"time"
 
+	"github.com/flaboy/aira-shop/pkg/config"
 	"github.com/flaboy/aira-shop/pkg/extensions/tracking/the17track"
+	"github.com/flaboy/aira-shop/pkg/extensions/tracking/ups"
+	"github.com/flaboy/aira-shop/pkg/extensions/tracking/usps"
 	"github.com/flaboy/aira-shop/pkg/extensions/tracking/utils"
 )
 
 type TrackRoute struct {
+	Name        string                 `json:"name"`
 	Provider    utils.TrackingProvider `json:"provider"`
 	Regex       string                 `json:"regex"`
 	CompiledReg *regexp.Regexp         `json:"-"`
+	// 优先级，数值大的先匹配，相同时按名称排序
+	Priority int `json:"priority"`
+	// 按识别出的承运商路由
+	Carriers []utils.Carrier `json:"carriers,omitempty"`
+	// 聚合服务商，没有其它路由匹配时使用
+	Fallback bool `json:"fallback"`
 }
 
-var providers map[string]TrackRoute
+var (
+	routesMu  sync.RWMutex
+	providers map[string]*TrackRoute
+	routes    []*TrackRoute // 按优先级排序
+)
 
 func Get(name string) utils.TrackingProvider {
-	if providers == nil {
-		return nil
-	}
+	routesMu.RLock()
+	defer routesMu.RUnlock()
 	route, exists := providers[name]
 	if !exists {
 		return nil
@@ -27,52 +44,173 @@ func Get(name string) utils.TrackingProvider {
 	return route.Provider
 }
 
+// RegisterTrackRoute 注册按正则匹配单号的服务商
 func RegisterTrackRoute(name, regex string, provider utils.TrackingProvider) {
-	if providers == nil {
-		providers = make(map[string]TrackRoute)
-	}
-	if _, exists := providers[name]; exists {
-		panic("Track route already registered: " + name)
-	}
-
 	// 预编译正则表达式
 	compiledReg, err := regexp.Compile(regex)
 	if err != nil {
 		panic("Invalid regex for provider " + name + ": " + err.Error())
 	}
-
-	providers[name] = TrackRoute{
+	addRoute(&TrackRoute{
+		Name:        name,
 		Provider:    provider,
 		Regex:       regex,
 		CompiledReg: compiledReg,
+	})
+}
+
+// RegisterCarrierRoute 注册处理指定承运商单号的服务商，单号格式由内置规则识别
+func RegisterCarrierRoute(name string, priority int, carriers []utils.Carrier, provider utils.TrackingProvider) {
+	addRoute(&TrackRoute{
+		Name:     name,
+		Provider: provider,
+		Priority: priority,
+		Carriers: carriers,
+	})
+}
+
+// RegisterFallbackRoute 注册聚合服务商（如17track），没有其它路由匹配时使用
+func RegisterFallbackRoute(name string, provider utils.TrackingProvider) {
+	addRoute(&TrackRoute{
+		Name:     name,
+		Provider: provider,
+		Fallback: true,
+	})
+}
+
+func addRoute(route *TrackRoute) {
+	routesMu.Lock()
+	defer routesMu.Unlock()
+
+	if providers == nil {
+		providers = make(map[string]*TrackRoute)
+	}
+	if _, exists := providers[route.Name]; exists {
+		panic("Track route already registered: " + route.Name)
 	}
+	providers[route.Name] = route
+	routes = append(routes, route)
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Fallback != routes[j].Fallback {
+			return !routes[i].Fallback
+		}
+		if routes[i].Priority != routes[j].Priority {
+			return routes[i].Priority > routes[j].Priority
+		}
+		return routes[i].Name < routes[j].Name
+	})
 }
 
 func Init() {
-	// Initialize all registered providers
+	// 17track作为聚合服务商兜底，承运商服务商按需注册
 	the17TrackProvider := &the17track.The17Track{}
-	RegisterTrackRoute("17track", ".*", the17TrackProvider)
+	RegisterFallbackRoute("17track", the17TrackProvider)
+
+	if config.Config.UPS.Enabled {
+		registerCarrierProvider(10, []utils.Carrier{utils.CarrierUPS}, &ups.UPS{})
+	}
+	if config.Config.USPS.Enabled {
+		registerCarrierProvider(10, []utils.Carrier{utils.CarrierUSPS}, &usps.USPS{})
+	}
+
+	if config.Config.TrackingPoll.Minutes > 0 {
+		go StartPollScheduler(time.Duration(config.Config.TrackingPoll.Minutes) * time.Minute)
+	}
+
+	// 送达或退回后停止追踪，不再消耗配额
+	utils.RegisterTrackingUpdateCallback(stopWhenFinished)
+
+	if config.Config.TrackingQuota.CheckMinutes > 0 {
+		go StartQuotaMonitor(time.Duration(config.Config.TrackingQuota.CheckMinutes) * time.Minute)
+	}
+
+	// 异常和退回在状态变化时立即预警，其它规则定期检查
+	utils.RegisterTrackingUpdateCallback(alertOnUpdate)
+	if config.Config.TrackingAlert.Minutes > 0 {
+		go StartAlertMonitor(time.Duration(config.Config.TrackingAlert.Minutes) * time.Minute)
+	}
 }
 
-// TODO: 当订单包裹号被添加时，调用此函数开始追踪
-// tracking.StartTracking("YT2301234567890")
-func StartTracking(trackingNumber string) error {
-	if providers == nil {
-		return errors.New("no tracking providers registered")
+// registerCarrierProvider 初始化失败时不注册，对应单号由聚合服务商处理
+func registerCarrierProvider(priority int, carriers []utils.Carrier, provider utils.TrackingProvider) {
+	if err := provider.Init(); err != nil {
+		slog.Error("Failed to init tracking provider", "provider", provider.GetProviderName(), "error", err)
+		return
+	}
+	RegisterCarrierRoute(provider.GetProviderName(), priority, carriers, provider)
+}
+
+// resolveRoute 按优先级选择服务商，carrier为调用方已知的承运商，为空时按单号格式识别
+func resolveRoute(trackingNumber string, carrier utils.Carrier) (*TrackRoute, error) {
+	routesMu.RLock()
+	defer routesMu.RUnlock()
+
+	if len(routes) == 0 {
+		return nil, errors.New("no tracking providers registered")
 	}
 
-	// 遍历所有注册的providers，使用正则匹配
-	for name, route := range providers {
-		if route.CompiledReg.MatchString(trackingNumber) {
-			// 找到匹配的provider，调用其StartTracking方法
-			err := route.Provider.StartTracking(trackingNumber)
-			if err != nil {
-				return errors.New("failed to start tracking with provider " + name + ": " + err.Error())
+	candidates := utils.DetectCarriers(trackingNumber)
+	if carrier != "" {
+		candidates = []utils.Carrier{carrier}
+	}
+
+	var fallback *TrackRoute
+	for _, route := range routes {
+		if route.Fallback {
+			if fallback == nil {
+				fallback = route
 			}
-			return nil
+			continue
 		}
+		if route.CompiledReg != nil && route.CompiledReg.MatchString(trackingNumber) {
+			return route, nil
+		}
+		for _, c := range route.Carriers {
+			for _, candidate := range candidates {
+				if c == candidate {
+					return route, nil
+				}
+			}
+		}
+	}
+	if fallback != nil {
+		return fallback, nil
 	}
 
 	// 没有找到匹配的provider
-	return errors.New("no matching tracking provider found for: " + trackingNumber)
+	return nil, errors.New("no matching tracking provider found for: " + trackingNumber)
+}
+
+// TODO: 当订单包裹号被添加时，调用此函数开始追踪
+// tracking.StartTracking("YT2301234567890")
+func StartTracking(trackingNumber string) error {
+	return StartTrackingWithCarrier(trackingNumber, "")
+}
+
+// StartTrackingWithCarrier 使用调用方提供的承运商选择服务商，carrier为空时按单号识别
+func StartTrackingWithCarrier(trackingNumber string, carrier utils.Carrier) error {
+	trackingNumber = utils.NormalizeTrackingNumber(trackingNumber)
+	route, err := resolveRoute(trackingNumber, carrier)
+	if err != nil {
+		return err
+	}
+
+	if err := route.Provider.StartTracking(trackingNumber); err != nil {
+		return errors.New("failed to start tracking with provider " + route.Name + ": " + err.Error())
+	}
+	return saveStartedShipment(trackingNumber, route.Provider, carrier)
+}
+
+// saveStartedShipment 服务商开始追踪后保存包裹，carrier为空时按单号识别
+func saveStartedShipment(trackingNumber string, provider utils.TrackingProvider, carrier utils.Carrier) error {
+	if carrier == "" {
+		if detected := utils.DetectCarriers(trackingNumber); len(detected) > 0 {
+			carrier = detected[0]
+		}
+	}
+	if _, err := utils.EnsureShipment(trackingNumber, provider.GetProviderName(), string(carrier)); err != nil {
+		return errors.New("failed to save shipment: " + err.Error())
+	}
+	return nil
 }
@@ -0,0 +1,159 @@
+package tracking
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/flaboy/aira-shop/pkg/extensions/tracking/utils"
+)
+
+// StartTrackingBatch 批量开始追踪，按路由分组，支持批量注册的服务商一次提交多个单号
+// 返回每个失败单号的原因
+func StartTrackingBatch(trackingNumbers []string) map[string]error {
+	errs := make(map[string]error)
+	groups := make(map[string][]string)
+	groupRoutes := make(map[string]*TrackRoute)
+	for _, number := range trackingNumbers {
+		number = utils.NormalizeTrackingNumber(number)
+		route, err := resolveRoute(number, "")
+		if err != nil {
+			errs[number] = err
+			continue
+		}
+		groups[route.Name] = append(groups[route.Name], number)
+		groupRoutes[route.Name] = route
+	}
+
+	for name, numbers := range groups {
+		provider := groupRoutes[name].Provider
+		batch, ok := provider.(utils.BatchTracker)
+		if !ok {
+			for _, number := range numbers {
+				if err := provider.StartTracking(number); err != nil {
+					errs[number] = err
+					continue
+				}
+				if err := saveStartedShipment(number, provider, ""); err != nil {
+					errs[number] = err
+				}
+			}
+			continue
+		}
+
+		rejected, err := batch.StartTrackingBatch(numbers)
+		for _, number := range numbers {
+			if rejectErr, ok := rejected[number]; ok {
+				errs[number] = rejectErr
+				continue
+			}
+			if err != nil {
+				errs[number] = errors.New("failed to start tracking with provider " + name + ": " + err.Error())
+				continue
+			}
+			if err := saveStartedShipment(number, provider, ""); err != nil {
+				errs[number] = err
+			}
+		}
+	}
+	return errs
+}
+
+// StopTracking 在服务商停止追踪，已停止的包裹直接返回
+func StopTracking(trackingNumber string) error {
+	trackingNumber = utils.NormalizeTrackingNumber(trackingNumber)
+	provider, err := shipmentProvider(trackingNumber)
+	if err != nil {
+		return err
+	}
+	if provider == nil {
+		return nil
+	}
+
+	if err := provider.StopTracking(trackingNumber); err != nil {
+		return errors.New("failed to stop tracking with provider " + provider.GetProviderName() + ": " + err.Error())
+	}
+	return utils.SetShipmentStopped(trackingNumber, true)
+}
+
+// RetrackNumber 恢复已停止的追踪，如退回后重新发出的包裹
+func RetrackNumber(trackingNumber string) error {
+	trackingNumber = utils.NormalizeTrackingNumber(trackingNumber)
+	shipment, err := utils.FindShipment(trackingNumber)
+	if err != nil {
+		return err
+	}
+	if shipment == nil {
+		return StartTracking(trackingNumber)
+	}
+
+	provider := Get(shipment.Provider)
+	if provider == nil {
+		return errors.New("tracking provider not registered: " + shipment.Provider)
+	}
+	if err := provider.RetrackNumber(trackingNumber); err != nil {
+		return errors.New("failed to retrack with provider " + provider.GetProviderName() + ": " + err.Error())
+	}
+	return utils.SetShipmentStopped(trackingNumber, false)
+}
+
+// shipmentProvider 返回包裹所在的服务商，包裹不存在或已停止时返回nil
+func shipmentProvider(trackingNumber string) (utils.TrackingProvider, error) {
+	shipment, err := utils.FindShipment(trackingNumber)
+	if err != nil || shipment == nil || shipment.StoppedAt != nil {
+		return nil, err
+	}
+	provider := Get(shipment.Provider)
+	if provider == nil {
+		return nil, errors.New("tracking provider not registered: " + shipment.Provider)
+	}
+	return provider, nil
+}
+
+// stopWhenFinished 包裹到达最终状态时停止追踪
+func stopWhenFinished(update *utils.TrackingUpdate) error {
+	if !update.Status.IsTerminal() {
+		return nil
+	}
+	return StopTracking(update.TrackingNumber)
+}
+
+// GetQuotas 查询各按配额计费的服务商的剩余配额
+func GetQuotas() map[string]*utils.Quota {
+	routesMu.RLock()
+	reporters := make(map[string]utils.QuotaReporter)
+	for name, route := range providers {
+		if reporter, ok := route.Provider.(utils.QuotaReporter); ok {
+			reporters[name] = reporter
+		}
+	}
+	routesMu.RUnlock()
+
+	quotas := make(map[string]*utils.Quota)
+	for name, reporter := range reporters {
+		quota, err := reporter.GetQuota()
+		if err != nil {
+			slog.Error("Failed to get tracking quota", "provider", name, "error", err)
+			continue
+		}
+		quotas[name] = quota
+	}
+	return quotas
+}
+
+// StartQuotaMonitor 定期检查配额，剩余低于配置比例时告警
+func StartQuotaMonitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for name, quota := range GetQuotas() {
+			slog.Info("Tracking quota", "provider", name, "total", quota.Total, "used", quota.Used, "remaining", quota.Remaining)
+			if quota.Total > 0 && quota.Remaining*100 < quota.Total*config.Config.TrackingQuota.WarnPercent {
+				slog.Warn("Tracking quota is running out", "provider", name, "total", quota.Total, "remaining", quota.Remaining)
+			}
+		}
+		<-ticker.C
+	}
+}
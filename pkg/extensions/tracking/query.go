@@ -0,0 +1,93 @@
+package tracking
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/flaboy/aira-shop/pkg/extensions/tracking/utils"
+)
+
+type cachedTracking struct {
+	response  *utils.TrackingResponse
+	expiresAt time.Time
+}
+
+var (
+	cacheMu       sync.Mutex
+	trackingCache = map[string]cachedTracking{}
+)
+
+// GetTracking 查询追踪号的当前追踪信息
+// 已在追踪的包裹使用原服务商并保存结果，其它单号按StartTracking的规则选择服务商
+// 结果按 TrackingCacheSeconds 缓存
+func GetTracking(trackingNumber string) (*utils.TrackingResponse, error) {
+	trackingNumber = utils.NormalizeTrackingNumber(trackingNumber)
+	if resp := cachedResponse(trackingNumber); resp != nil {
+		return resp, nil
+	}
+
+	shipment, err := utils.FindShipment(trackingNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var provider utils.TrackingProvider
+	if shipment != nil {
+		provider = Get(shipment.Provider)
+	}
+	if provider == nil {
+		route, err := resolveRoute(trackingNumber, "")
+		if err != nil {
+			return nil, err
+		}
+		provider = route.Provider
+	}
+
+	resp, err := provider.GetTracking(trackingNumber)
+	if err != nil {
+		return nil, errors.New("failed to get tracking with provider " + provider.GetProviderName() + ": " + err.Error())
+	}
+
+	if shipment != nil {
+		if err := utils.ApplyTrackingResponse(provider.GetProviderName(), resp); err != nil {
+			return nil, errors.New("failed to save tracking: " + err.Error())
+		}
+	}
+	cacheResponse(trackingNumber, resp)
+	return resp, nil
+}
+
+func cachedResponse(trackingNumber string) *utils.TrackingResponse {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	cached, ok := trackingCache[trackingNumber]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(cached.expiresAt) {
+		delete(trackingCache, trackingNumber)
+		return nil
+	}
+	return cached.response
+}
+
+func cacheResponse(trackingNumber string, resp *utils.TrackingResponse) {
+	ttl := time.Duration(config.Config.TrackingCacheSeconds) * time.Second
+	if ttl <= 0 {
+		return
+	}
+
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	now := time.Now()
+	for number, cached := range trackingCache {
+		if now.After(cached.expiresAt) {
+			delete(trackingCache, number)
+		}
+	}
+	trackingCache[trackingNumber] = cachedTracking{response: resp, expiresAt: now.Add(ttl)}
+}
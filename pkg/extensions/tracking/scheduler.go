@@ -0,0 +1,132 @@
+package tracking
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/flaboy/aira-shop/pkg/extensions/tracking/utils"
+	"github.com/flaboy/aira-shop/pkg/models"
+)
+
+// 查询失败后的重试间隔，正常查询的间隔由utils.NextPollInterval按状态计算
+const pollAfterError = time.Hour
+
+// StartPollScheduler 定期查询配置的服务商中到了查询时间的包裹
+func StartPollScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		pollDueShipments()
+		<-ticker.C
+	}
+}
+
+func pollDueShipments() {
+	concurrency := config.Config.TrackingPoll.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	for _, name := range strings.Split(config.Config.TrackingPoll.Providers, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		// 未启用或初始化失败的服务商没有注册，默认配置中的服务商多数不会启用，直接跳过
+		provider := Get(name)
+		if provider == nil {
+			continue
+		}
+
+		// 推送订阅会过期的服务商只轮询订阅已过期的包裹，订阅有效期内由推送更新
+		now := time.Now()
+		var createdBefore time.Time
+		if subscriber, ok := provider.(utils.PushSubscriber); ok {
+			createdBefore = now.Add(-subscriber.SubscriptionTTL())
+		}
+
+		shipments, err := utils.ListDueShipments(name, now, createdBefore, config.Config.TrackingPoll.BatchSize)
+		if err != nil {
+			slog.Error("Failed to load due shipments", "provider", name, "error", err)
+			continue
+		}
+
+		// 各服务商并行，同一服务商内最多concurrency个请求
+		wg.Add(1)
+		go func(provider utils.TrackingProvider, shipments []models.Shipment) {
+			defer wg.Done()
+			pollProvider(provider, shipments, concurrency)
+		}(provider, shipments)
+	}
+	wg.Wait()
+}
+
+func pollProvider(provider utils.TrackingProvider, shipments []models.Shipment, concurrency int) {
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range shipments {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(shipment *models.Shipment) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			pollShipment(provider, shipment)
+		}(&shipments[i])
+	}
+	wg.Wait()
+}
+
+// pollShipment 查询并保存追踪信息，保存时按最新状态安排下次查询，到达最终状态后不再查询
+// 单号长时间未知且没有任何事件时停止轮询
+func pollShipment(provider utils.TrackingProvider, shipment *models.Shipment) {
+	resp, err := provider.GetTracking(shipment.TrackingNumber)
+	if err == nil {
+		err = utils.ApplyTrackingResponse(provider.GetProviderName(), resp)
+	}
+	if err != nil {
+		slog.Error("Failed to poll tracking", "provider", provider.GetProviderName(), "trackingNumber", shipment.TrackingNumber, "error", err)
+		if !giveUpIfUnknown(shipment, time.Now()) {
+			scheduleNextPoll(shipment, utils.WithJitter(pollAfterError))
+		}
+		return
+	}
+
+	updated, err := utils.FindShipment(shipment.TrackingNumber)
+	if err != nil || updated == nil {
+		return
+	}
+	giveUpIfUnknown(updated, time.Now())
+}
+
+func scheduleNextPoll(shipment *models.Shipment, after time.Duration) {
+	if err := utils.SetNextPollAt(shipment.ID, time.Now().Add(after)); err != nil {
+		slog.Error("Failed to schedule tracking poll", "trackingNumber", shipment.TrackingNumber, "error", err)
+	}
+}
+
+// giveUpIfUnknown 创建超过UnknownGiveUpDays仍为未知状态且没有事件的单号多半无效，停止轮询
+func giveUpIfUnknown(shipment *models.Shipment, now time.Time) bool {
+	days := config.Config.TrackingPoll.UnknownGiveUpDays
+	if days <= 0 || shipment.LastEventAt != nil {
+		return false
+	}
+	if status := utils.TrackingStatus(shipment.Status); status != "" && status != utils.StatusUnknown {
+		return false
+	}
+	if now.Sub(shipment.CreatedAt) < time.Duration(days)*24*time.Hour {
+		return false
+	}
+
+	slog.Warn("Giving up polling unknown tracking number", "trackingNumber", shipment.TrackingNumber, "createdAt", shipment.CreatedAt)
+	if err := utils.SetShipmentStopped(shipment.TrackingNumber, true); err != nil {
+		slog.Error("Failed to stop tracking poll", "trackingNumber", shipment.TrackingNumber, "error", err)
+	}
+	return true
+}
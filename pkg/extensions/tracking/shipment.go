@@ -0,0 +1,38 @@
+package tracking
+
+import (
+	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/extensions/tracking/utils"
+	"github.com/flaboy/aira-shop/pkg/models"
+)
+
+// GetShipment 按追踪号获取包裹和追踪事件（最新的在前），不存在时返回nil
+func GetShipment(trackingNumber string) (*models.Shipment, []models.ShipmentEvent, error) {
+	shipment, err := utils.FindShipment(trackingNumber)
+	if err != nil || shipment == nil {
+		return nil, nil, err
+	}
+
+	var events []models.ShipmentEvent
+	err = database.Database().
+		Where("shipment_id = ?", shipment.ID).
+		Order("timestamp DESC, id DESC").
+		Find(&events).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	return shipment, events, nil
+}
+
+// ListShipmentsByStatus 按状态获取包裹，最近更新的在前，limit为0时不限数量
+func ListShipmentsByStatus(status utils.TrackingStatus, limit int) ([]models.Shipment, error) {
+	var shipments []models.Shipment
+	tx := database.Database().Where("status = ?", string(status)).Order("updated_at DESC")
+	if limit > 0 {
+		tx = tx.Limit(limit)
+	}
+	if err := tx.Find(&shipments).Error; err != nil {
+		return nil, err
+	}
+	return shipments, nil
+}
@@ -1,19 +1,17 @@
 package the17track
 
 import (
-	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/flaboy/aira-shop/pkg/config"
 	"github.com/flaboy/aira-shop/pkg/extensions/tracking/utils"
 
 	"github.com/flaboy/pin"
-	"github.com/valyala/fasthttp"
 )
 
 type The17Track struct {
@@ -27,71 +25,90 @@ func (t *The17Track) Init() error {
 func (t *The17Track) StartTracking(trackingNumber string) error {
 	slog.Info("Starting tracking", "trackingNumber", trackingNumber)
 
-	// 检查 API key 是否配置
-	apiKey := config.Config.The17TrackSecretKey
-	if apiKey == "" {
-		return fmt.Errorf("17track API key is not configured")
-	}
-
-	// 构建请求数据
-	registerData := []RegisterRequest{
-		{Number: trackingNumber},
-	}
-
-	requestBody, err := json.Marshal(registerData)
+	errs, err := t.StartTrackingBatch([]string{trackingNumber})
 	if err != nil {
 		return err
 	}
-
-	// 创建 fasthttp 请求
-	req := fasthttp.AcquireRequest()
-	resp := fasthttp.AcquireResponse()
-	defer fasthttp.ReleaseRequest(req)
-	defer fasthttp.ReleaseResponse(resp)
-
-	// 设置请求参数
-	req.SetRequestURI("https://api.17track.net/track/v2.2/register")
-	req.Header.SetMethod("POST")
-	req.Header.Set("17token", apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.SetBody(requestBody)
-
-	// 发送请求
-	if err := fasthttp.Do(req, resp); err != nil {
+	if err := errs[trackingNumber]; err != nil {
 		return err
 	}
 
-	// 检查响应状态
-	if resp.StatusCode() != 200 {
-		return fmt.Errorf("failed to register tracking number %s, status code: %d", trackingNumber, resp.StatusCode())
-	}
+	slog.Info("17Track webhook URL", "url", utils.GetPublicUrl("17track", "webhook"))
+	return nil
+}
 
-	// 解析响应
-	var registerResp RegisterResponse
-	if err := json.Unmarshal(resp.Body(), &registerResp); err != nil {
-		return err
-	}
+// StartTrackingBatch 批量注册单号，每次register请求最多40个，返回失败的单号及原因
+func (t *The17Track) StartTrackingBatch(trackingNumbers []string) (map[string]error, error) {
+	return t.batchCall("/register", trackingNumbers)
+}
 
-	// 检查 API 响应代码
-	if registerResp.Code != 0 {
-		return err
-	}
+// StopTracking 停止追踪，不再消耗配额，停止后可用RetrackNumber恢复
+func (t *The17Track) StopTracking(trackingNumber string) error {
+	return t.singleCall("/stoptrack", trackingNumber)
+}
+
+// RetrackNumber 重新追踪已停止的单号
+func (t *The17Track) RetrackNumber(trackingNumber string) error {
+	return t.singleCall("/retrack", trackingNumber)
+}
 
-	// 检查是否有被拒绝的追踪号
-	if len(registerResp.Data.Rejected) > 0 {
-		rejected := registerResp.Data.Rejected[0]
-		return fmt.Errorf("failed to register tracking number %s: %s (code: %d)", rejected.Number, rejected.Error.Message, rejected.Error.Code)
+// GetQuota 查询账户的单号配额
+func (t *The17Track) GetQuota() (*utils.Quota, error) {
+	var result QuotaResponse
+	if err := post("/getquota", []RegisterRequest{}, &result); err != nil {
+		return nil, err
 	}
+	if result.Code != 0 {
+		return nil, fmt.Errorf("17track getquota failed, code: %d", result.Code)
+	}
+	return &utils.Quota{
+		Total:     result.Data.QuotaTotal,
+		Used:      result.Data.QuotaUsed,
+		Remaining: result.Data.QuotaRemain,
+	}, nil
+}
 
-	// 检查是否成功接受
-	if len(registerResp.Data.Accepted) == 0 {
+func (t *The17Track) singleCall(path, trackingNumber string) error {
+	errs, err := t.batchCall(path, []string{trackingNumber})
+	if err != nil {
 		return err
 	}
+	return errs[trackingNumber]
+}
 
-	slog.Info("Successfully registered tracking number with 17Track", "trackingNumber", trackingNumber)
-	slog.Info("17Track webhook URL", "url", utils.GetPublicUrl("17track", "webhook"))
+// batchCall 按批次调用单号接口，被拒绝或所在批次请求失败的单号按号码返回错误
+func (t *The17Track) batchCall(path string, trackingNumbers []string) (map[string]error, error) {
+	errs := make(map[string]error)
+	for start := 0; start < len(trackingNumbers); start += batchSize {
+		end := start + batchSize
+		if end > len(trackingNumbers) {
+			end = len(trackingNumbers)
+		}
 
-	return nil
+		request := make([]RegisterRequest, 0, end-start)
+		for _, number := range trackingNumbers[start:end] {
+			request = append(request, RegisterRequest{Number: number})
+		}
+
+		var result RegisterResponse
+		err := post(path, request, &result)
+		if err == nil && result.Code != 0 {
+			err = fmt.Errorf("17track %s failed, code: %d", path, result.Code)
+		}
+		if err != nil {
+			// 请求失败时本批次的单号都未处理
+			for _, number := range trackingNumbers[start:end] {
+				errs[number] = err
+			}
+			continue
+		}
+
+		for _, rejected := range result.Data.Rejected {
+			errs[rejected.Number] = fmt.Errorf("17track %s rejected %s: %s (code: %d)", path, rejected.Number, rejected.Error.Message, rejected.Error.Code)
+		}
+		slog.Info("17Track batch call", "path", path, "accepted", len(result.Data.Accepted), "rejected", len(result.Data.Rejected))
+	}
+	return errs, nil
 }
 
 func (t *The17Track) GetTrackingUrl(trackingNumber string) string {
@@ -101,24 +118,11 @@ func (t *The17Track) GetTrackingUrl(trackingNumber string) string {
 
 func (t *The17Track) HandleRequest(c *pin.Context, path string) error {
 	if path == "webhook" {
-
-		event := TrackEvent{}
-		if err := c.Bind(&event); err != nil {
-			fmt.Println("Error binding request data:", err)
-		}
-
-		if event.Event == "TRACKING_UPDATED" {
-			err := utils.UpdateStatus(event.Data.Number, t.convertStatus(event.Data.TrackInfo.LatestStatus.Status))
-			if err != nil {
-				slog.Error("Error updating status", "trackingNumber", event.Data.Number, "error", err)
-				c.JSON(500, map[string]string{
-					"error": "Failed to update tracking status",
-				})
-				return nil
-			}
-		}
+		t.handleWebhook(c)
+		return nil
 	}
-	return c.Render("Request handled by 17trackProvider")
+	c.JSON(http.StatusNotFound, map[string]string{"error": "not found"})
+	return nil
 }
 
 func (t *The17Track) GetProviderName() string {
@@ -160,6 +164,23 @@ func (t *The17Track) Convert(localdata *TrackResponse) (*utils.TrackingResponse,
 		}
 	}
 
+	// 里程碑中的揽收和送达时间
+	for _, m := range localdata.TrackInfo.Milestone {
+		if m.TimeISO == "" {
+			continue
+		}
+		timestamp, err := time.Parse(time.RFC3339, m.TimeISO)
+		if err != nil {
+			continue
+		}
+		switch m.KeyStage {
+		case "PickedUp":
+			response.ShippedDate = &timestamp
+		case "Delivered":
+			response.ActualDeliveryDate = &timestamp
+		}
+	}
+
 	// 转换包裹信息
 	if localdata.TrackInfo.MiscInfo.WeightKg != "" || localdata.TrackInfo.MiscInfo.Dimensions != "" {
 		response.Package = &utils.PackageInfo{}
@@ -192,17 +213,20 @@ func (t *The17Track) Convert(localdata *TrackResponse) (*utils.TrackingResponse,
 }
 
 func (t *The17Track) convertStatus(status string) utils.TrackingStatus {
+	// v2.2接口的主状态为驼峰形式，如 InTransit、OutForDelivery
 	switch strings.ToLower(status) {
 	case "delivered":
 		return utils.StatusDelivered
-	case "in_transit", "transit":
+	case "in_transit", "transit", "intransit", "availableforpickup":
 		return utils.StatusInTransit
-	case "out_for_delivery":
+	case "out_for_delivery", "outfordelivery":
 		return utils.StatusOutForDelivery
-	case "pre_transit":
+	case "pre_transit", "inforeceived":
 		return utils.StatusPreTransit
 	case "return_to_sender":
 		return utils.StatusReturnToSender
+	case "deliveryfailure":
+		return utils.StatusFailure
 	case "exception":
 		return utils.StatusException
 	case "cancelled":
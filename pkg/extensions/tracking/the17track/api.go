@@ -0,0 +1,70 @@
+package the17track
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/flaboy/aira-shop/pkg/extensions/tracking/utils"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	apiBaseUrl     = "https://api.17track.net/track/v2.2"
+	requestTimeout = 30 * time.Second
+
+	// 单号类接口每次请求最多40个单号
+	batchSize = 40
+)
+
+// post 调用17track接口，非200时返回错误
+func post(path string, body interface{}, result interface{}) error {
+	apiKey := config.Config.The17TrackSecretKey
+	if apiKey == "" {
+		return fmt.Errorf("17track API key is not configured")
+	}
+
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(apiBaseUrl + path)
+	req.Header.SetMethod("POST")
+	req.Header.Set("17token", apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBody(requestBody)
+
+	if err := fasthttp.DoTimeout(req, resp, requestTimeout); err != nil {
+		return err
+	}
+	if resp.StatusCode() != 200 {
+		return fmt.Errorf("17track %s failed, status code: %d", path, resp.StatusCode())
+	}
+	return json.Unmarshal(resp.Body(), result)
+}
+
+// GetTracking 查询已注册单号的当前追踪信息，单号需先通过StartTracking注册
+func (t *The17Track) GetTracking(trackingNumber string) (*utils.TrackingResponse, error) {
+	var result TrackInfoResponse
+	if err := post("/gettrackinfo", []RegisterRequest{{Number: trackingNumber}}, &result); err != nil {
+		return nil, err
+	}
+	if result.Code != 0 {
+		return nil, fmt.Errorf("17track gettrackinfo failed, code: %d", result.Code)
+	}
+	if len(result.Data.Rejected) > 0 {
+		rejected := result.Data.Rejected[0]
+		return nil, fmt.Errorf("failed to get tracking info %s: %s (code: %d)", rejected.Number, rejected.Error.Message, rejected.Error.Code)
+	}
+	if len(result.Data.Accepted) == 0 {
+		return nil, fmt.Errorf("no tracking info returned for %s", trackingNumber)
+	}
+	return t.Convert(&result.Data.Accepted[0])
+}
@@ -152,3 +152,29 @@ type ProviderInfo struct {
 	Homepage string `json:"homepage"`
 	Country  string `json:"country"`
 }
+
+// 17Track API gettrackinfo response structure
+type TrackInfoResponse struct {
+	Code int `json:"code"`
+	Data struct {
+		Accepted []TrackResponse `json:"accepted"`
+		Rejected []struct {
+			Number string `json:"number"`
+			Error  struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		} `json:"rejected"`
+	} `json:"data"`
+}
+
+// 17Track API getquota response structure
+type QuotaResponse struct {
+	Code int `json:"code"`
+	Data struct {
+		QuotaTotal  int `json:"quota_total"`
+		QuotaUsed   int `json:"quota_used"`
+		QuotaRemain int `json:"quota_remain"`
+		TodayUsed   int `json:"today_used"`
+	} `json:"data"`
+}
@@ -0,0 +1,123 @@
+package the17track
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/flaboy/aira-shop/pkg/extensions/tracking/utils"
+	"github.com/flaboy/pin"
+)
+
+// 已处理的推送签名保留时间，期间相同内容的推送视为重放
+const processedSignTTL = 24 * time.Hour
+
+var (
+	processedMu    sync.Mutex
+	processedSigns = map[string]time.Time{}
+)
+
+// handleWebhook 校验签名后处理17track推送，只有处理成功才返回200
+func (t *The17Track) handleWebhook(c *pin.Context) {
+	if config.Config.The17TrackSecretKey == "" {
+		slog.Error("17track webhook rejected: secret key is not configured")
+		c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "webhook not configured"})
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid body"})
+		return
+	}
+
+	sign := c.GetHeader("sign")
+	if sign == "" {
+		c.JSON(http.StatusUnauthorized, map[string]string{"error": "missing signature"})
+		return
+	}
+	if !verifySign(body, sign) {
+		c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid signature"})
+		return
+	}
+
+	var event TrackEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+		return
+	}
+
+	if isProcessed(sign) {
+		slog.Info("Ignoring replayed 17track webhook", "trackingNumber", event.Data.Number)
+		c.JSON(http.StatusOK, map[string]string{"status": "duplicate"})
+		return
+	}
+
+	if event.Event == "TRACKING_UPDATED" {
+		resp, err := t.Convert(&event.Data)
+		if err != nil {
+			slog.Error("Error converting 17track webhook", "trackingNumber", event.Data.Number, "error", err)
+			c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+			return
+		}
+
+		stale, err := utils.IsStaleResponse(resp.TrackingNumber, resp.LastUpdated)
+		if err != nil {
+			slog.Error("Error checking 17track event time", "trackingNumber", event.Data.Number, "error", err)
+			c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to process event"})
+			return
+		}
+		if stale {
+			slog.Info("Ignoring stale 17track webhook", "trackingNumber", event.Data.Number)
+			c.JSON(http.StatusOK, map[string]string{"status": "stale"})
+			return
+		}
+
+		// 保存完整的追踪数据和事件历史，状态变化时通知
+		if err := utils.ApplyTrackingResponse(t.GetProviderName(), resp); err != nil {
+			slog.Error("Error updating status", "trackingNumber", event.Data.Number, "error", err)
+			c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to update tracking status"})
+			return
+		}
+	}
+
+	markProcessed(sign)
+	c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// verifySign 签名为 SHA256(body + "/" + 密钥) 的十六进制小写
+func verifySign(body []byte, sign string) bool {
+	h := sha256.New()
+	h.Write(body)
+	h.Write([]byte("/"))
+	h.Write([]byte(config.Config.The17TrackSecretKey))
+	expected := hex.EncodeToString(h.Sum(nil))
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(sign))) == 1
+}
+
+func isProcessed(sign string) bool {
+	processedMu.Lock()
+	defer processedMu.Unlock()
+	at, ok := processedSigns[sign]
+	return ok && time.Since(at) < processedSignTTL
+}
+
+func markProcessed(sign string) {
+	processedMu.Lock()
+	defer processedMu.Unlock()
+
+	now := time.Now()
+	for s, at := range processedSigns {
+		if now.Sub(at) >= processedSignTTL {
+			delete(processedSigns, s)
+		}
+	}
+	processedSigns[sign] = now
+}
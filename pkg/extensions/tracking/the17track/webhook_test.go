@@ -0,0 +1,43 @@
+package the17track
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flaboy/aira-shop/pkg/config"
+)
+
+func TestVerifySign(t *testing.T) {
+	prev := config.Config
+	t.Cleanup(func() { config.Config = prev })
+	config.Config = &config.CommenceConfig{}
+	config.Config.The17TrackSecretKey = "test-secret"
+
+	body := []byte(`{"event":"TRACKING_UPDATED"}`)
+	// sha256(body + "/test-secret")
+	sign := "12e5c2b1ed829da4acae0c2543f1c3a43ce29cef2cbf5462f59dce520dc171c6"
+
+	cases := []struct {
+		name string
+		body []byte
+		sign string
+		want bool
+	}{
+		{"lowercase hex", body, sign, true},
+		{"uppercase hex", body, strings.ToUpper(sign), true},
+		{"wrong signature", body, strings.Repeat("0", 64), false},
+		{"body changed", []byte(`{"event":"TRACKING_STOPPED"}`), sign, false},
+		{"empty signature", body, "", false},
+	}
+	for _, c := range cases {
+		if got := verifySign(c.body, c.sign); got != c.want {
+			t.Errorf("%s: verifySign = %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	// 密钥不同时签名失效
+	config.Config.The17TrackSecretKey = "other-secret"
+	if verifySign(body, sign) {
+		t.Fatalf("signature should not verify with a different secret")
+	}
+}
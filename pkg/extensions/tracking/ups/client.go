@@ -0,0 +1,138 @@
+package ups
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	productionBaseUrl = "https://onlinetools.ups.com"
+	sandboxBaseUrl    = "https://wwwcie.ups.com"
+
+	requestTimeout     = 30 * time.Second
+	tokenRefreshBefore = 5 * time.Minute
+)
+
+// 访问令牌在所有请求间共享，过期前刷新
+var (
+	tokenMu        sync.Mutex
+	tokenValue     string
+	tokenExpiresAt time.Time
+)
+
+func baseUrl() string {
+	if config.Config.UPS.Sandbox {
+		return sandboxBaseUrl
+	}
+	return productionBaseUrl
+}
+
+// accessToken 使用client_credentials获取访问令牌
+func accessToken() (string, error) {
+	tokenMu.Lock()
+	defer tokenMu.Unlock()
+
+	if tokenValue != "" && time.Until(tokenExpiresAt) > tokenRefreshBefore {
+		return tokenValue, nil
+	}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	basic := base64.StdEncoding.EncodeToString([]byte(config.Config.UPS.ClientID + ":" + config.Config.UPS.ClientSecret))
+	req.SetRequestURI(baseUrl() + "/security/v1/oauth/token")
+	req.Header.SetMethod("POST")
+	req.Header.Set("Authorization", "Basic "+basic)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBodyString(url.Values{"grant_type": {"client_credentials"}}.Encode())
+
+	if err := fasthttp.DoTimeout(req, resp, requestTimeout); err != nil {
+		return "", err
+	}
+	if resp.StatusCode() != 200 {
+		return "", fmt.Errorf("ups oauth failed: %s", responseError(resp))
+	}
+
+	var token TokenResponse
+	if err := json.Unmarshal(resp.Body(), &token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("ups oauth returned no access token")
+	}
+
+	expiresIn, _ := strconv.Atoi(token.ExpiresIn)
+	tokenValue = token.AccessToken
+	tokenExpiresAt = time.Now().Add(time.Duration(expiresIn) * time.Second)
+	return tokenValue, nil
+}
+
+// doRequest 带令牌调用UPS接口，body为nil时发送GET请求，非200时返回错误
+func doRequest(method, path string, body interface{}, result interface{}) error {
+	token, err := accessToken()
+	if err != nil {
+		return err
+	}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(baseUrl() + path)
+	req.Header.SetMethod(method)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("transId", transactionID())
+	req.Header.Set("transactionSrc", "aira-shop")
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBody(data)
+	}
+
+	if err := fasthttp.DoTimeout(req, resp, requestTimeout); err != nil {
+		return err
+	}
+	if resp.StatusCode() != 200 {
+		return fmt.Errorf("ups %s %s failed: %s", method, path, responseError(resp))
+	}
+	return json.Unmarshal(resp.Body(), result)
+}
+
+// responseError 解析UPS错误响应，无法解析时返回状态码
+func responseError(resp *fasthttp.Response) string {
+	var errResp ErrorResponse
+	if err := json.Unmarshal(resp.Body(), &errResp); err == nil && len(errResp.Response.Errors) > 0 {
+		var messages []string
+		for _, e := range errResp.Response.Errors {
+			messages = append(messages, e.Code+" "+e.Message)
+		}
+		return fmt.Sprintf("status %d: %s", resp.StatusCode(), strings.Join(messages, "; "))
+	}
+	return fmt.Sprintf("status %d", resp.StatusCode())
+}
+
+// transactionID UPS要求每个请求带唯一的transId，最长32位
+func transactionID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+	return hex.EncodeToString(b)
+}
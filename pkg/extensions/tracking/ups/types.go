@@ -60,13 +60,39 @@ type AccessPointInformation struct {
 
 // Activity 表示包裹的活动记录
 type Activity struct {
-	Date      *string `json:"date"`
-	GMTDate   *string `json:"gmtDate"`
-	GMTOffset *string `json:"gmtOffset"`
-	GMTTime   *string `json:"gmtTime"`
-	Location  *string `json:"location"`
-	Status    *string `json:"status"`
-	Time      *string `json:"time"`
+	Date      *string           `json:"date"`
+	GMTDate   *string           `json:"gmtDate"`
+	GMTOffset *string           `json:"gmtOffset"`
+	GMTTime   *string           `json:"gmtTime"`
+	Location  *ActivityLocation `json:"location"`
+	Status    *ActivityStatus   `json:"status"`
+	Time      *string           `json:"time"`
+}
+
+// ActivityLocation 表示活动发生的地点
+type ActivityLocation struct {
+	Address *Address `json:"address"`
+	Slic    string   `json:"slic"`
+}
+
+// ActivityStatus 表示活动状态，type为状态类型（D/I/M/P/X/O/RS等），statusCode为三位状态码
+type ActivityStatus struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Code        string `json:"code"`
+	StatusCode  string `json:"statusCode"`
+}
+
+// Address 表示UPS返回的地址
+type Address struct {
+	AddressLine1  string `json:"addressLine1"`
+	AddressLine2  string `json:"addressLine2"`
+	AddressLine3  string `json:"addressLine3"`
+	City          string `json:"city"`
+	StateProvince string `json:"stateProvince"`
+	PostalCode    string `json:"postalCode"`
+	Country       string `json:"country"`
+	CountryCode   string `json:"countryCode"`
 }
 
 // AlternateTrackingNumber 表示替代追踪号码
@@ -145,10 +171,10 @@ type Milestone struct {
 
 // PackageAddress 表示包裹地址
 type PackageAddress struct {
-	Address       *string `json:"address"`
-	AttentionName *string `json:"attentionName"`
-	Name          *string `json:"name"`
-	Type          *string `json:"type"`
+	Address       *Address `json:"address"`
+	AttentionName *string  `json:"attentionName"`
+	Name          *string  `json:"name"`
+	Type          *string  `json:"type"`
 }
 
 // PaymentInfo 表示支付信息
@@ -179,3 +205,55 @@ type Weight struct {
 	UnitOfMeasurement string `json:"unitOfMeasurement"`
 	Weight            string `json:"weight"`
 }
+
+// TokenResponse OAuth client_credentials 令牌响应，expires_in为字符串秒数
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   string `json:"expires_in"`
+	Status      string `json:"status"`
+}
+
+// ErrorResponse UPS接口的错误响应
+type ErrorResponse struct {
+	Response struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"response"`
+}
+
+// https://developer.ups.com/tag/Track-Alert
+
+// SubscriptionRequest Track Alert订阅请求，UPS推送时在credential头中带回destination.credential
+type SubscriptionRequest struct {
+	Locale             string                  `json:"locale"`
+	TrackingNumberList []string                `json:"trackingNumberList"`
+	Destination        SubscriptionDestination `json:"destination"`
+}
+
+type SubscriptionDestination struct {
+	URL            string `json:"url"`
+	CredentialType string `json:"credentialType"`
+	Credential     string `json:"credential"`
+}
+
+type SubscriptionResponse struct {
+	ValidTrackingNumbers   []string `json:"validTrackingNumbers"`
+	InvalidTrackingNumbers []string `json:"invalidTrackingNumbers"`
+}
+
+// AlertEvent Track Alert推送的单条事件
+type AlertEvent struct {
+	TrackingNumber        string         `json:"trackingNumber"`
+	LocalActivityDate     string         `json:"localActivityDate"`
+	LocalActivityTime     string         `json:"localActivityTime"`
+	ScheduledDeliveryDate string         `json:"scheduledDeliveryDate"`
+	ActualDeliveryDate    string         `json:"actualDeliveryDate"`
+	ActualDeliveryTime    string         `json:"actualDeliveryTime"`
+	GMTActivityDate       string         `json:"gmtActivityDate"`
+	GMTActivityTime       string         `json:"gmtActivityTime"`
+	ActivityLocation      Address        `json:"activityLocation"`
+	ActivityStatus        ActivityStatus `json:"activityStatus"`
+}
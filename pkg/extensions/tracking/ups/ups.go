@@ -1,37 +1,115 @@
 package ups
 
-// 本包没有启用
+// https://developer.ups.com/tag/Tracking
+// 1Z单号通过Track API查询，开始追踪时订阅Track Alert，之后由UPS推送更新
 
 import (
 	"fmt"
 	"log/slog"
+	"net/http"
+	"net/url"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/flaboy/aira-shop/pkg/config"
 	"github.com/flaboy/aira-shop/pkg/extensions/tracking/utils"
 
 	"github.com/flaboy/pin"
 )
 
+// Track Alert订阅的有效期，过期后由调度轮询
+const subscriptionTTL = 14 * 24 * time.Hour
+
 type UPS struct {
 }
 
 func (u *UPS) Init() error {
-	// Initialization logic for UPS
+	if config.Config.UPS.ClientID == "" || config.Config.UPS.ClientSecret == "" {
+		return fmt.Errorf("ups client id and secret are not configured")
+	}
+	if config.Config.UPS.WebhookCredential == "" {
+		return fmt.Errorf("ups webhook credential is not configured")
+	}
 	return nil
 }
 
+// StartTracking 订阅Track Alert，并立即查询一次当前状态
 func (u *UPS) StartTracking(trackingNumber string) error {
 	slog.Info("Starting UPS tracking", "trackingNumber", trackingNumber)
-	slog.Info("UPS webhook URL", "url", utils.GetPublicUrl("ups", "/webhook"))
+
+	subscription := SubscriptionRequest{
+		Locale:             config.Config.UPS.Locale,
+		TrackingNumberList: []string{trackingNumber},
+		Destination: SubscriptionDestination{
+			URL:            utils.GetPublicUrl("ups", "webhook"),
+			CredentialType: "Bearer",
+			Credential:     config.Config.UPS.WebhookCredential,
+		},
+	}
+	var result SubscriptionResponse
+	if err := doRequest("POST", "/api/track/v1/subscription/standard/package", subscription, &result); err != nil {
+		return err
+	}
+	if len(result.ValidTrackingNumbers) == 0 {
+		return fmt.Errorf("ups rejected tracking number %s", trackingNumber)
+	}
+
+	// 订阅只推送之后的事件，已有的事件通过查询补齐
+	resp, err := u.GetTracking(trackingNumber)
+	if err != nil {
+		slog.Warn("Initial UPS lookup failed", "trackingNumber", trackingNumber, "error", err)
+		return nil
+	}
+	return utils.ApplyTrackingResponse(u.GetProviderName(), resp)
+}
+
+// StopTracking Track Alert订阅14天后自动过期，没有取消接口
+func (u *UPS) StopTracking(trackingNumber string) error {
 	return nil
 }
 
-func (u *UPS) HandleRequest(c *pin.Context) error {
-	// Logic to handle requests for UPS
-	return c.Render("Request handled by UPS")
+// SubscriptionTTL 订阅过期后UPS不再推送，TrackingPoll.Providers包含ups时改为轮询
+func (u *UPS) SubscriptionTTL() time.Duration {
+	return subscriptionTTL
+}
+
+// RetrackNumber 重新订阅Track Alert
+func (u *UPS) RetrackNumber(trackingNumber string) error {
+	return u.StartTracking(trackingNumber)
+}
+
+// GetTracking 通过Track API查询单号的完整追踪信息
+func (u *UPS) GetTracking(trackingNumber string) (*utils.TrackingResponse, error) {
+	query := url.Values{
+		"locale":          {config.Config.UPS.Locale},
+		"returnSignature": {"false"},
+	}
+	var localdata TrackingResponse
+	path := "/api/track/v1/details/" + url.PathEscape(trackingNumber) + "?" + query.Encode()
+	if err := doRequest("GET", path, nil, &localdata); err != nil {
+		return nil, err
+	}
+	for _, shipment := range localdata.TrackResponse.Shipments {
+		if len(shipment.Packages) == 0 && len(shipment.Warnings) > 0 {
+			return nil, fmt.Errorf("ups tracking %s: %s", trackingNumber, shipment.Warnings[0].Message)
+		}
+	}
+	return u.Convert(&localdata)
+}
+
+func (u *UPS) GetTrackingUrl(trackingNumber string) string {
+	return fmt.Sprintf("https://www.ups.com/track?tracknum=%s", trackingNumber)
+}
+
+func (u *UPS) HandleRequest(c *pin.Context, path string) error {
+	if path == "webhook" {
+		u.handleWebhook(c)
+		return nil
+	}
+	c.JSON(http.StatusNotFound, map[string]string{"error": "not found"})
+	return nil
 }
 
 func (u *UPS) GetProviderName() string {
@@ -59,8 +137,13 @@ func (u *UPS) Convert(localdata *TrackingResponse) (*utils.TrackingResponse, err
 		RawData:        localdata,
 	}
 
-	// 转换状态信息
+	// 转换状态信息，currentStatus只有状态码，状态类型取最新的活动
 	response.Status = u.convertStatus(pkg.CurrentStatus.Code)
+	if len(pkg.Activities) > 0 && pkg.Activities[0].Status != nil {
+		if status := u.convertStatus(pkg.Activities[0].Status.Type); status != utils.StatusUnknown {
+			response.Status = status
+		}
+	}
 	response.StatusCode = pkg.CurrentStatus.Code
 	response.StatusMessage = pkg.CurrentStatus.Description
 
@@ -75,7 +158,14 @@ func (u *UPS) Convert(localdata *TrackingResponse) (*utils.TrackingResponse, err
 	if len(pkg.DeliveryDates) > 0 {
 		for _, deliveryDate := range pkg.DeliveryDates {
 			if deliveryDate.Date != nil && *deliveryDate.Date != "" {
-				if timestamp, err := time.Parse("2006-01-02", *deliveryDate.Date); err == nil {
+				timestamp, ok := parseDate(*deliveryDate.Date)
+				if !ok {
+					continue
+				}
+				// DEL为实际送达日期，其余为预计送达日期
+				if deliveryDate.Type != nil && *deliveryDate.Type == "DEL" {
+					response.ActualDeliveryDate = &timestamp
+				} else {
 					response.EstimatedDeliveryDate = &timestamp
 				}
 			}
@@ -155,7 +245,7 @@ func (u *UPS) convertStatus(statusCode string) utils.TrackingStatus {
 		return utils.StatusInTransit
 	case "O", "OUT_FOR_DELIVERY":
 		return utils.StatusOutForDelivery
-	case "P", "PICKUP", "LABEL_CREATED":
+	case "P", "M", "MV", "PICKUP", "LABEL_CREATED":
 		return utils.StatusPreTransit
 	case "X", "EXCEPTION":
 		return utils.StatusException
@@ -169,19 +259,7 @@ func (u *UPS) convertStatus(statusCode string) utils.TrackingStatus {
 func (u *UPS) convertPackageAddresses(addresses []PackageAddress, addressType string) *utils.Address {
 	for _, addr := range addresses {
 		if addr.Type != nil && strings.ToLower(*addr.Type) == addressType {
-			result := &utils.Address{}
-
-			if addr.Address != nil {
-				// UPS的地址格式可能需要解析
-				result.Street = *addr.Address
-			}
-
-			if addr.Name != nil {
-				// 这里可能包含城市/州信息
-				// 简单处理，实际可能需要更复杂的地址解析
-			}
-
-			return result
+			return convertAddress(addr.Address)
 		}
 	}
 	return nil
@@ -193,39 +271,30 @@ func (u *UPS) convertActivities(activities []Activity) []utils.TrackingEvent {
 	for _, activity := range activities {
 		event := utils.TrackingEvent{}
 
-		// 转换状态
+		// 转换状态和描述
 		if activity.Status != nil {
-			event.Status = *activity.Status
-		}
-
-		// 转换描述
-		if activity.Location != nil {
-			event.Description = *activity.Location
+			event.Status = string(u.convertStatus(activity.Status.Type))
+			event.StatusCode = activity.Status.StatusCode
+			event.Description = activity.Status.Description
 		}
 
 		// 转换时间
 		if activity.Date != nil && activity.Time != nil {
-			dateTimeStr := *activity.Date + " " + *activity.Time
-			if timestamp, err := time.Parse("2006-01-02 15:04:05", dateTimeStr); err == nil {
-				event.Timestamp = timestamp
-			} else if timestamp, err := time.Parse("20060102 1504", dateTimeStr); err == nil {
+			if timestamp, ok := parseDateTime(*activity.Date, *activity.Time); ok {
 				event.Timestamp = timestamp
 			}
 		}
 
 		// 转换GMT时间（如果可用）
 		if activity.GMTDate != nil && activity.GMTTime != nil {
-			gmtDateTimeStr := *activity.GMTDate + " " + *activity.GMTTime
-			if timestamp, err := time.Parse("2006-01-02 15:04:05", gmtDateTimeStr); err == nil {
+			if timestamp, ok := parseDateTime(*activity.GMTDate, *activity.GMTTime); ok {
 				event.Timestamp = timestamp
 			}
 		}
 
 		// 转换位置信息
 		if activity.Location != nil {
-			event.Location = &utils.Address{
-				City: *activity.Location,
-			}
+			event.Location = convertAddress(activity.Location.Address)
 		}
 
 		events = append(events, event)
@@ -238,3 +307,45 @@ func (u *UPS) convertActivities(activities []Activity) []utils.TrackingEvent {
 
 	return events
 }
+
+func convertAddress(addr *Address) *utils.Address {
+	if addr == nil {
+		return nil
+	}
+	street := strings.TrimSpace(strings.Join([]string{addr.AddressLine1, addr.AddressLine2, addr.AddressLine3}, " "))
+	country := addr.CountryCode
+	if country == "" {
+		country = addr.Country
+	}
+	return &utils.Address{
+		Street:     street,
+		City:       addr.City,
+		State:      addr.StateProvince,
+		PostalCode: addr.PostalCode,
+		Country:    country,
+	}
+}
+
+// parseDate UPS日期格式为 20060102
+func parseDate(date string) (time.Time, bool) {
+	for _, layout := range []string{"20060102", "2006-01-02"} {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseDateTime 时间有 150405 和 15:04:05 两种格式
+func parseDateTime(date, clock string) (time.Time, bool) {
+	d, ok := parseDate(date)
+	if !ok {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{"150405", "15:04:05", "1504"} {
+		if t, err := time.Parse(layout, clock); err == nil {
+			return d.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second), true
+		}
+	}
+	return d, true
+}
@@ -0,0 +1,108 @@
+package ups
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/flaboy/aira-shop/pkg/extensions/tracking/utils"
+	"github.com/flaboy/pin"
+)
+
+// handleWebhook 处理Track Alert推送，请求需带订阅时提供的凭证，只有处理成功才返回200
+func (u *UPS) handleWebhook(c *pin.Context) {
+	if config.Config.UPS.WebhookCredential == "" {
+		slog.Error("UPS webhook rejected: webhook credential is not configured")
+		c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "webhook not configured"})
+		return
+	}
+
+	if !verifyCredential(c) {
+		c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid credential"})
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid body"})
+		return
+	}
+
+	var event AlertEvent
+	if err := json.Unmarshal(body, &event); err != nil || event.TrackingNumber == "" {
+		c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid payload"})
+		return
+	}
+
+	resp := u.convertAlert(&event)
+	stale, err := utils.IsStaleResponse(resp.TrackingNumber, resp.LastUpdated)
+	if err != nil {
+		slog.Error("Error checking UPS event time", "trackingNumber", event.TrackingNumber, "error", err)
+		c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to process event"})
+		return
+	}
+	if stale {
+		slog.Info("Ignoring stale UPS webhook", "trackingNumber", event.TrackingNumber)
+		c.JSON(http.StatusOK, map[string]string{"status": "stale"})
+		return
+	}
+
+	if err := utils.ApplyTrackingResponse(u.GetProviderName(), resp); err != nil {
+		slog.Error("Error updating status", "trackingNumber", event.TrackingNumber, "error", err)
+		c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to update tracking status"})
+		return
+	}
+	c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// verifyCredential UPS在credential头中带回订阅时的凭证，也兼容Authorization: Bearer
+func verifyCredential(c *pin.Context) bool {
+	credential := c.GetHeader("credential")
+	if credential == "" {
+		credential = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	}
+	expected := config.Config.UPS.WebhookCredential
+	return credential != "" && subtle.ConstantTimeCompare([]byte(credential), []byte(expected)) == 1
+}
+
+// convertAlert 推送只包含最新的一条活动
+func (u *UPS) convertAlert(event *AlertEvent) *utils.TrackingResponse {
+	status := u.convertStatus(event.ActivityStatus.Type)
+	response := &utils.TrackingResponse{
+		TrackingNumber: event.TrackingNumber,
+		Carrier:        "ups",
+		CarrierCode:    "ups",
+		Status:         status,
+		StatusCode:     event.ActivityStatus.StatusCode,
+		StatusMessage:  event.ActivityStatus.Description,
+		RawData:        event,
+	}
+
+	if t, ok := parseDate(event.ScheduledDeliveryDate); ok {
+		response.EstimatedDeliveryDate = &t
+	}
+	if event.ActualDeliveryDate != "" {
+		if t, ok := parseDateTime(event.ActualDeliveryDate, event.ActualDeliveryTime); ok {
+			response.ActualDeliveryDate = &t
+		}
+	}
+
+	timestamp, ok := parseDateTime(event.GMTActivityDate, event.GMTActivityTime)
+	if !ok {
+		timestamp, ok = parseDateTime(event.LocalActivityDate, event.LocalActivityTime)
+	}
+	if ok {
+		response.LastUpdated = &timestamp
+		response.Events = []utils.TrackingEvent{{
+			Timestamp:   timestamp,
+			Status:      string(status),
+			StatusCode:  event.ActivityStatus.StatusCode,
+			Description: event.ActivityStatus.Description,
+			Location:    convertAddress(&event.ActivityLocation),
+		}}
+	}
+	return response
+}
@@ -0,0 +1,141 @@
+package usps
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flaboy/aira-shop/pkg/config"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	requestTimeout     = 30 * time.Second
+	tokenRefreshBefore = 5 * time.Minute
+)
+
+// errAuth 获取访问令牌失败，多为client id或secret配置错误
+var errAuth = errors.New("usps authentication failed")
+
+// apiError USPS接口返回的非200响应
+type apiError struct {
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("usps GET %s failed: %s", e.Path, e.Message)
+}
+
+// isAuthError 令牌获取失败或被拒绝时重试没有意义，其它错误可以稍后再查
+func isAuthError(err error) bool {
+	if errors.Is(err, errAuth) {
+		return true
+	}
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == 401 || apiErr.StatusCode == 403
+	}
+	return false
+}
+
+// 访问令牌在所有请求间共享，过期前刷新
+var (
+	tokenMu        sync.Mutex
+	tokenValue     string
+	tokenExpiresAt time.Time
+)
+
+// accessToken 使用client_credentials获取访问令牌
+func accessToken() (string, error) {
+	tokenMu.Lock()
+	defer tokenMu.Unlock()
+
+	if tokenValue != "" && time.Until(tokenExpiresAt) > tokenRefreshBefore {
+		return tokenValue, nil
+	}
+
+	body, err := json.Marshal(TokenRequest{
+		GrantType:    "client_credentials",
+		ClientID:     config.Config.USPS.ClientID,
+		ClientSecret: config.Config.USPS.ClientSecret,
+		Scope:        "tracking",
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(strings.TrimRight(config.Config.USPS.ApiBaseUrl, "/") + "/oauth2/v3/token")
+	req.Header.SetMethod("POST")
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBody(body)
+
+	if err := fasthttp.DoTimeout(req, resp, requestTimeout); err != nil {
+		return "", err
+	}
+	if resp.StatusCode() != 200 {
+		return "", fmt.Errorf("%w: %s", errAuth, responseError(resp))
+	}
+
+	var token TokenResponse
+	if err := json.Unmarshal(resp.Body(), &token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("%w: no access token returned", errAuth)
+	}
+
+	tokenValue = token.AccessToken
+	tokenExpiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return tokenValue, nil
+}
+
+// doGet 带令牌调用USPS接口，非200时返回*apiError
+func doGet(path string, result interface{}) error {
+	token, err := accessToken()
+	if err != nil {
+		return err
+	}
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(strings.TrimRight(config.Config.USPS.ApiBaseUrl, "/") + path)
+	req.Header.SetMethod("GET")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	if err := fasthttp.DoTimeout(req, resp, requestTimeout); err != nil {
+		return err
+	}
+	if resp.StatusCode() != 200 {
+		return &apiError{Path: path, StatusCode: resp.StatusCode(), Message: responseError(resp)}
+	}
+	return json.Unmarshal(resp.Body(), result)
+}
+
+// responseError 解析USPS错误响应，无法解析时返回状态码
+func responseError(resp *fasthttp.Response) string {
+	var errResp ErrorResponse
+	if err := json.Unmarshal(resp.Body(), &errResp); err == nil && errResp.Error.Message != "" {
+		messages := []string{errResp.Error.Message}
+		for _, e := range errResp.Error.Errors {
+			if e.Detail != "" {
+				messages = append(messages, e.Detail)
+			}
+		}
+		return fmt.Sprintf("status %d: %s", resp.StatusCode(), strings.Join(messages, "; "))
+	}
+	return fmt.Sprintf("status %d", resp.StatusCode())
+}
@@ -1,92 +1,77 @@
 package usps
 
-// 本包没有启用
-//https://www.usps.com/business/web-tools-apis/track-and-confirm-api.htm#_Toc188442122
+// https://developers.usps.com/trackingv3
 
-// TrackRequest 表示USPS查询包裹状态的请求
-type TrackRequest struct {
-	USERID   string    `xml:"USERID,attr"`
-	PASSWORD string    `xml:"PASSWORD,attr,omitempty"`
-	TrackIDs []TrackID `xml:"TrackID"`
+// TokenRequest OAuth client_credentials 令牌请求
+type TokenRequest struct {
+	GrantType    string `json:"grant_type"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	Scope        string `json:"scope,omitempty"`
 }
 
-// TrackID 表示要跟踪的包裹ID
-type TrackID struct {
-	ID string `xml:"ID,attr"`
+// TokenResponse 令牌响应，expires_in为秒数
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
 }
 
-// TrackFieldRequest 表示USPS更详细的包裹查询请求
-type TrackFieldRequest struct {
-	USERID   string  `xml:"USERID,attr"`
-	PASSWORD string  `xml:"PASSWORD,attr,omitempty"`
-	Revision int     `xml:"Revision"`
-	ClientIp string  `xml:"ClientIp"`
-	SourceId string  `xml:"SourceId"`
-	TrackID  TrackID `xml:"TrackID"`
+// ErrorResponse USPS接口的错误响应
+type ErrorResponse struct {
+	APIVersion string `json:"apiVersion"`
+	Error      struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Errors  []struct {
+			Status string `json:"status"`
+			Code   string `json:"code"`
+			Title  string `json:"title"`
+			Detail string `json:"detail"`
+		} `json:"errors"`
+	} `json:"error"`
 }
 
-// uspsResponse 包含USPS跟踪请求的响应
+// TrackingResponse 单个包裹的跟踪信息，expand=DETAIL时包含全部事件
 type TrackingResponse struct {
-	TrackInfo []TrackInfo `xml:"TrackInfo"`
+	TrackingNumber              string          `json:"trackingNumber"`
+	MailClass                   string          `json:"mailClass"`
+	MailType                    string          `json:"mailType"`
+	ServiceTypeCode             string          `json:"serviceTypeCode"`
+	Services                    []string        `json:"services"`
+	OriginCity                  string          `json:"originCity"`
+	OriginState                 string          `json:"originState"`
+	OriginZIP                   string          `json:"originZIP"`
+	OriginCountry               string          `json:"originCountry"`
+	DestinationCity             string          `json:"destinationCity"`
+	DestinationState            string          `json:"destinationState"`
+	DestinationZIP              string          `json:"destinationZIP"`
+	DestinationCountryCode      string          `json:"destinationCountryCode"`
+	Status                      string          `json:"status"`
+	StatusCategory              string          `json:"statusCategory"`
+	StatusSummary               string          `json:"statusSummary"`
+	ExpectedDeliveryTimeStamp   string          `json:"expectedDeliveryTimeStamp"`
+	GuaranteedDeliveryTimeStamp string          `json:"guaranteedDeliveryTimeStamp"`
+	PredictedDeliveryTimeStamp  string          `json:"predictedDeliveryTimeStamp"`
+	PredictedDeliveryDate       string          `json:"predictedDeliveryDate"`
+	TrackingEvents              []TrackingEvent `json:"trackingEvents"`
 }
 
-// TrackInfo 包含单个包裹的跟踪信息
-type TrackInfo struct {
-	ID                     string        `xml:"ID,attr"`
-	Class                  string        `xml:"Class,omitempty"`
-	ClassOfMailCode        string        `xml:"ClassOfMailCode,omitempty"`
-	DestinationCity        string        `xml:"DestinationCity,omitempty"`
-	DestinationState       string        `xml:"DestinationState,omitempty"`
-	DestinationZip         string        `xml:"DestinationZip,omitempty"`
-	EmailEnabled           bool          `xml:"EmailEnabled,omitempty"`
-	KahalaIndicator        bool          `xml:"KahalaIndicator,omitempty"`
-	MailTypeCode           string        `xml:"MailTypeCode,omitempty"`
-	MPDATE                 string        `xml:"MPDATE,omitempty"`
-	MPSUFFIX               string        `xml:"MPSUFFIX,omitempty"`
-	OriginCity             string        `xml:"OriginCity,omitempty"`
-	OriginState            string        `xml:"OriginState,omitempty"`
-	OriginZip              string        `xml:"OriginZip,omitempty"`
-	PodEnabled             bool          `xml:"PodEnabled,omitempty"`
-	TPodEnabled            bool          `xml:"TPodEnabled,omitempty"`
-	RestoreEnabled         bool          `xml:"RestoreEnabled,omitempty"`
-	RramEnabled            bool          `xml:"RramEnabled,omitempty"`
-	RreEnabled             bool          `xml:"RreEnabled,omitempty"`
-	Service                []string      `xml:"Service,omitempty"`
-	ServiceTypeCode        string        `xml:"ServiceTypeCode,omitempty"`
-	Status                 string        `xml:"Status,omitempty"`
-	StatusCategory         string        `xml:"StatusCategory,omitempty"`
-	StatusSummary          string        `xml:"StatusSummary,omitempty"`
-	TABLECODE              string        `xml:"TABLECODE,omitempty"`
-	TrackSummary           EventDetail   `xml:"TrackSummary"`
-	TrackDetail            []EventDetail `xml:"TrackDetail,omitempty"`
-	ExpectedDeliveryDate   string        `xml:"ExpectedDeliveryDate,omitempty"`
-	ExpectedDeliveryTime   string        `xml:"ExpectedDeliveryTime,omitempty"`
-	GuaranteedDeliveryDate string        `xml:"GuaranteedDeliveryDate,omitempty"`
-	Error                  *Error        `xml:"Error,omitempty"`
-}
-
-// EventDetail 表示包裹跟踪事件的详细信息
-type EventDetail struct {
-	EventTime             string `xml:"EventTime,omitempty"`
-	EventDate             string `xml:"EventDate,omitempty"`
-	Event                 string `xml:"Event,omitempty"`
-	EventCity             string `xml:"EventCity,omitempty"`
-	EventState            string `xml:"EventState,omitempty"`
-	EventZIPCode          string `xml:"EventZIPCode,omitempty"`
-	EventCountry          string `xml:"EventCountry,omitempty"`
-	FirmName              string `xml:"FirmName,omitempty"`
-	Name                  string `xml:"Name,omitempty"`
-	AuthorizedAgent       bool   `xml:"AuthorizedAgent,omitempty"`
-	EventCode             string `xml:"EventCode,omitempty"`
-	DeliveryAttributeCode string `xml:"DeliveryAttributeCode,omitempty"`
-	GMT                   string `xml:"GMT,omitempty"`
-	GMTOffset             string `xml:"GMTOffset,omitempty"`
-}
-
-// Error 表示跟踪请求的错误信息
-type Error struct {
-	Number      string `xml:"Number,omitempty"`
-	Description string `xml:"Description,omitempty"`
-	HelpFile    string `xml:"HelpFile,omitempty"`
-	HelpContext string `xml:"HelpContext,omitempty"`
+// TrackingEvent 表示包裹跟踪事件的详细信息
+type TrackingEvent struct {
+	EventType       string `json:"eventType"`
+	EventTimestamp  string `json:"eventTimestamp"`
+	GMTTimestamp    string `json:"GMTTimestamp"`
+	GMTOffset       string `json:"GMTOffset"`
+	EventCountry    string `json:"eventCountry"`
+	EventCity       string `json:"eventCity"`
+	EventState      string `json:"eventState"`
+	EventZIP        string `json:"eventZIP"`
+	Firm            string `json:"firm"`
+	Name            string `json:"name"`
+	AuthorizedAgent bool   `json:"authorizedAgent"`
+	EventCode       string `json:"eventCode"`
+	ActionCode      string `json:"actionCode"`
+	ReasonCode      string `json:"reasonCode"`
 }
@@ -1,12 +1,17 @@
 package usps
 
+// USPS v3 追踪接口没有可用的推送，开始追踪时查询一次，之后由tracking的轮询调度更新
+
 import (
 	"fmt"
 	"log/slog"
+	"net/http"
+	"net/url"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/flaboy/aira-shop/pkg/config"
 	"github.com/flaboy/aira-shop/pkg/extensions/tracking/utils"
 
 	"github.com/flaboy/pin"
@@ -16,19 +21,57 @@ type USPS struct {
 }
 
 func (u *USPS) Init() error {
-	// Initialization logic for USPS
+	if config.Config.USPS.ClientID == "" || config.Config.USPS.ClientSecret == "" {
+		return fmt.Errorf("usps client id and secret are not configured")
+	}
 	return nil
 }
 
+// StartTracking 查询一次当前状态，之后由轮询更新，只有认证失败时返回错误
+// 刚创建的面单在USPS系统中通常还查不到，先按未知状态保存包裹，由轮询继续查询
 func (u *USPS) StartTracking(trackingNumber string) error {
 	slog.Info("Starting USPS tracking", "trackingNumber", trackingNumber)
-	slog.Info("USPS webhook URL", "url", utils.GetPublicUrl("usps", "/webhook"))
+
+	resp, err := u.GetTracking(trackingNumber)
+	if err != nil {
+		if isAuthError(err) {
+			return err
+		}
+		slog.Warn("USPS tracking not available yet", "trackingNumber", trackingNumber, "error", err)
+		_, err = utils.EnsureShipment(trackingNumber, u.GetProviderName(), "")
+		return err
+	}
+	return utils.ApplyTrackingResponse(u.GetProviderName(), resp)
+}
+
+// StopTracking 轮询调度不再查询已停止的包裹，USPS无需处理
+func (u *USPS) StopTracking(trackingNumber string) error {
 	return nil
 }
 
-func (u *USPS) HandleRequest(c *pin.Context) error {
-	// Logic to handle requests for USPS
-	return c.Render("Request handled by USPS")
+// RetrackNumber 重新查询一次当前状态
+func (u *USPS) RetrackNumber(trackingNumber string) error {
+	return u.StartTracking(trackingNumber)
+}
+
+// GetTracking 查询单号的完整追踪信息
+func (u *USPS) GetTracking(trackingNumber string) (*utils.TrackingResponse, error) {
+	var localdata TrackingResponse
+	path := "/tracking/v3/tracking/" + url.PathEscape(trackingNumber) + "?expand=DETAIL"
+	if err := doGet(path, &localdata); err != nil {
+		return nil, err
+	}
+	return u.Convert(&localdata)
+}
+
+func (u *USPS) GetTrackingUrl(trackingNumber string) string {
+	return fmt.Sprintf("https://tools.usps.com/go/TrackConfirmAction?tLabels=%s", trackingNumber)
+}
+
+// HandleRequest USPS没有推送，不处理任何请求
+func (u *USPS) HandleRequest(c *pin.Context, path string) error {
+	c.JSON(http.StatusNotFound, map[string]string{"error": "not found"})
+	return nil
 }
 
 func (u *USPS) GetProviderName() string {
@@ -36,51 +79,37 @@ func (u *USPS) GetProviderName() string {
 }
 
 func (u *USPS) Convert(localdata *TrackingResponse) (*utils.TrackingResponse, error) {
-	if localdata == nil || len(localdata.TrackInfo) == 0 {
+	if localdata == nil || localdata.TrackingNumber == "" {
 		return nil, fmt.Errorf("local data is nil or empty")
 	}
 
-	// 取第一个TrackInfo
-	trackInfo := localdata.TrackInfo[0]
-
-	// 检查是否有错误
-	if trackInfo.Error != nil {
-		return nil, fmt.Errorf("USPS tracking error: %s", trackInfo.Error.Description)
-	}
-
 	response := &utils.TrackingResponse{
-		TrackingNumber: trackInfo.ID,
+		TrackingNumber: localdata.TrackingNumber,
 		Carrier:        "usps",
 		CarrierCode:    "usps",
 		RawData:        localdata,
 	}
 
-	// 转换状态信息
-	response.Status = u.convertStatus(trackInfo.Status)
-	response.StatusCode = trackInfo.StatusCategory
-	response.StatusMessage = trackInfo.StatusSummary
+	// 转换状态信息，statusCategory比status更稳定
+	response.Status = u.convertStatus(localdata.StatusCategory)
+	if response.Status == utils.StatusUnknown {
+		response.Status = u.convertStatus(localdata.Status)
+	}
+	response.StatusCode = localdata.StatusCategory
+	response.StatusMessage = localdata.StatusSummary
 
 	// 转换地址信息
-	response.Origin = u.convertOriginAddress(&trackInfo)
-	response.Destination = u.convertDestinationAddress(&trackInfo)
+	response.Origin = u.convertAddress(localdata.OriginCity, localdata.OriginState, localdata.OriginZIP, localdata.OriginCountry)
+	response.Destination = u.convertAddress(localdata.DestinationCity, localdata.DestinationState, localdata.DestinationZIP, localdata.DestinationCountryCode)
 
 	// 转换服务类型
-	if len(trackInfo.Service) > 0 {
-		response.ServiceType = trackInfo.Service[0]
-	}
+	response.ServiceType = localdata.MailClass
 
-	// 转换时间信息
-	if trackInfo.ExpectedDeliveryDate != "" {
-		if timestamp, err := time.Parse("January 2, 2006", trackInfo.ExpectedDeliveryDate); err == nil {
-			response.EstimatedDeliveryDate = &timestamp
-		} else if timestamp, err := time.Parse("2006-01-02", trackInfo.ExpectedDeliveryDate); err == nil {
-			response.EstimatedDeliveryDate = &timestamp
-		}
-	}
-
-	if trackInfo.GuaranteedDeliveryDate != "" {
-		if timestamp, err := time.Parse("January 2, 2006", trackInfo.GuaranteedDeliveryDate); err == nil {
+	// 转换预计送达时间，优先使用承诺时间
+	for _, ts := range []string{localdata.GuaranteedDeliveryTimeStamp, localdata.ExpectedDeliveryTimeStamp, localdata.PredictedDeliveryTimeStamp, localdata.PredictedDeliveryDate} {
+		if timestamp, err := u.parseUSPSDateTime(ts); err == nil {
 			response.EstimatedDeliveryDate = &timestamp
+			break
 		}
 	}
 
@@ -89,122 +118,73 @@ func (u *USPS) Convert(localdata *TrackingResponse) (*utils.TrackingResponse, er
 		Pieces: 1, // USPS通常是单件
 	}
 
-	// 转换最后更新时间
-	if trackInfo.TrackSummary.EventDate != "" && trackInfo.TrackSummary.EventTime != "" {
-		dateTimeStr := trackInfo.TrackSummary.EventDate + " " + trackInfo.TrackSummary.EventTime
-		if timestamp, err := u.parseUSPSDateTime(dateTimeStr); err == nil {
-			response.LastUpdated = &timestamp
-		}
-	}
-
-	// 检查是否已送达
-	if strings.ToLower(trackInfo.Status) == "delivered" && trackInfo.TrackSummary.EventDate != "" {
-		if timestamp, err := u.parseUSPSDateTime(trackInfo.TrackSummary.EventDate + " " + trackInfo.TrackSummary.EventTime); err == nil {
-			response.ActualDeliveryDate = &timestamp
+	// 转换事件历史
+	response.Events = u.convertEvents(localdata.TrackingEvents)
+	if len(response.Events) > 0 {
+		latest := response.Events[0].Timestamp
+		response.LastUpdated = &latest
+
+		// 检查是否已送达
+		if response.Status == utils.StatusDelivered {
+			response.ActualDeliveryDate = &latest
 		}
 	}
 
-	// 转换事件历史
-	response.Events = u.convertEvents(&trackInfo)
-
 	return response, nil
 }
 
 func (u *USPS) convertStatus(status string) utils.TrackingStatus {
-	switch strings.ToLower(status) {
+	switch strings.ToLower(strings.TrimSpace(status)) {
 	case "delivered":
 		return utils.StatusDelivered
-	case "in transit", "in_transit":
+	case "in transit", "in_transit", "accepted", "usps in possession of item", "available for pickup":
 		return utils.StatusInTransit
 	case "out for delivery":
 		return utils.StatusOutForDelivery
-	case "pre-shipment", "acceptance", "usps in possession of item":
+	case "pre-shipment", "pre-transit", "acceptance", "label created, not yet in system":
 		return utils.StatusPreTransit
-	case "return to sender":
+	case "return to sender", "returned to sender":
 		return utils.StatusReturnToSender
-	case "exception", "notice left", "attempted delivery":
+	case "delivery attempt", "notice left", "attempted delivery":
+		return utils.StatusFailure
+	case "alert", "exception":
 		return utils.StatusException
 	default:
 		return utils.StatusUnknown
 	}
 }
 
-func (u *USPS) convertOriginAddress(trackInfo *TrackInfo) *utils.Address {
-	if trackInfo.OriginCity == "" && trackInfo.OriginState == "" && trackInfo.OriginZip == "" {
+func (u *USPS) convertAddress(city, state, zip, country string) *utils.Address {
+	if city == "" && state == "" && zip == "" {
 		return nil
 	}
-
-	return &utils.Address{
-		City:       trackInfo.OriginCity,
-		State:      trackInfo.OriginState,
-		PostalCode: trackInfo.OriginZip,
-		Country:    "US", // USPS默认为美国
-	}
-}
-
-func (u *USPS) convertDestinationAddress(trackInfo *TrackInfo) *utils.Address {
-	if trackInfo.DestinationCity == "" && trackInfo.DestinationState == "" && trackInfo.DestinationZip == "" {
-		return nil
+	if country == "" {
+		country = "US" // USPS默认为美国
 	}
 
 	return &utils.Address{
-		City:       trackInfo.DestinationCity,
-		State:      trackInfo.DestinationState,
-		PostalCode: trackInfo.DestinationZip,
-		Country:    "US", // USPS默认为美国
+		City:       city,
+		State:      state,
+		PostalCode: zip,
+		Country:    country,
 	}
 }
 
-func (u *USPS) convertEvents(trackInfo *TrackInfo) []utils.TrackingEvent {
+func (u *USPS) convertEvents(trackingEvents []TrackingEvent) []utils.TrackingEvent {
 	var events []utils.TrackingEvent
 
-	// 添加TrackSummary作为最新事件
-	if trackInfo.TrackSummary.Event != "" {
+	for _, detail := range trackingEvents {
 		event := utils.TrackingEvent{
-			Status:      trackInfo.Status,
-			Description: trackInfo.TrackSummary.Event,
-		}
-
-		// 解析时间
-		if trackInfo.TrackSummary.EventDate != "" {
-			dateTimeStr := trackInfo.TrackSummary.EventDate
-			if trackInfo.TrackSummary.EventTime != "" {
-				dateTimeStr += " " + trackInfo.TrackSummary.EventTime
-			}
-			if timestamp, err := u.parseUSPSDateTime(dateTimeStr); err == nil {
-				event.Timestamp = timestamp
-			}
+			Status:      detail.EventType,
+			StatusCode:  detail.EventCode,
+			Description: detail.EventType,
 		}
 
-		// 解析位置
-		if trackInfo.TrackSummary.EventCity != "" || trackInfo.TrackSummary.EventState != "" {
-			event.Location = &utils.Address{
-				City:       trackInfo.TrackSummary.EventCity,
-				State:      trackInfo.TrackSummary.EventState,
-				PostalCode: trackInfo.TrackSummary.EventZIPCode,
-				Country:    trackInfo.TrackSummary.EventCountry,
-			}
-		}
-
-		events = append(events, event)
-	}
-
-	// 添加所有详细事件
-	for _, detail := range trackInfo.TrackDetail {
-		event := utils.TrackingEvent{
-			Status:      detail.Event,
-			Description: detail.Event,
-		}
-
-		// 解析时间
-		if detail.EventDate != "" {
-			dateTimeStr := detail.EventDate
-			if detail.EventTime != "" {
-				dateTimeStr += " " + detail.EventTime
-			}
-			if timestamp, err := u.parseUSPSDateTime(dateTimeStr); err == nil {
-				event.Timestamp = timestamp
-			}
+		// 解析时间，优先使用GMT时间
+		if timestamp, err := u.parseUSPSDateTime(detail.GMTTimestamp); err == nil {
+			event.Timestamp = timestamp
+		} else if timestamp, err := u.parseUSPSDateTime(detail.EventTimestamp); err == nil {
+			event.Timestamp = timestamp
 		}
 
 		// 解析位置
@@ -212,7 +192,7 @@ func (u *USPS) convertEvents(trackInfo *TrackInfo) []utils.TrackingEvent {
 			event.Location = &utils.Address{
 				City:       detail.EventCity,
 				State:      detail.EventState,
-				PostalCode: detail.EventZIPCode,
+				PostalCode: detail.EventZIP,
 				Country:    detail.EventCountry,
 			}
 		}
@@ -231,15 +211,10 @@ func (u *USPS) convertEvents(trackInfo *TrackInfo) []utils.TrackingEvent {
 func (u *USPS) parseUSPSDateTime(dateTimeStr string) (time.Time, error) {
 	// USPS的时间格式可能有多种，尝试不同的解析格式
 	formats := []string{
-		"January 2, 2006 3:04 pm",
-		"January 2, 2006 15:04",
-		"January 2, 2006",
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05",
 		"2006-01-02 15:04:05",
-		"2006-01-02 15:04",
 		"2006-01-02",
-		"01/02/2006 15:04:05",
-		"01/02/2006 15:04",
-		"01/02/2006",
 	}
 
 	for _, format := range formats {
@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Carrier 承运商标识
+type Carrier string
+
+const (
+	CarrierUPS       Carrier = "ups"
+	CarrierUSPS      Carrier = "usps"
+	CarrierFedEx     Carrier = "fedex"
+	CarrierDHL       Carrier = "dhl"
+	CarrierChinaPost Carrier = "china_post"
+	CarrierYanwen    Carrier = "yanwen"
+)
+
+// carrierPattern 承运商单号格式，valid为空时只校验格式
+type carrierPattern struct {
+	carrier Carrier
+	regex   *regexp.Regexp
+	valid   func(number string) bool
+}
+
+// 按识别准确度排序，带校验位的格式在前
+var carrierPatterns = []carrierPattern{
+	{CarrierUPS, regexp.MustCompile(`^1Z[0-9A-Z]{16}$`), validUPS},
+	{CarrierUSPS, regexp.MustCompile(`^420(\d{5}|\d{9})9[2-5]\d{18,20}$`), validUSPSWithZip},
+	{CarrierUSPS, regexp.MustCompile(`^9[2-5]\d{18,20}$`), validMod10},
+	{CarrierUSPS, regexp.MustCompile(`^[A-Z]{2}\d{9}US$`), validS10},
+	{CarrierChinaPost, regexp.MustCompile(`^[A-Z]{2}\d{9}CN$`), validS10},
+	{CarrierYanwen, regexp.MustCompile(`^[A-Z]{2}\d{9}YP$`), nil},
+	{CarrierFedEx, regexp.MustCompile(`^\d{12}$`), validFedEx12},
+	{CarrierFedEx, regexp.MustCompile(`^\d{15}$`), validMod10},
+	{CarrierDHL, regexp.MustCompile(`^\d{10}$`), validDHL},
+	{CarrierDHL, regexp.MustCompile(`^JJD\d{10,20}$`), nil},
+}
+
+// NormalizeTrackingNumber 去除空格和分隔符并转为大写
+func NormalizeTrackingNumber(number string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "", "\t", "").Replace(strings.TrimSpace(number)))
+}
+
+// DetectCarriers 根据单号格式和校验位识别可能的承运商，按可信度排序
+func DetectCarriers(number string) []Carrier {
+	number = NormalizeTrackingNumber(number)
+
+	var result []Carrier
+	seen := make(map[Carrier]bool)
+	for _, p := range carrierPatterns {
+		if seen[p.carrier] || !p.regex.MatchString(number) {
+			continue
+		}
+		if p.valid != nil && !p.valid(number) {
+			continue
+		}
+		seen[p.carrier] = true
+		result = append(result, p.carrier)
+	}
+	return result
+}
+
+// validUPS 1Z单号第3-17位计算校验位，字母按 (ASCII-63)%10 转为数字，偶数位乘2
+func validUPS(number string) bool {
+	body := number[2:17]
+	sum := 0
+	for i, ch := range body {
+		var v int
+		if ch >= '0' && ch <= '9' {
+			v = int(ch - '0')
+		} else {
+			v = (int(ch) - 63) % 10
+		}
+		if i%2 == 1 {
+			v *= 2
+		}
+		sum += v
+	}
+	check := (10 - sum%10) % 10
+	return int(number[17]-'0') == check
+}
+
+// validMod10 USPS IMpb和FedEx Ground使用的模10校验，从右往左奇数位乘3
+func validMod10(number string) bool {
+	n := len(number)
+	sum := 0
+	for i := n - 2; i >= 0; i-- {
+		v := int(number[i] - '0')
+		if (n-2-i)%2 == 0 {
+			v *= 3
+		}
+		sum += v
+	}
+	check := (10 - sum%10) % 10
+	return int(number[n-1]-'0') == check
+}
+
+// validUSPSWithZip 420开头的单号带有收件邮编，去掉后按模10校验
+func validUSPSWithZip(number string) bool {
+	rest := number[3:]
+	for _, zipLen := range []int{5, 9} {
+		if len(rest) > zipLen && rest[zipLen] == '9' {
+			n := rest[zipLen:]
+			if len(n) >= 20 && len(n) <= 22 && validMod10(n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validS10 万国邮联S10格式，8位序号加权8,6,4,2,3,5,9,7后模11
+func validS10(number string) bool {
+	weights := []int{8, 6, 4, 2, 3, 5, 9, 7}
+	digits := number[2:11]
+	sum := 0
+	for i, w := range weights {
+		sum += int(digits[i]-'0') * w
+	}
+	check := 11 - sum%11
+	switch check {
+	case 10:
+		check = 0
+	case 11:
+		check = 5
+	}
+	return int(digits[8]-'0') == check
+}
+
+// validFedEx12 FedEx Express 12位单号，前11位从右往左加权1,3,7后模11
+func validFedEx12(number string) bool {
+	weights := []int{1, 3, 7}
+	sum := 0
+	for i := 0; i < 11; i++ {
+		sum += int(number[10-i]-'0') * weights[i%3]
+	}
+	return int(number[11]-'0') == sum%11%10
+}
+
+// validDHL DHL Express 10位单号，前9位模7
+func validDHL(number string) bool {
+	n := 0
+	for _, ch := range number[:9] {
+		n = n*10 + int(ch-'0')
+	}
+	return int(number[9]-'0') == n%7
+}
@@ -0,0 +1,46 @@
+package utils
+
+import "testing"
+
+func TestCheckDigitValidators(t *testing.T) {
+	cases := []struct {
+		name   string
+		valid  func(string) bool
+		number string
+		want   bool
+	}{
+		// 字母按 (ASCII-63)%10 转为数字
+		{"ups", validUPS, "1Z999AA10123456784", true},
+		{"ups", validUPS, "1Z12345E6605272234", true},
+		{"ups wrong check digit", validUPS, "1Z999AA10123456785", false},
+		{"ups letter changed", validUPS, "1Z999AB10123456784", false},
+
+		{"usps impb", validMod10, "9205590164917312751089", true},
+		{"usps impb wrong check digit", validMod10, "9205590164917312751088", false},
+		{"fedex ground", validMod10, "020207021381215", true},
+		{"fedex ground wrong check digit", validMod10, "020207021381216", false},
+
+		{"usps with zip5", validUSPSWithZip, "420902109205590164917312751089", true},
+		{"usps with zip9", validUSPSWithZip, "4209021012349205590164917312751089", true},
+		{"usps with zip wrong check digit", validUSPSWithZip, "420902109205590164917312751080", false},
+
+		{"s10", validS10, "EE123456785US", true},
+		{"s10 check 11 maps to 5", validS10, "RA100000025CN", true},
+		{"s10 check 10 maps to 0", validS10, "RA100000140US", true},
+		{"s10 wrong check digit", validS10, "EE123456784US", false},
+
+		{"fedex express", validFedEx12, "986578788855", true},
+		{"fedex express", validFedEx12, "797806677146", true},
+		{"fedex express wrong check digit", validFedEx12, "986578788856", false},
+
+		{"dhl", validDHL, "3318810025", true},
+		{"dhl", validDHL, "1234567891", true},
+		{"dhl wrong check digit", validDHL, "3318810024", false},
+	}
+
+	for _, c := range cases {
+		if got := c.valid(c.number); got != c.want {
+			t.Errorf("%s: %s valid = %v, want %v", c.name, c.number, got, c.want)
+		}
+	}
+}
@@ -11,14 +11,66 @@ func GetPublicUrl(provider, path string) string {
 }
 
 // UpdateStatus 更新追踪状态 - 现在使用回调机制
+// 只有状态的服务商使用该函数，有完整追踪数据时使用ApplyTrackingResponse
 func UpdateStatus(trackingNumber string, status TrackingStatus) error {
 	slog.Info("Updating status for tracking number", "trackingNumber", trackingNumber, "status", status)
 
+	previous := previousStatus(trackingNumber)
+	changed, err := saveShipmentStatus(trackingNumber, status)
+	if err != nil {
+		slog.Error("Failed to save shipment status", "trackingNumber", trackingNumber, "error", err)
+	}
+	// 只有条件更新确实改变了状态才通知，未建档的包裹不通知
+	if changed {
+		notifyTrackingUpdate(&TrackingUpdate{
+			TrackingNumber: trackingNumber,
+			Status:         status,
+			PreviousStatus: previous,
+			Response:       &TrackingResponse{TrackingNumber: trackingNumber, Status: status},
+		})
+	}
+
 	// 使用回调机制通知状态更新，具体的业务逻辑由主项目注册的回调处理
 	// 这样tracking模块就不会依赖任何业务相关的代码
 	return NotifyStatusUpdate(trackingNumber, status)
 }
 
+// ApplyTrackingResponse 保存服务商返回的完整追踪数据，状态变化时通知TrackingUpdateCallback
+// 简单的状态回调每次都会通知，与UpdateStatus一致
+func ApplyTrackingResponse(provider string, resp *TrackingResponse) error {
+	shipment, previous, newEvents, err := SaveTrackingResponse(provider, resp)
+	if err != nil {
+		return err
+	}
+
+	if resp.Status != "" && resp.Status != previous {
+		notifyTrackingUpdate(&TrackingUpdate{
+			TrackingNumber: resp.TrackingNumber,
+			Provider:       provider,
+			Status:         resp.Status,
+			PreviousStatus: previous,
+			NewEvents:      newEvents,
+			Response:       resp,
+			ShipmentID:     shipment.ID,
+		})
+	}
+
+	return NotifyStatusUpdate(resp.TrackingNumber, resp.Status)
+}
+
+// previousStatus 包裹当前保存的状态，仅用于通知中的PreviousStatus，未追踪的包裹视为未知
+func previousStatus(trackingNumber string) TrackingStatus {
+	shipment, err := FindShipment(trackingNumber)
+	if err != nil {
+		slog.Error("Failed to load shipment", "trackingNumber", trackingNumber, "error", err)
+		return StatusUnknown
+	}
+	if shipment == nil || shipment.Status == "" {
+		return StatusUnknown
+	}
+	return TrackingStatus(shipment.Status)
+}
+
 // StatusUpdateCallback 状态更新回调函数类型
 type StatusUpdateCallback func(trackingNumber string, status TrackingStatus) error
 
@@ -46,8 +98,69 @@ func NotifyStatusUpdate(trackingNumber string, status TrackingStatus) error {
 	return nil
 }
 
+// TrackingUpdate 状态变化时的完整追踪信息
+type TrackingUpdate struct {
+	TrackingNumber string
+	Provider       string
+	Status         TrackingStatus
+	PreviousStatus TrackingStatus
+	NewEvents      int               // 本次新增的追踪事件数
+	Response       *TrackingResponse // 标准化的追踪数据，只有状态的更新中仅包含状态
+	ShipmentID     uint
+}
+
+// TrackingUpdateCallback 状态变化回调函数类型
+type TrackingUpdateCallback func(update *TrackingUpdate) error
+
+var updateCallbackRegistry []TrackingUpdateCallback
+
+// RegisterTrackingUpdateCallback 注册状态变化回调，只在状态实际变化时调用
+func RegisterTrackingUpdateCallback(callback TrackingUpdateCallback) {
+	updateCallbackRegistry = append(updateCallbackRegistry, callback)
+	slog.Info("Registered tracking update callback", "totalCallbacks", len(updateCallbackRegistry))
+}
+
+func notifyTrackingUpdate(update *TrackingUpdate) {
+	for i, callback := range updateCallbackRegistry {
+		if err := callback(update); err != nil {
+			slog.Error("Tracking update callback failed", "callbackIndex", i, "trackingNumber", update.TrackingNumber, "error", err)
+		}
+	}
+}
+
+// ShipmentAlert 包裹触发预警规则时的通知
+type ShipmentAlert struct {
+	TrackingNumber string
+	ShipmentID     uint
+	Status         TrackingStatus
+	Rule           string // 规则名称，如 no_events、eta_passed
+	Reason         string
+}
+
+// ShipmentAlertCallback 预警回调函数类型
+type ShipmentAlertCallback func(alert *ShipmentAlert) error
+
+var alertCallbackRegistry []ShipmentAlertCallback
+
+// RegisterShipmentAlertCallback 注册预警回调，同一包裹的同一预警只通知一次
+func RegisterShipmentAlertCallback(callback ShipmentAlertCallback) {
+	alertCallbackRegistry = append(alertCallbackRegistry, callback)
+	slog.Info("Registered shipment alert callback", "totalCallbacks", len(alertCallbackRegistry))
+}
+
+// NotifyShipmentAlert 通知所有预警回调
+func NotifyShipmentAlert(alert *ShipmentAlert) {
+	for i, callback := range alertCallbackRegistry {
+		if err := callback(alert); err != nil {
+			slog.Error("Shipment alert callback failed", "callbackIndex", i, "trackingNumber", alert.TrackingNumber, "error", err)
+		}
+	}
+}
+
 // ClearCallbacks 清除所有回调（主要用于测试）
 func ClearCallbacks() {
 	callbackRegistry = callbackRegistry[:0]
+	updateCallbackRegistry = updateCallbackRegistry[:0]
+	alertCallbackRegistry = alertCallbackRegistry[:0]
 	slog.Info("Cleared all tracking status update callbacks")
 }
@@ -1,6 +1,10 @@
 package utils
 
-import "github.com/flaboy/pin"
+import (
+	"time"
+
+	"github.com/flaboy/pin"
+)
 
 // TrackingProvider 定义追踪服务提供商的接口
 type TrackingProvider interface {
@@ -10,6 +14,15 @@ type TrackingProvider interface {
 	// 开始追踪指定的追踪号
 	StartTracking(trackingNumber string) error
 
+	// 停止追踪，送达或退回后调用，不再消耗服务商配额
+	StopTracking(trackingNumber string) error
+
+	// 重新追踪已停止的追踪号
+	RetrackNumber(trackingNumber string) error
+
+	// 查询追踪号的当前追踪信息
+	GetTracking(trackingNumber string) (*TrackingResponse, error)
+
 	// 处理来自服务商的webhook请求
 	HandleRequest(c *pin.Context, path string) error
 
@@ -18,3 +31,27 @@ type TrackingProvider interface {
 	// 获取服务商名称
 	GetProviderName() string
 }
+
+// BatchTracker 支持一次注册多个追踪号的服务商
+type BatchTracker interface {
+	// 返回被拒绝的追踪号及原因，请求失败时返回error
+	StartTrackingBatch(trackingNumbers []string) (map[string]error, error)
+}
+
+// PushSubscriber 通过推送订阅接收更新、订阅会过期的服务商，订阅过期后的包裹由调度轮询
+type PushSubscriber interface {
+	// 开始追踪后订阅的有效期
+	SubscriptionTTL() time.Duration
+}
+
+// QuotaReporter 按单号配额计费的服务商
+type QuotaReporter interface {
+	GetQuota() (*Quota, error)
+}
+
+// Quota 服务商的单号配额
+type Quota struct {
+	Total     int `json:"total"`
+	Used      int `json:"used"`
+	Remaining int `json:"remaining"`
+}
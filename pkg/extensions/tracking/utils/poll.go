@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/flaboy/aira-shop/pkg/models"
+)
+
+// 查询间隔按状态调整，派送中频繁查询，长时间没有新事件时降低频率
+const (
+	pollOutForDelivery = 30 * time.Minute
+	pollException      = 2 * time.Hour
+	pollInTransit      = 4 * time.Hour
+	pollPreTransit     = 6 * time.Hour
+	pollStalled        = 12 * time.Hour
+	pollDormant        = 24 * time.Hour
+
+	// 最新事件超过该时间视为停滞
+	stalledAfter = 3 * 24 * time.Hour
+	dormantAfter = 14 * 24 * time.Hour
+
+	// 间隔随机浮动的比例，避免大量包裹在同一时间查询
+	pollJitter = 0.1
+)
+
+// NextPollInterval 按状态和最新事件的时间计算下次查询间隔
+func NextPollInterval(shipment *models.Shipment, now time.Time) time.Duration {
+	if shipment.LastEventAt != nil {
+		idle := now.Sub(*shipment.LastEventAt)
+		if idle > dormantAfter {
+			return pollDormant
+		}
+		if idle > stalledAfter && TrackingStatus(shipment.Status) != StatusOutForDelivery {
+			return pollStalled
+		}
+	}
+
+	switch TrackingStatus(shipment.Status) {
+	case StatusOutForDelivery:
+		return pollOutForDelivery
+	case StatusException, StatusFailure:
+		return pollException
+	case StatusPreTransit, StatusUnknown:
+		return pollPreTransit
+	default:
+		return pollInTransit
+	}
+}
+
+// WithJitter 在间隔上加入随机浮动
+func WithJitter(d time.Duration) time.Duration {
+	delta := float64(d) * pollJitter
+	return d + time.Duration((rand.Float64()*2-1)*delta)
+}
+
+// nextPollAt 包裹下次查询的时间，已到最终状态的包裹不再查询
+func nextPollAt(shipment *models.Shipment, now time.Time) *time.Time {
+	if TrackingStatus(shipment.Status).IsTerminal() {
+		return nil
+	}
+	at := now.Add(WithJitter(NextPollInterval(shipment, now)))
+	return &at
+}
@@ -0,0 +1,259 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/flaboy/aira-core/pkg/database"
+	"github.com/flaboy/aira-shop/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EnsureShipment 开始追踪时创建包裹记录，已存在时更新服务商，carrier为空时保留原值
+func EnsureShipment(trackingNumber, provider, carrier string) (*models.Shipment, error) {
+	shipment := &models.Shipment{
+		TrackingNumber: trackingNumber,
+		Provider:       provider,
+		Carrier:        carrier,
+		Status:         string(StatusUnknown),
+	}
+	// 新包裹刚由服务商开始追踪，按未知状态的间隔安排首次轮询，已存在的包裹不改变
+	shipment.NextPollAt = nextPollAt(shipment, time.Now())
+	updates := []string{"provider", "updated_at"}
+	if carrier != "" {
+		updates = append(updates, "carrier")
+	}
+	err := database.Database().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tracking_number"}},
+		DoUpdates: clause.AssignmentColumns(updates),
+	}).Create(shipment).Error
+	if err != nil {
+		return nil, err
+	}
+	return FindShipment(trackingNumber)
+}
+
+// FindShipment 按追踪号获取包裹，不存在时返回nil
+func FindShipment(trackingNumber string) (*models.Shipment, error) {
+	var shipment models.Shipment
+	err := database.Database().Where("tracking_number = ?", trackingNumber).First(&shipment).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+// IsStaleResponse 推送的最新事件早于已保存的最新事件时，视为重放或乱序的旧推送
+func IsStaleResponse(trackingNumber string, latest *time.Time) (bool, error) {
+	if latest == nil {
+		return false, nil
+	}
+	shipment, err := FindShipment(trackingNumber)
+	if err != nil || shipment == nil || shipment.LastEventAt == nil {
+		return false, err
+	}
+	return latest.Before(*shipment.LastEventAt), nil
+}
+
+// ListDueShipments 获取服务商未到最终状态、未停止且到了查询时间的包裹，最久未同步的在前
+// createdBefore不为零值时只取该时间之前创建的包裹
+func ListDueShipments(provider string, now, createdBefore time.Time, limit int) ([]models.Shipment, error) {
+	terminal := make([]string, 0, len(TerminalStatuses))
+	for _, s := range TerminalStatuses {
+		terminal = append(terminal, string(s))
+	}
+
+	var shipments []models.Shipment
+	tx := database.Database().
+		Where("provider = ? AND status NOT IN ? AND stopped_at IS NULL", provider, terminal).
+		Where("next_poll_at IS NULL OR next_poll_at <= ?", now).
+		Order("last_synced_at IS NOT NULL, last_synced_at ASC, id ASC")
+	if !createdBefore.IsZero() {
+		tx = tx.Where("created_at <= ?", createdBefore)
+	}
+	if limit > 0 {
+		tx = tx.Limit(limit)
+	}
+	if err := tx.Find(&shipments).Error; err != nil {
+		return nil, err
+	}
+	return shipments, nil
+}
+
+// SaveTrackingResponse 保存服务商返回的追踪数据，包裹不存在时创建，事件按内容去重
+// 在事务中锁定包裹行，返回更新后的包裹、更新前的状态和新增的事件数
+func SaveTrackingResponse(provider string, resp *TrackingResponse) (*models.Shipment, TrackingStatus, int, error) {
+	existing, err := FindShipment(resp.TrackingNumber)
+	if err != nil {
+		return nil, StatusUnknown, 0, err
+	}
+	if existing == nil {
+		if _, err = EnsureShipment(resp.TrackingNumber, provider, ""); err != nil {
+			return nil, StatusUnknown, 0, err
+		}
+	}
+
+	var shipment models.Shipment
+	previous := StatusUnknown
+	var inserted int
+	err = database.Database().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("tracking_number = ?", resp.TrackingNumber).
+			First(&shipment).Error; err != nil {
+			return err
+		}
+		if shipment.Status != "" {
+			previous = TrackingStatus(shipment.Status)
+		}
+
+		fillShipment(&shipment, provider, resp)
+		if err := tx.Save(&shipment).Error; err != nil {
+			return err
+		}
+		for _, event := range resp.Events {
+			record := &models.ShipmentEvent{
+				ShipmentID:  shipment.ID,
+				Hash:        eventHash(&event),
+				Timestamp:   event.Timestamp,
+				Status:      event.Status,
+				StatusCode:  event.StatusCode,
+				Description: event.Description,
+				Location:    truncate(formatAddress(event.Location), 500),
+				Details:     event.Details,
+			}
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+			if result.Error != nil {
+				return result.Error
+			}
+			inserted += int(result.RowsAffected)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, StatusUnknown, 0, err
+	}
+	return &shipment, previous, inserted, nil
+}
+
+// fillShipment 把追踪数据写到包裹上，空值不覆盖已有数据
+func fillShipment(shipment *models.Shipment, provider string, resp *TrackingResponse) {
+	now := time.Now()
+	shipment.Provider = provider
+	shipment.LastSyncedAt = &now
+	if resp.Carrier != "" {
+		shipment.Carrier = resp.Carrier
+	}
+	if resp.CarrierCode != "" {
+		shipment.CarrierCode = resp.CarrierCode
+	}
+	if resp.Status != "" {
+		shipment.Status = string(resp.Status)
+	}
+	if resp.StatusCode != "" {
+		shipment.StatusCode = resp.StatusCode
+	}
+	if resp.StatusMessage != "" {
+		shipment.StatusMessage = truncate(resp.StatusMessage, 500)
+	}
+	if resp.EstimatedDeliveryDate != nil {
+		shipment.EstimatedDeliveryAt = resp.EstimatedDeliveryDate
+	}
+	if resp.ActualDeliveryDate != nil {
+		shipment.DeliveredAt = resp.ActualDeliveryDate
+	}
+
+	lastEventAt := resp.LastUpdated
+	for i := range resp.Events {
+		ts := resp.Events[i].Timestamp
+		if !ts.IsZero() && (lastEventAt == nil || ts.After(*lastEventAt)) {
+			lastEventAt = &ts
+		}
+	}
+	if lastEventAt != nil {
+		shipment.LastEventAt = lastEventAt
+	}
+	if resp.Status == StatusDelivered && shipment.DeliveredAt == nil {
+		shipment.DeliveredAt = shipment.LastEventAt
+	}
+	shipment.NextPollAt = nextPollAt(shipment, now)
+}
+
+// SetNextPollAt 设置包裹下次主动查询的时间
+func SetNextPollAt(shipmentID uint, at time.Time) error {
+	return database.Database().Model(&models.Shipment{}).
+		Where("id = ?", shipmentID).
+		Update("next_poll_at", at).Error
+}
+
+// SetShipmentStopped 记录包裹在服务商停止或恢复追踪，stopped为false时清除
+func SetShipmentStopped(trackingNumber string, stopped bool) error {
+	var stoppedAt interface{}
+	if stopped {
+		stoppedAt = time.Now()
+	}
+	return database.Database().Model(&models.Shipment{}).
+		Where("tracking_number = ?", trackingNumber).
+		Update("stopped_at", stoppedAt).Error
+}
+
+// saveShipmentStatus 只有状态的更新，以status <> ?为条件，返回状态是否确实发生变化
+// 并发的重复推送只有一个会更新成功，包裹不存在时不更新也不视为变化
+func saveShipmentStatus(trackingNumber string, status TrackingStatus) (bool, error) {
+	now := time.Now()
+	result := database.Database().Model(&models.Shipment{}).
+		Where("tracking_number = ? AND status <> ?", trackingNumber, string(status)).
+		Updates(map[string]interface{}{
+			"status":         string(status),
+			"last_synced_at": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+	return false, database.Database().Model(&models.Shipment{}).
+		Where("tracking_number = ?", trackingNumber).
+		Update("last_synced_at", now).Error
+}
+
+// eventHash 同一事件在多次推送中内容相同，以时间、状态、描述和地点识别
+func eventHash(event *TrackingEvent) string {
+	h := sha256.New()
+	for _, part := range []string{
+		event.Timestamp.UTC().Format(time.RFC3339),
+		event.Status,
+		event.StatusCode,
+		strings.TrimSpace(event.Description),
+		formatAddress(event.Location),
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func formatAddress(addr *Address) string {
+	if addr == nil {
+		return ""
+	}
+	var parts []string
+	for _, part := range []string{addr.Street, addr.City, addr.State, addr.PostalCode, addr.Country} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func truncate(s string, max int) string {
+	if runes := []rune(s); len(runes) > max {
+		return string(runes[:max])
+	}
+	return s
+}
@@ -53,6 +53,19 @@ const (
 	StatusUnknown        TrackingStatus = "unknown"          // 未知状态
 )
 
+// TerminalStatuses 不会再变化的状态，到达后不再需要追踪
+var TerminalStatuses = []TrackingStatus{StatusDelivered, StatusReturnToSender, StatusCancelled}
+
+// IsTerminal 是否为最终状态
+func (s TrackingStatus) IsTerminal() bool {
+	for _, t := range TerminalStatuses {
+		if s == t {
+			return true
+		}
+	}
+	return false
+}
+
 // Address 地址信息
 type Address struct {
 	Country     string       `json:"country,omitempty"`
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/flaboy/aira-web/pkg/migration"
+)
+
+// 批量发布任务状态
+const (
+	PublishJobStatusPending   = "pending"
+	PublishJobStatusRunning   = "running"
+	PublishJobStatusCompleted = "completed"
+	PublishJobStatusPartial   = "partial" // 部分店铺发布失败
+	PublishJobStatusFailed    = "failed"
+)
+
+// 批量发布任务中单个店铺的发布状态
+const (
+	PublishItemStatusPending   = "pending"
+	PublishItemStatusRunning   = "running"
+	PublishItemStatusSucceeded = "succeeded"
+	PublishItemStatusFailed    = "failed"
+)
+
+// PublishJob 批量发布任务，同一产品发布到多个店铺
+type PublishJob struct {
+	ID              uint            `gorm:"primaryKey"`
+	OwnerID         string          `gorm:"size:100;index"`                  // 发起任务的商户，只能发布到该商户的店铺
+	Status          string          `gorm:"size:20;index;default:'pending'"` // pending, running, completed, partial, failed
+	Product         json.RawMessage `gorm:"type:text"`                       // types.ProductData序列化
+	BusinessContext json.RawMessage `gorm:"type:text"`
+	Concurrency     int             `gorm:"default:5"`
+	Total           int
+	Succeeded       int
+	Failed          int
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	FinishedAt      *time.Time
+}
+
+func (j *PublishJob) TableName() string {
+	return "ar_shoplink_publish_jobs"
+}
+
+// PublishJobItem 批量发布任务中单个店铺的发布状态
+type PublishJobItem struct {
+	ID        uint   `gorm:"primaryKey"`
+	JobID     uint   `gorm:"index"`
+	ShopID    uint   `gorm:"index"`
+	Status    string `gorm:"size:20;index;default:'pending'"` // pending, running, succeeded, failed
+	Attempts  int
+	OuterID   string `gorm:"size:255"`
+	Url       string `gorm:"size:500"`
+	Error     string `gorm:"type:text"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (i *PublishJobItem) TableName() string {
+	return "ar_shoplink_publish_job_items"
+}
+
+func init() {
+	migration.RegisterAutoMigrateModels(&PublishJob{}, &PublishJobItem{})
+}
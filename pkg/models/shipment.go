@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"github.com/flaboy/aira-web/pkg/migration"
+)
+
+// Shipment 追踪中的包裹，每个追踪号一条
+type Shipment struct {
+	ID             uint   `gorm:"primaryKey"`
+	TrackingNumber string `gorm:"size:100;uniqueIndex"`
+	Provider       string `gorm:"size:50;index"` // 追踪服务商，如 17track, ups
+	Carrier        string `gorm:"size:100"`      // 承运商名称
+	CarrierCode    string `gorm:"size:50"`       // 承运商代码
+	Status         string `gorm:"size:30;index"` // tracking/utils.TrackingStatus
+	StatusCode     string `gorm:"size:100"`      // 服务商原始状态码
+	StatusMessage  string `gorm:"size:500"`
+
+	EstimatedDeliveryAt *time.Time
+	DeliveredAt         *time.Time
+	LastEventAt         *time.Time // 最新追踪事件时间
+	LastSyncedAt        *time.Time // 最后一次收到服务商数据的时间
+	NextPollAt          *time.Time `gorm:"index"` // 下次主动查询的时间，为空时尽快查询
+	StoppedAt           *time.Time // 在服务商停止追踪的时间
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (s *Shipment) TableName() string {
+	return "ar_tracking_shipments"
+}
+
+// ShipmentEvent 包裹的追踪事件，按内容哈希去重
+type ShipmentEvent struct {
+	ID          uint      `gorm:"primaryKey"`
+	ShipmentID  uint      `gorm:"uniqueIndex:idx_shipment_event_hash;index"`
+	Hash        string    `gorm:"size:64;uniqueIndex:idx_shipment_event_hash"`
+	Timestamp   time.Time `gorm:"index"`
+	Status      string    `gorm:"size:100"`
+	StatusCode  string    `gorm:"size:100"`
+	Description string    `gorm:"type:text"`
+	Location    string    `gorm:"size:500"`
+	Details     string    `gorm:"type:text"`
+	CreatedAt   time.Time
+}
+
+func (s *ShipmentEvent) TableName() string {
+	return "ar_tracking_shipment_events"
+}
+
+// ShipmentAlert 包裹触发的预警，同一规则和触发条件只记录一次
+type ShipmentAlert struct {
+	ID          uint   `gorm:"primaryKey"`
+	ShipmentID  uint   `gorm:"uniqueIndex:idx_shipment_alert;index"`
+	Rule        string `gorm:"size:50;uniqueIndex:idx_shipment_alert"`
+	Fingerprint string `gorm:"size:100;uniqueIndex:idx_shipment_alert"` // 触发条件，如预计送达时间，条件变化后可再次预警
+	Reason      string `gorm:"size:500"`
+	CreatedAt   time.Time
+}
+
+func (s *ShipmentAlert) TableName() string {
+	return "ar_tracking_shipment_alerts"
+}
+
+func init() {
+	migration.RegisterAutoMigrateModels(&Shipment{}, &ShipmentEvent{}, &ShipmentAlert{})
+}
@@ -9,12 +9,23 @@ import (
 
 type ShopLink struct {
 	ID          uint            `gorm:"primaryKey"`
+	OwnerID     string          `gorm:"size:100;index"` // 所属商户，由BusinessContext解析
 	Name        string          `gorm:"size:255"`
 	Url         string          `gorm:"size:255"`
-	Platform    string          `gorm:"size:50;index"`
+	Platform    string          `gorm:"size:50;index;uniqueIndex:idx_shoplink_platform_domain"`
+	Domain      string          `gorm:"size:255;uniqueIndex:idx_shoplink_platform_domain"` // 平台店铺域名，同一平台内唯一，旧数据为NULL
 	Credentials json.RawMessage `gorm:"type:text"`
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	Scopes      string          `gorm:"size:1000"`                // 已授权的scope，逗号分隔
+	Overrides   json.RawMessage `gorm:"type:text"`                // types.ProductOverrides序列化，发布前应用
+	DriftPolicy string          `gorm:"size:20;default:'report'"` // report, reapply
+
+	// 健康检查
+	HealthStatus    string     `gorm:"size:20;default:'unknown'"` // healthy, unhealthy, unknown
+	HealthCheckedAt *time.Time `gorm:"index"`
+	HealthError     string     `gorm:"type:text"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 func (s *ShopLink) TableName() string {
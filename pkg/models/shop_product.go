@@ -7,6 +7,15 @@ import (
 	"github.com/flaboy/aira-web/pkg/migration"
 )
 
+const (
+	ShopProductStatusPending   = "pending" // 已提交，等待平台异步处理（如Amazon listing）
+	ShopProductStatusDraft     = "draft"
+	ShopProductStatusScheduled = "scheduled"
+	ShopProductStatusActive    = "active"
+	ShopProductStatusArchived  = "archived"
+	ShopProductStatusFailed    = "failed" // 平台异步处理失败（如Amazon listing被拒绝）
+)
+
 type ShopProduct struct {
 	ID         uint            `gorm:"primaryKey"`
 	ShopID     uint            `gorm:"index"`
@@ -17,8 +26,10 @@ type ShopProduct struct {
 	Platform   string          `gorm:"size:50;index"`
 	Data       json.RawMessage `gorm:"type:text"`
 	RemoteData json.RawMessage `gorm:"type:text"`
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
+	// 定时上架时间，上架后清空
+	PublishAt *time.Time `gorm:"index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 func (s *ShopProduct) TableName() string {
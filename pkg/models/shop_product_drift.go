@@ -0,0 +1,33 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/flaboy/aira-web/pkg/migration"
+)
+
+const (
+	DriftActionReported      = "reported"
+	DriftActionReapplied     = "reapplied"
+	DriftActionReapplyFailed = "reapply_failed"
+)
+
+// ShopProductDrift 商品漂移记录，只保存存在差异的检查结果
+type ShopProductDrift struct {
+	ID            uint            `gorm:"primaryKey"`
+	ShopProductID uint            `gorm:"index"`
+	ShopID        uint            `gorm:"index"`
+	Changes       json.RawMessage `gorm:"type:text"` // []types.DriftChange
+	Action        string          `gorm:"size:20"`   // reported, reapplied, reapply_failed
+	Error         string          `gorm:"type:text"`
+	CreatedAt     time.Time
+}
+
+func (s *ShopProductDrift) TableName() string {
+	return "ar_shoplink_product_drifts"
+}
+
+func init() {
+	migration.RegisterAutoMigrateModels(&ShopProductDrift{})
+}
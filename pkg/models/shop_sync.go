@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/flaboy/aira-web/pkg/migration"
+)
+
+// ShopSyncCheckpoint 轮询类平台的同步进度，每个店铺每种数据一条
+type ShopSyncCheckpoint struct {
+	ID        uint      `gorm:"primaryKey"`
+	ShopID    uint      `gorm:"uniqueIndex:idx_shop_sync_kind"`
+	Kind      string    `gorm:"size:50;uniqueIndex:idx_shop_sync_kind"` // 如 orders
+	SyncedAt  time.Time // 已同步到的平台更新时间
+	Cursor    string    `gorm:"size:500"` // 平台分页游标等附加信息
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (s *ShopSyncCheckpoint) TableName() string {
+	return "ar_shoplink_sync_checkpoints"
+}
+
+// SyncedOrder 已通知业务系统的轮询订单，用于去重
+type SyncedOrder struct {
+	ID        uint   `gorm:"primaryKey"`
+	ShopID    uint   `gorm:"uniqueIndex:idx_synced_order"`
+	OuterID   string `gorm:"size:100;uniqueIndex:idx_synced_order"`
+	CreatedAt time.Time
+}
+
+func (s *SyncedOrder) TableName() string {
+	return "ar_shoplink_synced_orders"
+}
+
+func init() {
+	migration.RegisterAutoMigrateModels(&ShopSyncCheckpoint{}, &SyncedOrder{})
+}
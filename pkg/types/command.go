@@ -1,6 +1,8 @@
 package types
 
 import (
+	"time"
+
 	"github.com/flaboy/aira-shop/pkg/models"
 	"github.com/shopspring/decimal"
 )
@@ -8,8 +10,22 @@ import (
 // HandleRequest结果 - 返回授权URL给前端跳转到Shopify
 type HandleRequestResult struct {
 	AuthURL string `json:"auth_url"`
+	// 已有店铺重新授权时，是否缺少权限需要升级
+	UpgradeRequired bool                `json:"upgrade_required,omitempty"`
+	MissingScopes   []string            `json:"missing_scopes,omitempty"`
+	Status          HandleRequestStatus `json:"status,omitempty"`
 }
 
+// HandleRequest的处理结果，不需要升级权限和webhook请求时AuthURL为空
+type HandleRequestStatus string
+
+const (
+	HandleRequestStatusUpgradeRequired HandleRequestStatus = "upgrade_required"
+	HandleRequestStatusNoUpgradeNeeded HandleRequestStatus = "no_upgrade_needed"
+	// webhook已校验并处理，调用方返回200
+	HandleRequestStatusWebhookAccepted HandleRequestStatus = "webhook_accepted"
+)
+
 // 定义回调响应类型常量
 type CallbackResponseType string
 
@@ -41,6 +57,17 @@ type PutProductResult struct {
 	RemoteData    interface{}   `json:"remote_data"`
 }
 
+// 店铺健康检查结果
+type HealthCheckResult struct {
+	Healthy          bool     `json:"healthy"`
+	CredentialsValid bool     `json:"credentials_valid"`
+	GrantedScopes    []string `json:"granted_scopes,omitempty"`
+	MissingScopes    []string `json:"missing_scopes,omitempty"`
+	MissingWebhooks  []string `json:"missing_webhooks,omitempty"`
+	RepairedWebhooks []string `json:"repaired_webhooks,omitempty"`
+	Message          string   `json:"message,omitempty"`
+}
+
 type ShopCredential struct {
 	Platform string                 `json:"platform"`
 	Data     map[string]interface{} `json:"data"`
@@ -67,6 +94,63 @@ type ProductData struct {
 	Options         []ProductOption  `json:"options"`
 	Variants        []ProductVariant `json:"variants"`
 	BusinessContext interface{}      `json:"business_context"`
+
+	// 平台类目和类目属性，TikTok Shop等平台发布时需要
+	CategoryID string             `json:"category_id,omitempty"`
+	Attributes []ProductAttribute `json:"attributes,omitempty"`
+
+	// 发布后加入的集合，按标题指定时不存在则自动创建
+	CollectionIDs    []string `json:"collection_ids,omitempty"`
+	CollectionTitles []string `json:"collection_titles,omitempty"`
+	// 发布到的销售渠道（ID或名称），为空时只发布到网店
+	SalesChannels []string `json:"sales_channels,omitempty"`
+
+	Vendor         string             `json:"vendor,omitempty"`
+	ProductType    string             `json:"product_type,omitempty"`
+	SEOTitle       string             `json:"seo_title,omitempty"`
+	SEODescription string             `json:"seo_description,omitempty"`
+	Metafields     []ProductMetafield `json:"metafields,omitempty"`
+
+	// 发布方式，为空时直接上架；定时发布时PublishAt必须晚于当前时间
+	PublishMode PublishMode `json:"publish_mode,omitempty"`
+	PublishAt   *time.Time  `json:"publish_at,omitempty"`
+}
+
+// PublishMode 商品发布方式
+type PublishMode string
+
+const (
+	PublishModeActive    PublishMode = "active"
+	PublishModeDraft     PublishMode = "draft"
+	PublishModeScheduled PublishMode = "scheduled"
+)
+
+// ProductMetafield 商品或变体的自定义字段，Type为空时按单行文本处理
+type ProductMetafield struct {
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+	Type      string `json:"type,omitempty"`
+	Value     string `json:"value"`
+}
+
+// 店铺中的商品集合
+type Collection struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Handle string `json:"handle"`
+	Smart  bool   `json:"smart"` // 智能集合按规则自动包含商品，不能手动加入
+}
+
+// 店铺的销售渠道
+type SalesChannel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// 产品属性，按名称匹配平台类目属性
+type ProductAttribute struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
 }
 
 type ProductImage struct {
@@ -93,4 +177,21 @@ type ProductVariant struct {
 	Option1        string           `json:"option1"`
 	Option2        string           `json:"option2"`
 	Option3        string           `json:"option3"`
+	// 库存数量，为0时使用平台默认库存
+	InventoryQuantity int `json:"inventory_quantity,omitempty"`
+	// 变体图片，可以是产品图片之一，也可以是单独的图片
+	ImageSrc string `json:"image_src,omitempty"`
+	Barcode  string `json:"barcode,omitempty"`
+	// 海关编码和原产国（ISO 3166-1两位代码），用于报关
+	HSCode          string             `json:"hs_code,omitempty"`
+	CountryOfOrigin string             `json:"country_of_origin,omitempty"`
+	Metafields      []ProductMetafield `json:"metafields,omitempty"`
+}
+
+// 向平台回传订单的物流单号
+type ShipmentTracking struct {
+	OrderID        string `json:"order_id"` // 平台订单ID
+	TrackingNumber string `json:"tracking_number"`
+	Carrier        string `json:"carrier"`
+	NotifyBuyer    bool   `json:"notify_buyer"`
 }
@@ -0,0 +1,32 @@
+package types
+
+import "time"
+
+// DriftPolicy 发现商品被直接修改后的处理策略
+type DriftPolicy string
+
+const (
+	// 只记录差异报告
+	DriftPolicyReport DriftPolicy = "report"
+	// 记录报告并重新应用本系统发布的版本
+	DriftPolicyReapply DriftPolicy = "reapply"
+)
+
+// DriftChange 单个字段的差异，VariantID为0时是商品级字段
+type DriftChange struct {
+	Field     string `json:"field"` // title, price, compare_at_price, sku, variant, image
+	VariantID uint   `json:"variant_id,omitempty"`
+	Expected  string `json:"expected"`
+	Actual    string `json:"actual"`
+}
+
+// DriftReport 平台商品与最后发布数据的差异
+type DriftReport struct {
+	ShopProductID uint          `json:"shop_product_id"`
+	Changes       []DriftChange `json:"changes"`
+	CheckedAt     time.Time     `json:"checked_at"`
+}
+
+func (r *DriftReport) Drifted() bool {
+	return len(r.Changes) > 0
+}
@@ -41,6 +41,14 @@ type ShopConnectedEvent struct {
 	CreatedAt       time.Time              `json:"created_at"`
 }
 
+type ShopUnhealthyEvent struct {
+	ShopID    uint               `json:"shop_id"`
+	Platform  string             `json:"platform"`
+	Reason    string             `json:"reason"`
+	Result    *HealthCheckResult `json:"result"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
 type ProductPublishedEvent struct {
 	ShopProductID   uint                   `json:"shop_product_id"`
 	ShopID          uint                   `json:"shop_id"`
@@ -51,6 +59,17 @@ type ProductPublishedEvent struct {
 	CreatedAt       time.Time              `json:"created_at"`
 }
 
+type ProductPublishFailedEvent struct {
+	JobID           uint                   `json:"job_id"`
+	ShopProductID   uint                   `json:"shop_product_id,omitempty"` // 提交后由平台异步处理失败时有值
+	ShopID          uint                   `json:"shop_id"`
+	Platform        string                 `json:"platform"`
+	Error           string                 `json:"error"`
+	ProductData     map[string]interface{} `json:"product_data"`
+	BusinessContext json.RawMessage        `json:"business_context"`
+	CreatedAt       time.Time              `json:"created_at"`
+}
+
 type OrderData struct {
 	ID                string                 `json:"id"`                 // 平台订单ID
 	Name              string                 `json:"name"`               // 订单编号
@@ -0,0 +1,22 @@
+package types
+
+// 图片处理状态
+const (
+	ImageStatusOK        = "ok"
+	ImageStatusDuplicate = "duplicate"
+	ImageStatusFailed    = "failed"
+)
+
+// 单张图片的处理结果，保存在ShopProduct.RemoteData中
+type ImageResult struct {
+	Src         string `json:"src"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+	Hash        string `json:"hash,omitempty"`         // 内容SHA-256
+	Attached    bool   `json:"attached,omitempty"`     // 是否以附件方式上传
+	DuplicateOf string `json:"duplicate_of,omitempty"` // 内容重复时指向保留的图片
+	VariantIDs  []uint `json:"variant_ids,omitempty"`
+	RemoteID    string `json:"remote_id,omitempty"` // 平台图片ID
+}
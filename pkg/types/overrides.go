@@ -0,0 +1,27 @@
+package types
+
+import "github.com/shopspring/decimal"
+
+// ProductOverrides 店铺级别的产品覆盖规则，发布前应用到ProductData
+type ProductOverrides struct {
+	// 价格加价百分比，如 20 表示价格上浮20%
+	PriceMarkupPercent *decimal.Decimal `json:"price_markup_percent,omitempty"`
+	// 变体固定价格，key为ProductVariant.ID，优先于加价百分比
+	VariantPrices map[uint]decimal.Decimal `json:"variant_prices,omitempty"`
+	// 标题模板，支持 {{title}}、{{shop_name}} 占位符
+	TitleTemplate string `json:"title_template,omitempty"`
+	// 描述模板，支持 {{body}}、{{title}}、{{shop_name}} 占位符
+	BodyTemplate string `json:"body_template,omitempty"`
+	// 追加的标签
+	ExtraTags []string `json:"extra_tags,omitempty"`
+	// 价格舍入规则
+	Rounding *PriceRounding `json:"rounding,omitempty"`
+}
+
+// PriceRounding 价格舍入规则，先按Increment舍入再应用Ending
+type PriceRounding struct {
+	// 舍入到该值的整数倍，如 0.05、1
+	Increment *decimal.Decimal `json:"increment,omitempty"`
+	// 价格尾数，如 0.99 表示 12.30 -> 12.99
+	Ending *decimal.Decimal `json:"ending,omitempty"`
+}